@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/remotewrap"
+)
+
+// RunCacheGC runs the garbage collection of the asset cache once with specified options.
+//
+// It's safe to run while the factory replicas are serving the cache.
+func RunCacheGC(ctx context.Context, logger *zap.Logger, opts Options) error {
+	gcOptions := cacheGCOptions(opts.CacheGC)
+
+	if !gcOptions.PruneVersions && gcOptions.MaxUnusedAge == 0 {
+		return errors.New("cache garbage collection is disabled: neither Talos versions are pruned nor the unused assets are removed")
+	}
+
+	defer remotewrap.ShutdownTransport()
+
+	var artifactsManager *artifacts.Manager
+
+	// the artifacts manager is only used to list the offered Talos versions
+	if gcOptions.PruneVersions {
+		var err error
+
+		artifactsManager, err = buildArtifactsManager(ctx, logger, opts)
+		if err != nil {
+			return err
+		}
+
+		defer artifactsManager.Close() //nolint:errcheck
+	}
+
+	cacheSigningKey, err := loadPrivateKey(opts.CacheSigningKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load cache signing key: %w", err)
+	}
+
+	// the disk cache of the factory is not touched, the removed assets are removed from it when revalidated
+	opts.AssetDiskCachePath = ""
+
+	assetBuilder, err := buildAssetBuilder(logger, artifactsManager, cacheSigningKey, nil, nil, opts)
+	if err != nil {
+		return err
+	}
+
+	result, err := assetBuilder.CollectGarbage(ctx, gcOptions)
+
+	var removedBytes int64
+
+	for _, entry := range result.Removed {
+		removedBytes += entry.Size
+	}
+
+	logger.Info("cache garbage collection result", zap.Int("removed", len(result.Removed)), zap.Int64("removed_bytes", removedBytes),
+		zap.Int("kept", result.Kept), zap.Int("orphans_removed", result.OrphansRemoved), zap.Bool("dry_run", gcOptions.DryRun))
+
+	return err
+}
+
+// runCacheGC runs the background garbage collection of the asset cache, if enabled.
+func runCacheGC(ctx context.Context, logger *zap.Logger, assetBuilder *asset.Builder, opts CacheGCOptions) error {
+	gcOptions := cacheGCOptions(opts)
+
+	if opts.Interval == 0 || (!gcOptions.PruneVersions && gcOptions.MaxUnusedAge == 0) {
+		return nil
+	}
+
+	logger.Info("running cache garbage collection", zap.Duration("interval", opts.Interval), zap.Bool("prune_versions", gcOptions.PruneVersions),
+		zap.Duration("max_unused_age", gcOptions.MaxUnusedAge), zap.Bool("dry_run", gcOptions.DryRun))
+
+	return assetBuilder.RunGarbageCollector(ctx, opts.Interval, gcOptions)
+}
+
+func cacheGCOptions(opts CacheGCOptions) asset.GCOptions {
+	return asset.GCOptions{
+		MaxUnusedAge:  time.Duration(opts.UnusedDays) * 24 * time.Hour,
+		PruneVersions: opts.PruneVersions,
+		DryRun:        opts.DryRun,
+	}
+}
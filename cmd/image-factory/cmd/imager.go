@@ -0,0 +1,29 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+// RunImager runs the Talos imager for the profile read from stdin, and writes the path of the built asset to stdout.
+//
+// It's run by the asset builder in the subprocess, see imagerCommand.
+func RunImager(ctx context.Context, outputPath string) error {
+	return asset.RunImager(ctx, outputPath, os.Stdin, os.Stdout)
+}
+
+// imagerCommand returns the command which runs the Talos imager in the subprocess of the factory.
+func imagerCommand() ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{executable, asset.ImagerCommand}, nil
+}
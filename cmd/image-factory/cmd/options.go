@@ -13,10 +13,22 @@ type Options struct { //nolint:govet
 
 	// Asset builder options: minimum supported Talos version.
 	MinTalosVersion string
+	// Path to the YAML file with the Talos version policy (allow/deny lists, maximum version, pre-releases, pinned versions).
+	//
+	// If not set, all versions starting with the minimum version are offered.
+	TalosVersionPolicyPath string
 	// Image registry for source images: imager, extensions, etc..
 	ImageRegistry string
 	// Allow insecure connection to the image registry
 	InsecureImageRegistry bool
+	// Local directory with images: imager, extensions, etc. (air-gapped mode).
+	//
+	// If set, images are loaded from the directory instead of the image registry.
+	ImageSourcePath string
+	// Local directory with the Sigstore trust root (Fulcio certificates, Rekor and CT log public keys).
+	//
+	// If not set, the trust root is fetched via Sigstore TUF.
+	SigstoreTrustRootPath string
 
 	// RegistryRefreshInterval is the interval for refreshing the image registry connections.
 	RegistryRefreshInterval time.Duration
@@ -28,8 +40,28 @@ type Options struct { //nolint:govet
 	ContainerSignaturePublicKeyFile     string
 	ContainerSignaturePublicKeyHashAlgo string
 
+	// Options to verify container signatures for custom (non-official) system extensions.
+	//
+	// Custom extensions are disabled unless a subject regexp or a public key is set.
+	CustomExtensionSignatureSubjectRegExp     string
+	CustomExtensionSignatureIssuerRegExp      string
+	CustomExtensionSignatureIssuer            string
+	CustomExtensionSignaturePublicKeyFile     string
+	CustomExtensionSignaturePublicKeyHashAlgo string
+
 	// Maximum number of concurrent asset builds.
 	AssetBuildMaxConcurrency int
+	// Maximum number of asset builds waiting for the available workers (0 = unlimited).
+	AssetBuildMaxQueueLength int
+	// TTL of the build lease shared across the replicas via the cache repository (0 = disabled).
+	AssetBuildLeaseTTL time.Duration
+
+	// Local disk cache of the assets in front of the cache repository.
+	//
+	// If empty, the cached assets are always served from the cache repository.
+	AssetDiskCachePath string
+	// Maximum size of the local disk cache in bytes (zero means no limit).
+	AssetDiskCacheMaxSize int64
 
 	// External URL of the image factory HTTP frontend.
 	ExternalURL string
@@ -41,6 +73,14 @@ type Options struct { //nolint:govet
 	SchematicServiceRepository string
 	// Allow insecure connection to the schematic service repository.
 	InsecureSchematicRepository bool
+	// Schematic storage directory.
+	//
+	// If set, schematics are stored in the local directory instead of the OCI registry.
+	SchematicStoragePath string
+	// Maximum number of schematics in the in-memory cache.
+	SchematicCacheMaxSize int
+	// Time to keep "not found" schematic lookups in the in-memory cache.
+	SchematicCacheNegativeTTL time.Duration
 
 	// OCI registry to store installer images has two endpoints:
 	// - one for the image factory to push images to
@@ -53,6 +93,13 @@ type Options struct { //nolint:govet
 	// TalosVersionRecheckInterval is the interval for rechecking Talos versions.
 	TalosVersionRecheckInterval time.Duration
 
+	// Persistent storage path for the source artifacts (imager, extensions, etc.).
+	//
+	// If empty, a temporary directory is used.
+	ArtifactsStoragePath string
+	// Maximum size of the artifacts storage in bytes (zero means no limit).
+	ArtifactsStorageMaxSize int64
+
 	// CacheSigningKeyPath is the path to the signing key for the cache.
 	//
 	// Best choice is to use ECDSA key.
@@ -64,6 +111,18 @@ type Options struct { //nolint:govet
 	// Allow insecure connection to the cache repository.
 	InsecureCacheRepository bool
 
+	// Storage of the cached boot assets: "registry" (cache repository), "filesystem" or "s3".
+	CacheStorage string
+	// Directory of the cached boot assets for the "filesystem" storage.
+	CacheStoragePath string
+	// S3-compatible object store for the "s3" storage.
+	CacheS3 CacheS3Options
+	// Redirect the downloads of the cached assets to the cache storage (registry blob or pre-signed object store URL) instead of proxying them.
+	CacheRedirect bool
+
+	// Garbage collection of the cache repository.
+	CacheGC CacheGCOptions
+
 	// Bind address for Prometheus metrics.
 	//
 	// Leave empty to disable.
@@ -71,6 +130,36 @@ type Options struct { //nolint:govet
 
 	// SecureBoot settings.
 	SecureBoot SecureBootOptions
+
+	// Authentication settings.
+	Auth AuthOptions
+
+	// Rate limiting settings.
+	RateLimit RateLimitOptions
+
+	// Distributed build workers settings.
+	Worker WorkerOptions
+}
+
+// AuthOptions configures authentication of the HTTP frontend.
+//
+// Authentication is enabled if static tokens or OIDC are configured.
+type AuthOptions struct { //nolint:govet
+	// Require authentication for creating schematics, building and downloading assets.
+	//
+	// If not set, authentication is only required for private schematics.
+	Required bool
+
+	// Path to the file with static bearer tokens, one `<name>:<token>` per line.
+	TokensPath string
+
+	// OIDC JWT bearer tokens: issuer, JWKS URL and (optional) audience.
+	OIDCIssuer   string
+	OIDCJWKSURL  string
+	OIDCAudience string
+
+	// Comma-separated subjects allowed to access the admin API, qualified with the method (static:<token name> or oidc:<JWT subject>).
+	AdminSubjects string
 }
 
 // SecureBootOptions configures SecureBoot.
@@ -88,6 +177,94 @@ type SecureBootOptions struct { //nolint:govet
 	AzureKeyName         string
 }
 
+// RateLimitOptions configures per-client rate limiting.
+//
+// Clients are identified by the authenticated identity, or by the IP address.
+type RateLimitOptions struct { //nolint:govet
+	// Budget for every request (cached assets, metadata, etc.): token refill interval and burst.
+	//
+	// Zero interval disables the limit.
+	RequestsEvery time.Duration
+	RequestsBurst int
+
+	// Budget for fresh builds (assets missing in the cache): token refill interval and burst.
+	//
+	// Zero interval disables the limit.
+	BuildsEvery time.Duration
+	BuildsBurst int
+
+	// Header with the client IP address set by the reverse proxy (e.g. X-Forwarded-For).
+	//
+	// If not set, the remote address of the connection is used.
+	ClientIPHeader string
+}
+
+// WorkerOptions configures distributed builds.
+//
+// The factory either serves the frontends and assigns the builds to the workers (coordinator),
+// or runs the builds assigned by the coordinator (worker).
+type WorkerOptions struct { //nolint:govet
+	// Run as a build worker instead of serving the frontends.
+	Enabled bool
+
+	// Bind address of the worker build endpoint.
+	ListenAddr string
+
+	// Comma-separated base URLs of the workers (coordinator).
+	//
+	// If not set, the assets are built locally.
+	Endpoints string
+
+	// Shared token to authenticate the coordinator to the workers.
+	//
+	// The worker refuses to start without the token, unless Insecure is set.
+	Token string
+
+	// Accept the builds without the token (worker).
+	Insecure bool
+}
+
+// Cache storage types.
+const (
+	CacheStorageRegistry   = "registry"
+	CacheStorageFilesystem = "filesystem"
+	CacheStorageS3         = "s3"
+)
+
+// CacheS3Options configures the S3-compatible object store of the cached boot assets.
+//
+// The credentials are read from the standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type CacheS3Options struct {
+	// Endpoint URL, e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000.
+	Endpoint string
+	// Region of the bucket.
+	Region string
+	// Bucket name.
+	Bucket string
+	// Prefix of the object keys (optional).
+	Prefix string
+
+	// Address the bucket as the subdomain of the endpoint instead of the path segment.
+	VirtualHostedStyle bool
+}
+
+// CacheGCOptions configures the garbage collection of the asset cache.
+//
+// The garbage collection runs either in the background of the factory, or once via the `cache gc` subcommand.
+type CacheGCOptions struct { //nolint:govet
+	// Interval between the background garbage collection runs (0 = disabled).
+	Interval time.Duration
+
+	// Remove the assets for the Talos versions which are no longer offered.
+	PruneVersions bool
+
+	// Remove the assets which were not accessed for the number of days (0 = disabled).
+	UnusedDays int
+
+	// Only report the assets to be removed.
+	DryRun bool
+}
+
 // DefaultOptions are the default options.
 var DefaultOptions = Options{
 	HTTPListenAddr: ":8080",
@@ -102,11 +279,18 @@ var DefaultOptions = Options{
 	ContainerSignatureIssuer:            "https://accounts.google.com",
 	ContainerSignaturePublicKeyHashAlgo: "sha256",
 
+	CustomExtensionSignatureIssuer:            "https://accounts.google.com",
+	CustomExtensionSignaturePublicKeyHashAlgo: "sha256",
+
 	AssetBuildMaxConcurrency: 6,
+	AssetBuildLeaseTTL:       time.Minute,
+	AssetDiskCacheMaxSize:    10 << 30,
 
 	ExternalURL: "https://localhost/",
 
 	SchematicServiceRepository: "ghcr.io/siderolabs/image-factory/schematics",
+	SchematicCacheMaxSize:      10000,
+	SchematicCacheNegativeTTL:  time.Minute,
 
 	InstallerInternalRepository: "ghcr.io/siderolabs",
 	InstallerExternalRepository: "ghcr.io/siderolabs",
@@ -114,6 +298,24 @@ var DefaultOptions = Options{
 	TalosVersionRecheckInterval: 15 * time.Minute,
 
 	CacheRepository: "ghcr.io/siderolabs/image-factory/cache",
+	CacheStorage:    CacheStorageRegistry,
+	CacheS3: CacheS3Options{
+		Region: "us-east-1",
+	},
+
+	CacheGC: CacheGCOptions{
+		PruneVersions: true,
+		UnusedDays:    30,
+	},
 
 	MetricsListenAddr: ":2122",
+
+	RateLimit: RateLimitOptions{
+		RequestsBurst: 100,
+		BuildsBurst:   5,
+	},
+
+	Worker: WorkerOptions{
+		ListenAddr: ":8090",
+	},
 }
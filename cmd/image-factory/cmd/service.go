@@ -24,23 +24,29 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	"github.com/sigstore/cosign/v2/pkg/signature"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	sigstoresignature "github.com/sigstore/sigstore/pkg/signature"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
 
 	"github.com/siderolabs/image-factory/internal/artifacts"
 	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/auth"
 	frontendhttp "github.com/siderolabs/image-factory/internal/frontend/http"
+	"github.com/siderolabs/image-factory/internal/objectstore"
+	"github.com/siderolabs/image-factory/internal/ratelimit"
 	"github.com/siderolabs/image-factory/internal/remotewrap"
 	"github.com/siderolabs/image-factory/internal/schematic"
+	"github.com/siderolabs/image-factory/internal/schematic/storage"
 	"github.com/siderolabs/image-factory/internal/schematic/storage/cache"
+	"github.com/siderolabs/image-factory/internal/schematic/storage/filesystem"
 	"github.com/siderolabs/image-factory/internal/schematic/storage/registry"
 	"github.com/siderolabs/image-factory/internal/secureboot"
 	"github.com/siderolabs/image-factory/internal/version"
+	"github.com/siderolabs/image-factory/internal/worker"
 )
 
 // RunFactory runs the image factory with specified options.
@@ -68,16 +74,31 @@ func RunFactory(ctx context.Context, logger *zap.Logger, opts Options) error {
 		return err
 	}
 
+	// the metrics are unregistered on shutdown, so that the factory can be started again in the same process (e.g. in the tests)
+	defer prometheus.Unregister(configFactory)
+
 	cacheSigningKey, err := loadPrivateKey(opts.CacheSigningKeyPath)
 	if err != nil {
 		return fmt.Errorf("failed to load cache signing key: %w", err)
 	}
 
-	assetBuilder, err := buildAssetBuilder(logger, artifactsManager, cacheSigningKey, opts)
+	rateLimiter := buildRateLimiter(opts.RateLimit)
+	if rateLimiter != nil {
+		defer prometheus.Unregister(rateLimiter)
+	}
+
+	workerPool, err := buildWorkerPool(logger, opts.Worker)
+	if err != nil {
+		return err
+	}
+
+	assetBuilder, err := buildAssetBuilder(logger, artifactsManager, cacheSigningKey, rateLimiter, workerPool, opts)
 	if err != nil {
 		return err
 	}
 
+	defer prometheus.Unregister(assetBuilder)
+
 	secureBootService, err := secureboot.NewService(secureboot.Options(opts.SecureBoot))
 	if err != nil {
 		return fmt.Errorf("failed to initialize SecureBoot service: %w", err)
@@ -119,6 +140,32 @@ func RunFactory(ctx context.Context, logger *zap.Logger, opts Options) error {
 
 	frontendOptions.RemoteOptions = append(frontendOptions.RemoteOptions, remoteOptions()...)
 	frontendOptions.RegistryRefreshInterval = opts.RegistryRefreshInterval
+	frontendOptions.RedirectCachedAssets = opts.CacheRedirect
+
+	frontendOptions.Authenticator, err = buildAuthenticator(ctx, opts.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+
+	if opts.Auth.Required && frontendOptions.Authenticator == nil {
+		return errors.New("authentication is required, but neither static tokens nor OIDC are configured")
+	}
+
+	frontendOptions.RequireAuth = opts.Auth.Required
+	frontendOptions.AdminSubjects = splitList(opts.Auth.AdminSubjects)
+
+	if len(frontendOptions.AdminSubjects) > 0 && frontendOptions.Authenticator == nil {
+		return errors.New("admin subjects are set, but neither static tokens nor OIDC are configured")
+	}
+
+	for _, subject := range frontendOptions.AdminSubjects {
+		if err = auth.ValidateQualifiedSubject(subject); err != nil {
+			return fmt.Errorf("invalid admin subject: %w", err)
+		}
+	}
+
+	frontendOptions.RateLimiter = rateLimiter
+	frontendOptions.ClientIPHeader = opts.RateLimit.ClientIPHeader
 
 	frontendHTTP, err := frontendhttp.NewFrontend(logger, configFactory, assetBuilder, artifactsManager, secureBootService, frontendOptions)
 	if err != nil {
@@ -152,6 +199,10 @@ func RunFactory(ctx context.Context, logger *zap.Logger, opts Options) error {
 		return httpServer.Shutdown(shutdownCtx) //nolint:contextcheck
 	})
 
+	eg.Go(func() error {
+		return runCacheGC(ctx, logger, assetBuilder, opts.CacheGC)
+	})
+
 	if opts.MetricsListenAddr != "" {
 		runMetricsServer(ctx, logger, eg, opts)
 	}
@@ -191,24 +242,19 @@ func runMetricsServer(ctx context.Context, logger *zap.Logger, eg *errgroup.Grou
 }
 
 func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options) (*artifacts.Manager, error) {
-	rootCerts, err := fulcio.GetRoots()
-	if err != nil {
-		return nil, fmt.Errorf("getting Fulcio roots: %w", err)
-	}
+	var (
+		trustRoot sigstoreTrustRoot
+		err       error
+	)
 
-	intermediateCerts, err := fulcio.GetIntermediates()
-	if err != nil {
-		return nil, fmt.Errorf("getting Fulcio intermediates: %w", err)
-	}
-
-	rekorPubKeys, err := cosign.GetRekorPubs(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("error getting rekor public keys: %w", err)
+	if opts.SigstoreTrustRootPath != "" {
+		trustRoot, err = loadSigstoreTrustRoot(opts.SigstoreTrustRootPath)
+	} else {
+		trustRoot, err = fetchSigstoreTrustRoot(ctx)
 	}
 
-	ctLogPubKeys, err := cosign.GetCTLogPubs(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error ctlog public keys: %w", err)
+		return nil, err
 	}
 
 	minVersion, err := semver.Parse(opts.MinTalosVersion)
@@ -216,52 +262,68 @@ func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options
 		return nil, fmt.Errorf("failed to parse minimum Talos version: %w", err)
 	}
 
-	var checkOpts []cosign.CheckOpts
+	var versionPolicy artifacts.VersionPolicy
 
-	if len(strings.TrimSpace(opts.ContainerSignaturePublicKeyFile)) > 0 {
-		var keyVerifier sigstoresignature.Verifier
-
-		keyVerifier, err = getPublicKeyVerifier(opts)
+	if opts.TalosVersionPolicyPath != "" {
+		versionPolicy, err = loadVersionPolicy(opts.TalosVersionPolicyPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get signature verifier for key %s: %w", opts.ContainerSignaturePublicKeyFile, err)
+			return nil, err
 		}
+	}
 
-		checkOpts = append(checkOpts, cosign.CheckOpts{
-			SigVerifier: keyVerifier,
-			Offline:     true,
-			IgnoreTlog:  true,
-		})
+	keylessCheckOpts := cosign.CheckOpts{
+		RootCerts:         trustRoot.rootCerts,
+		IntermediateCerts: trustRoot.intermediateCerts,
+		RekorPubKeys:      trustRoot.rekorPubKeys,
+		CTLogPubKeys:      trustRoot.ctLogPubKeys,
+		// with a local trust root, there is no network access to the transparency log
+		Offline: opts.SigstoreTrustRootPath != "",
 	}
 
-	// Prefer opts.ContainerSignatureIssuerRegExp if set as this is more flexible
-	cosignIdentities := []cosign.Identity{
-		{
-			SubjectRegExp: opts.ContainerSignatureSubjectRegExp,
-		},
+	var checkOpts []cosign.CheckOpts
+
+	keyCheckOpts, err := publicKeyCheckOpts(opts.ContainerSignaturePublicKeyFile, opts.ContainerSignaturePublicKeyHashAlgo)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(strings.TrimSpace(opts.ContainerSignatureIssuerRegExp)) > 0 {
-		cosignIdentities[0].IssuerRegExp = opts.ContainerSignatureIssuerRegExp
-	} else {
-		cosignIdentities[0].Issuer = opts.ContainerSignatureIssuer
+	if keyCheckOpts != nil {
+		checkOpts = append(checkOpts, *keyCheckOpts)
 	}
 
-	checkOpts = append(checkOpts, cosign.CheckOpts{
-		RootCerts:         rootCerts,
-		IntermediateCerts: intermediateCerts,
-		RekorPubKeys:      rekorPubKeys,
-		CTLogPubKeys:      ctLogPubKeys,
-		Identities:        cosignIdentities,
-	})
+	checkOpts = append(checkOpts, identityCheckOpts(keylessCheckOpts, opts.ContainerSignatureSubjectRegExp, opts.ContainerSignatureIssuerRegExp, opts.ContainerSignatureIssuer))
+
+	// custom extensions are only enabled if there is a way to verify them
+	var customExtensionCheckOpts []cosign.CheckOpts
+
+	keyCheckOpts, err = publicKeyCheckOpts(opts.CustomExtensionSignaturePublicKeyFile, opts.CustomExtensionSignaturePublicKeyHashAlgo)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyCheckOpts != nil {
+		customExtensionCheckOpts = append(customExtensionCheckOpts, *keyCheckOpts)
+	}
+
+	if len(strings.TrimSpace(opts.CustomExtensionSignatureSubjectRegExp)) > 0 {
+		customExtensionCheckOpts = append(customExtensionCheckOpts,
+			identityCheckOpts(keylessCheckOpts, opts.CustomExtensionSignatureSubjectRegExp, opts.CustomExtensionSignatureIssuerRegExp, opts.CustomExtensionSignatureIssuer),
+		)
+	}
 
 	artifactsManager, err := artifacts.NewManager(logger, artifacts.Options{
-		MinVersion:                  minVersion,
-		ImageRegistry:               opts.ImageRegistry,
-		InsecureImageRegistry:       opts.InsecureImageRegistry,
-		ImageVerifyOptions:          checkOpts,
-		TalosVersionRecheckInterval: opts.TalosVersionRecheckInterval,
-		RemoteOptions:               remoteOptions(),
-		RegistryRefreshInterval:     opts.RegistryRefreshInterval,
+		MinVersion:                   minVersion,
+		VersionPolicy:                versionPolicy,
+		ImageRegistry:                opts.ImageRegistry,
+		InsecureImageRegistry:        opts.InsecureImageRegistry,
+		ImageSourcePath:              opts.ImageSourcePath,
+		ImageVerifyOptions:           checkOpts,
+		CustomExtensionVerifyOptions: customExtensionCheckOpts,
+		TalosVersionRecheckInterval:  opts.TalosVersionRecheckInterval,
+		RemoteOptions:                remoteOptions(),
+		RegistryRefreshInterval:      opts.RegistryRefreshInterval,
+		StoragePath:                  opts.ArtifactsStoragePath,
+		StorageMaxSize:               opts.ArtifactsStorageMaxSize,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize artifacts manager: %w", err)
@@ -270,14 +332,52 @@ func buildArtifactsManager(ctx context.Context, logger *zap.Logger, opts Options
 	return artifactsManager, nil
 }
 
-func buildAssetBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager, cacheSigningKey crypto.PrivateKey, opts Options) (*asset.Builder, error) {
+func loadVersionPolicy(path string) (artifacts.VersionPolicy, error) {
+	var policy artifacts.VersionPolicy
+
+	f, err := os.Open(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to open Talos version policy: %w", err)
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+
+	if err = decoder.Decode(&policy); err != nil {
+		return policy, fmt.Errorf("failed to parse Talos version policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+func buildAssetBuilder(
+	logger *zap.Logger,
+	artifactsManager *artifacts.Manager,
+	cacheSigningKey crypto.PrivateKey,
+	rateLimiter *ratelimit.Limiter,
+	workerPool *worker.Pool,
+	opts Options,
+) (*asset.Builder, error) {
 	builderOptions := asset.Options{
 		AllowedConcurrency:      opts.AssetBuildMaxConcurrency,
+		MaxQueueLength:          opts.AssetBuildMaxQueueLength,
+		BuildLeaseTTL:           opts.AssetBuildLeaseTTL,
+		DiskCachePath:           opts.AssetDiskCachePath,
+		DiskCacheMaxSize:        opts.AssetDiskCacheMaxSize,
 		CacheSigningKey:         cacheSigningKey,
 		RegistryRefreshInterval: opts.RegistryRefreshInterval,
+		RemoteKeychain:          remoteKeychain(),
+	}
+
+	if rateLimiter != nil {
+		builderOptions.BuildAdmitter = rateLimiter
 	}
 
-	builderOptions.RemoteOptions = append(builderOptions.RemoteOptions, remoteOptions()...)
+	if workerPool != nil {
+		builderOptions.RemoteBuilder = workerPool
+	}
 
 	var repoOpts []name.Option
 
@@ -287,11 +387,44 @@ func buildAssetBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager,
 
 	var err error
 
+	// the imager runs in the subprocess to capture its output into the build log
+	builderOptions.ImagerCommand, err = imagerCommand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the imager command: %w", err)
+	}
+
 	builderOptions.CacheRepository, err = name.NewRepository(opts.CacheRepository, repoOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse cache repository: %w", err)
 	}
 
+	switch opts.CacheStorage {
+	case CacheStorageRegistry:
+	case CacheStorageFilesystem:
+		if opts.CacheStoragePath == "" {
+			return nil, errors.New("cache storage path is required for the filesystem cache storage")
+		}
+
+		logger.Info("using filesystem asset cache storage", zap.String("path", opts.CacheStoragePath))
+
+		builderOptions.CacheStoragePath = opts.CacheStoragePath
+	case CacheStorageS3:
+		logger.Info("using S3 asset cache storage", zap.String("endpoint", opts.CacheS3.Endpoint), zap.String("bucket", opts.CacheS3.Bucket))
+
+		builderOptions.CacheObjectStore, err = objectstore.New(objectstore.Options{
+			Endpoint:           opts.CacheS3.Endpoint,
+			Region:             opts.CacheS3.Region,
+			Bucket:             opts.CacheS3.Bucket,
+			Prefix:             opts.CacheS3.Prefix,
+			VirtualHostedStyle: opts.CacheS3.VirtualHostedStyle,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache object store client: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported cache storage %q", opts.CacheStorage)
+	}
+
 	builder, err := asset.NewBuilder(logger, artifactsManager, builderOptions)
 	if err != nil {
 		return nil, err
@@ -302,42 +435,130 @@ func buildAssetBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager,
 	return builder, nil
 }
 
-func buildSchematicFactory(logger *zap.Logger, opts Options) (*schematic.Factory, error) {
-	var repoOpts []name.Option
+// buildRateLimiter builds the rate limiter, it returns nil if rate limiting is not configured.
+func buildRateLimiter(opts RateLimitOptions) *ratelimit.Limiter {
+	limiterOptions := ratelimit.Options{
+		Requests: ratelimit.Limit{
+			Every: opts.RequestsEvery,
+			Burst: opts.RequestsBurst,
+		},
+		Builds: ratelimit.Limit{
+			Every: opts.BuildsEvery,
+			Burst: opts.BuildsBurst,
+		},
+	}
 
-	if opts.InsecureSchematicRepository {
-		repoOpts = append(repoOpts, name.Insecure)
+	if !limiterOptions.Enabled() {
+		return nil
 	}
 
-	repo, err := name.NewRepository(opts.SchematicServiceRepository, repoOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse repository: %w", err)
+	limiter := ratelimit.New(limiterOptions)
+
+	prometheus.MustRegister(limiter)
+
+	return limiter
+}
+
+// buildWorkerPool builds the pool of the remote build workers, it returns nil if the workers are not configured.
+func buildWorkerPool(logger *zap.Logger, opts WorkerOptions) (*worker.Pool, error) {
+	endpoints := splitList(opts.Endpoints)
+
+	if len(endpoints) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	logger.Info("building assets on the remote workers", zap.Strings("endpoints", endpoints))
+
+	return worker.NewPool(logger, worker.PoolOptions{
+		Endpoints: endpoints,
+		Token:     opts.Token,
+	})
+}
+
+// buildAuthenticator builds the authenticator, it returns nil if authentication is not configured.
+func buildAuthenticator(ctx context.Context, opts AuthOptions) (auth.Authenticator, error) {
+	var chain auth.Chain
+
+	if opts.TokensPath != "" {
+		tokens, err := auth.LoadStaticTokens(opts.TokensPath)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, tokens)
 	}
 
-	storage, err := registry.NewStorage(repo, opts.RegistryRefreshInterval, remoteOptions())
+	if opts.OIDCIssuer != "" || opts.OIDCJWKSURL != "" {
+		oidc, err := auth.NewOIDC(ctx, auth.OIDCOptions{
+			Issuer:   opts.OIDCIssuer,
+			JWKSURL:  opts.OIDCJWKSURL,
+			Audience: opts.OIDCAudience,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, oidc)
+	}
+
+	if len(chain) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return chain, nil
+}
+
+func buildSchematicFactory(logger *zap.Logger, opts Options) (*schematic.Factory, error) {
+	schematicStorage, err := buildSchematicStorage(logger, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	factory := schematic.NewFactory(logger, cache.NewCache(storage), schematic.Options{})
+	factory := schematic.NewFactory(logger, cache.NewCache(schematicStorage, cache.Options{
+		MaxSize:     opts.SchematicCacheMaxSize,
+		NegativeTTL: opts.SchematicCacheNegativeTTL,
+	}), schematic.Options{})
 
 	prometheus.MustRegister(factory)
 
 	return factory, nil
 }
 
+func buildSchematicStorage(logger *zap.Logger, opts Options) (storage.Storage, error) {
+	if opts.SchematicStoragePath != "" {
+		logger.Info("using filesystem schematic storage", zap.String("path", opts.SchematicStoragePath))
+
+		return filesystem.NewStorage(opts.SchematicStoragePath)
+	}
+
+	var repoOpts []name.Option
+
+	if opts.InsecureSchematicRepository {
+		repoOpts = append(repoOpts, name.Insecure)
+	}
+
+	repo, err := name.NewRepository(opts.SchematicServiceRepository, repoOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository: %w", err)
+	}
+
+	return registry.NewStorage(repo, opts.RegistryRefreshInterval, remoteOptions())
+}
+
 // remoteOptions returns options for remote registry access.
 //
 // Enable registry auth from the standard Docker config, and from GitHub via the token.
+func remoteKeychain() authn.Keychain {
+	return authn.NewMultiKeychain(
+		authn.DefaultKeychain,
+		github.Keychain,
+		google.Keychain,
+	)
+}
+
 func remoteOptions() []remote.Option {
 	return []remote.Option{
-		remote.WithAuthFromKeychain(
-			authn.NewMultiKeychain(
-				authn.DefaultKeychain,
-				github.Keychain,
-				google.Keychain,
-			),
-		),
+		remote.WithAuthFromKeychain(remoteKeychain()),
 	}
 }
 
@@ -376,16 +597,65 @@ func getHashAlgo(algo string) (crypto.Hash, error) {
 	return ralgo, nil
 }
 
-func getPublicKeyVerifier(opts Options) (sigstoresignature.Verifier, error) {
-	hashAlgo, err := getHashAlgo(opts.ContainerSignaturePublicKeyHashAlgo)
+func getPublicKeyVerifier(publicKeyFile, hashAlgoName string) (sigstoresignature.Verifier, error) {
+	hashAlgo, err := getHashAlgo(hashAlgoName)
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := os.ReadFile(opts.ContainerSignaturePublicKeyFile)
+	key, err := os.ReadFile(publicKeyFile)
 	if err != nil {
 		return nil, err
 	}
 
 	return signature.LoadPublicKeyRaw(key, hashAlgo)
 }
+
+// publicKeyCheckOpts returns signature verification options for the public key, if the key is set.
+func publicKeyCheckOpts(publicKeyFile, hashAlgoName string) (*cosign.CheckOpts, error) {
+	if len(strings.TrimSpace(publicKeyFile)) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	keyVerifier, err := getPublicKeyVerifier(publicKeyFile, hashAlgoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signature verifier for key %s: %w", publicKeyFile, err)
+	}
+
+	return &cosign.CheckOpts{
+		SigVerifier: keyVerifier,
+		Offline:     true,
+		IgnoreTlog:  true,
+	}, nil
+}
+
+// identityCheckOpts returns signature verification options for the keyless signature with the certificate identity.
+func identityCheckOpts(keylessCheckOpts cosign.CheckOpts, subjectRegExp, issuerRegExp, issuer string) cosign.CheckOpts {
+	identity := cosign.Identity{
+		SubjectRegExp: subjectRegExp,
+	}
+
+	// Prefer issuer regexp if set as this is more flexible
+	if len(strings.TrimSpace(issuerRegExp)) > 0 {
+		identity.IssuerRegExp = issuerRegExp
+	} else {
+		identity.Issuer = issuer
+	}
+
+	keylessCheckOpts.Identities = []cosign.Identity{identity}
+
+	return keylessCheckOpts
+}
+
+// splitList splits the comma-separated list skipping the empty items.
+func splitList(s string) []string {
+	var items []string
+
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
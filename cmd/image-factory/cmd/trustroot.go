@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/tuf"
+)
+
+// sigstoreTrustRoot is the set of certificates and keys to verify keyless signatures.
+type sigstoreTrustRoot struct {
+	rootCerts         *x509.CertPool
+	intermediateCerts *x509.CertPool
+	rekorPubKeys      *cosign.TrustedTransparencyLogPubKeys
+	ctLogPubKeys      *cosign.TrustedTransparencyLogPubKeys
+}
+
+// fetchSigstoreTrustRoot fetches the trust root via Sigstore TUF (requires network access).
+func fetchSigstoreTrustRoot(ctx context.Context) (sigstoreTrustRoot, error) {
+	var (
+		trustRoot sigstoreTrustRoot
+		err       error
+	)
+
+	trustRoot.rootCerts, err = fulcio.GetRoots()
+	if err != nil {
+		return trustRoot, fmt.Errorf("getting Fulcio roots: %w", err)
+	}
+
+	trustRoot.intermediateCerts, err = fulcio.GetIntermediates()
+	if err != nil {
+		return trustRoot, fmt.Errorf("getting Fulcio intermediates: %w", err)
+	}
+
+	trustRoot.rekorPubKeys, err = cosign.GetRekorPubs(ctx)
+	if err != nil {
+		return trustRoot, fmt.Errorf("error getting rekor public keys: %w", err)
+	}
+
+	trustRoot.ctLogPubKeys, err = cosign.GetCTLogPubs(ctx)
+	if err != nil {
+		return trustRoot, fmt.Errorf("error ctlog public keys: %w", err)
+	}
+
+	return trustRoot, nil
+}
+
+// loadSigstoreTrustRoot loads the trust root from the local directory.
+//
+// The directory follows the naming of the Sigstore TUF repository targets:
+//   - `*.crt.pem` are Fulcio certificates (self-signed ones are used as roots, others as intermediates);
+//   - `rekor*.pub` are Rekor public keys;
+//   - `ctfe*.pub` are CT log public keys.
+func loadSigstoreTrustRoot(path string) (sigstoreTrustRoot, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return sigstoreTrustRoot{}, fmt.Errorf("failed to read trust root directory: %w", err)
+	}
+
+	rekorPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	ctLogPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+
+	trustRoot := sigstoreTrustRoot{
+		rootCerts:         x509.NewCertPool(),
+		intermediateCerts: x509.NewCertPool(),
+		rekorPubKeys:      &rekorPubKeys,
+		ctLogPubKeys:      &ctLogPubKeys,
+	}
+
+	var numRoots int
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		fileName := entry.Name()
+
+		data, err := os.ReadFile(filepath.Join(path, fileName))
+		if err != nil {
+			return trustRoot, err
+		}
+
+		switch {
+		case strings.HasSuffix(fileName, ".crt.pem"):
+			certs, err := cryptoutils.UnmarshalCertificatesFromPEM(data)
+			if err != nil {
+				return trustRoot, fmt.Errorf("failed to parse certificates from %q: %w", fileName, err)
+			}
+
+			for _, cert := range certs {
+				if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+					trustRoot.rootCerts.AddCert(cert)
+
+					numRoots++
+				} else {
+					trustRoot.intermediateCerts.AddCert(cert)
+				}
+			}
+		case strings.HasPrefix(fileName, "rekor") && strings.HasSuffix(fileName, ".pub"):
+			if err = trustRoot.rekorPubKeys.AddTransparencyLogPubKey(data, tuf.Active); err != nil {
+				return trustRoot, fmt.Errorf("failed to parse Rekor public key from %q: %w", fileName, err)
+			}
+		case strings.HasPrefix(fileName, "ctfe") && strings.HasSuffix(fileName, ".pub"):
+			if err = trustRoot.ctLogPubKeys.AddTransparencyLogPubKey(data, tuf.Active); err != nil {
+				return trustRoot, fmt.Errorf("failed to parse CT log public key from %q: %w", fileName, err)
+			}
+		}
+	}
+
+	if numRoots == 0 {
+		return trustRoot, fmt.Errorf("no Fulcio root certificates found in %q", path)
+	}
+
+	return trustRoot, nil
+}
@@ -0,0 +1,132 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/siderolabs/image-factory/internal/remotewrap"
+	"github.com/siderolabs/image-factory/internal/secureboot"
+	"github.com/siderolabs/image-factory/internal/version"
+	"github.com/siderolabs/image-factory/internal/worker"
+)
+
+// RunWorker runs the image factory as a build worker with specified options.
+//
+// The worker builds the assets assigned by the coordinator and pushes them to the shared asset cache,
+// so it should be configured the same way as the coordinator (artifacts, schematic storage, cache repository, SecureBoot).
+func RunWorker(ctx context.Context, logger *zap.Logger, opts Options) error {
+	logger.Info("starting worker", zap.String("name", version.Name), zap.String("version", version.Tag), zap.String("sha", version.SHA))
+	defer logger.Info("shutting down worker", zap.String("name", version.Name))
+
+	// anyone reaching the worker could build any asset and push it to the cache
+	if opts.Worker.Token == "" && !opts.Worker.Insecure {
+		return errors.New("worker token is required, use -worker-insecure to accept the builds without authentication")
+	}
+
+	if opts.Worker.Token != "" && opts.Worker.Insecure {
+		return errors.New("-worker-token and -worker-insecure are mutually exclusive")
+	}
+
+	if opts.Worker.Insecure {
+		logger.Warn("worker accepts the builds without authentication")
+	}
+
+	// see RunFactory
+	if err := os.Setenv("SOURCE_DATE_EPOCH", "1559424892"); err != nil {
+		return err
+	}
+
+	defer remotewrap.ShutdownTransport()
+
+	artifactsManager, err := buildArtifactsManager(ctx, logger, opts)
+	if err != nil {
+		return err
+	}
+
+	defer artifactsManager.Close() //nolint:errcheck
+
+	configFactory, err := buildSchematicFactory(logger, opts)
+	if err != nil {
+		return err
+	}
+
+	// see RunFactory
+	defer prometheus.Unregister(configFactory)
+
+	cacheSigningKey, err := loadPrivateKey(opts.CacheSigningKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load cache signing key: %w", err)
+	}
+
+	// the coordinator holds the build lease while the worker builds
+	opts.AssetBuildLeaseTTL = 0
+
+	// the coordinator serves the cached assets
+	opts.AssetDiskCachePath = ""
+
+	// the coordinator does the rate limiting, and the worker builds locally
+	assetBuilder, err := buildAssetBuilder(logger, artifactsManager, cacheSigningKey, nil, nil, opts)
+	if err != nil {
+		return err
+	}
+
+	defer prometheus.Unregister(assetBuilder)
+
+	secureBootService, err := secureboot.NewService(secureboot.Options(opts.SecureBoot))
+	if err != nil {
+		return fmt.Errorf("failed to initialize SecureBoot service: %w", err)
+	}
+
+	workerServer, err := worker.NewServer(logger, assetBuilder, configFactory, artifactsManager, secureBootService, worker.ServerOptions{
+		Token:    opts.Worker.Token,
+		Insecure: opts.Worker.Insecure,
+	})
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:    opts.Worker.ListenAddr,
+		Handler: workerServer.Handler(),
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		logger.Info("serving worker builds", zap.String("listen_addr", opts.Worker.ListenAddr))
+
+		err := httpServer.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+
+		return err
+	})
+
+	eg.Go(func() error {
+		<-ctx.Done()
+
+		shutdownCtx, shutdownCtxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCtxCancel()
+
+		return httpServer.Shutdown(shutdownCtx) //nolint:contextcheck
+	})
+
+	if opts.MetricsListenAddr != "" {
+		runMetricsServer(ctx, logger, eg, opts)
+	}
+
+	return eg.Wait()
+}
@@ -11,14 +11,17 @@ import (
 	"github.com/siderolabs/image-factory/cmd/image-factory/cmd"
 )
 
-func initFlags() cmd.Options {
+func initFlags(args []string) cmd.Options {
 	opts := cmd.DefaultOptions
 
 	flag.StringVar(&opts.HTTPListenAddr, "http-port", cmd.DefaultOptions.HTTPListenAddr, "HTTP listen address")
 
 	flag.StringVar(&opts.MinTalosVersion, "min-talos-version", cmd.DefaultOptions.MinTalosVersion, "minimum Talos version")
+	flag.StringVar(&opts.TalosVersionPolicyPath, "talos-version-policy", cmd.DefaultOptions.TalosVersionPolicyPath, "path to the YAML file with the Talos version policy")
 	flag.StringVar(&opts.ImageRegistry, "image-registry", cmd.DefaultOptions.ImageRegistry, "image registry for imager, extensions, etc.")
 	flag.BoolVar(&opts.InsecureImageRegistry, "insecure-image-registry", cmd.DefaultOptions.InsecureImageRegistry, "allow an insecure connection to the image registry")
+	flag.StringVar(&opts.ImageSourcePath, "image-source-path", cmd.DefaultOptions.ImageSourcePath, "load imager, extensions, etc. from the local directory instead of the image registry (air-gapped mode)") //nolint:lll
+	flag.StringVar(&opts.SigstoreTrustRootPath, "sigstore-trust-root-path", cmd.DefaultOptions.SigstoreTrustRootPath, "load Sigstore trust root from the local directory instead of TUF (air-gapped mode)")  //nolint:lll
 
 	flag.DurationVar(&opts.RegistryRefreshInterval, "registry-refresh-interval", cmd.DefaultOptions.RegistryRefreshInterval, "image registry refresh interval")
 
@@ -28,7 +31,18 @@ func initFlags() cmd.Options {
 	flag.StringVar(&opts.ContainerSignaturePublicKeyFile, "container-signature-pubkey", cmd.DefaultOptions.ContainerSignaturePublicKeyFile, "container signature public key (optional)")
 	flag.StringVar(&opts.ContainerSignaturePublicKeyHashAlgo, "container-signature-pubkey-hashalgo", cmd.DefaultOptions.ContainerSignaturePublicKeyHashAlgo, "hash algo of the container signature public key (optional)") //nolint:lll
 
+	flag.StringVar(&opts.CustomExtensionSignatureSubjectRegExp, "custom-extension-signature-subject-regexp", cmd.DefaultOptions.CustomExtensionSignatureSubjectRegExp, "custom extension signature subject regexp (optional)") //nolint:lll
+	flag.StringVar(&opts.CustomExtensionSignatureIssuerRegExp, "custom-extension-signature-issuer-regexp", cmd.DefaultOptions.CustomExtensionSignatureIssuerRegExp, "custom extension signature issuer regexp")
+	flag.StringVar(&opts.CustomExtensionSignatureIssuer, "custom-extension-signature-issuer", cmd.DefaultOptions.CustomExtensionSignatureIssuer, "custom extension signature issuer")
+	flag.StringVar(&opts.CustomExtensionSignaturePublicKeyFile, "custom-extension-signature-pubkey", cmd.DefaultOptions.CustomExtensionSignaturePublicKeyFile, "custom extension signature public key (optional)")
+	flag.StringVar(&opts.CustomExtensionSignaturePublicKeyHashAlgo, "custom-extension-signature-pubkey-hashalgo", cmd.DefaultOptions.CustomExtensionSignaturePublicKeyHashAlgo, "hash algo of the custom extension signature public key (optional)") //nolint:lll
+
 	flag.IntVar(&opts.AssetBuildMaxConcurrency, "asset-builder-max-concurrency", cmd.DefaultOptions.AssetBuildMaxConcurrency, "maximum concurrency for asset builder")
+	flag.IntVar(&opts.AssetBuildMaxQueueLength, "asset-builder-max-queue-length", cmd.DefaultOptions.AssetBuildMaxQueueLength, "maximum number of asset builds waiting for a worker, builds beyond that are rejected (0 = unlimited)") //nolint:lll
+	flag.DurationVar(&opts.AssetBuildLeaseTTL, "asset-builder-lease-ttl", cmd.DefaultOptions.AssetBuildLeaseTTL, "TTL of the build lease in the cache repository which deduplicates builds across the replicas (0 to disable)")        //nolint:lll
+
+	flag.StringVar(&opts.AssetDiskCachePath, "asset-disk-cache-path", cmd.DefaultOptions.AssetDiskCachePath, "local disk cache directory for the assets from the cache repository (optional)")
+	flag.Int64Var(&opts.AssetDiskCacheMaxSize, "asset-disk-cache-max-size", cmd.DefaultOptions.AssetDiskCacheMaxSize, "maximum size of the local disk cache in bytes (0 for unlimited)")
 
 	flag.StringVar(&opts.ExternalURL, "external-url", cmd.DefaultOptions.ExternalURL, "factory external endpoint URL")
 	flag.StringVar(&opts.ExternalPXEURL, "external-pxe-url", cmd.DefaultOptions.ExternalPXEURL, "factory external PXE endpoint URL, if not set defaults to --external-url")
@@ -40,6 +54,19 @@ func initFlags() cmd.Options {
 		cmd.DefaultOptions.InsecureSchematicRepository,
 		"allow an insecure connection to the schematics repository",
 	)
+	flag.StringVar(
+		&opts.SchematicStoragePath,
+		"schematic-storage-path",
+		cmd.DefaultOptions.SchematicStoragePath,
+		"store schematics in the local directory instead of the schematic service repository (optional)",
+	)
+	flag.IntVar(&opts.SchematicCacheMaxSize, "schematic-cache-max-size", cmd.DefaultOptions.SchematicCacheMaxSize, "maximum number of schematics in the in-memory cache (0 for unlimited)")
+	flag.DurationVar(
+		&opts.SchematicCacheNegativeTTL,
+		"schematic-cache-negative-ttl",
+		cmd.DefaultOptions.SchematicCacheNegativeTTL,
+		"time to cache \"not found\" schematic lookups (0 to disable)",
+	)
 
 	flag.StringVar(&opts.InstallerExternalRepository, "installer-external-repository", cmd.DefaultOptions.InstallerExternalRepository, "image repository for the installer (external)")
 	flag.StringVar(&opts.InstallerInternalRepository, "installer-internal-repository", cmd.DefaultOptions.InstallerInternalRepository, "image repository for the installer (internal)")
@@ -52,6 +79,9 @@ func initFlags() cmd.Options {
 
 	flag.DurationVar(&opts.TalosVersionRecheckInterval, "talos-versions-recheck-interval", cmd.DefaultOptions.TalosVersionRecheckInterval, "interval to recheck Talos versions")
 
+	flag.StringVar(&opts.ArtifactsStoragePath, "artifacts-storage-path", cmd.DefaultOptions.ArtifactsStoragePath, "persistent storage path for source artifacts (optional, defaults to temporary directory)")
+	flag.Int64Var(&opts.ArtifactsStorageMaxSize, "artifacts-storage-max-size", cmd.DefaultOptions.ArtifactsStorageMaxSize, "maximum size of the artifacts storage in bytes (0 for unlimited)")
+
 	flag.StringVar(&opts.CacheSigningKeyPath, "cache-signing-key-path", cmd.DefaultOptions.CacheSigningKeyPath, "path to the default cache signing key (PEM-encoded, ECDSA private key)")
 
 	flag.StringVar(&opts.CacheRepository, "cache-repository", cmd.DefaultOptions.CacheRepository, "cache repository for boot assets")
@@ -62,6 +92,25 @@ func initFlags() cmd.Options {
 		"allow an insecure connection to the cache repository",
 	)
 
+	flag.StringVar(&opts.CacheStorage, "cache-storage", cmd.DefaultOptions.CacheStorage, "storage of the cached boot assets: registry (cache repository), filesystem or s3")
+	flag.StringVar(&opts.CacheStoragePath, "cache-storage-path", cmd.DefaultOptions.CacheStoragePath, "directory of the cached boot assets for the filesystem cache storage")
+	flag.StringVar(&opts.CacheS3.Endpoint, "cache-s3-endpoint", cmd.DefaultOptions.CacheS3.Endpoint, "S3-compatible object store endpoint URL for the s3 cache storage")
+	flag.StringVar(&opts.CacheS3.Region, "cache-s3-region", cmd.DefaultOptions.CacheS3.Region, "region of the S3 bucket for the s3 cache storage")
+	flag.StringVar(&opts.CacheS3.Bucket, "cache-s3-bucket", cmd.DefaultOptions.CacheS3.Bucket, "S3 bucket for the s3 cache storage")
+	flag.StringVar(&opts.CacheS3.Prefix, "cache-s3-prefix", cmd.DefaultOptions.CacheS3.Prefix, "prefix of the object keys for the s3 cache storage (optional)")
+	flag.BoolVar(
+		&opts.CacheS3.VirtualHostedStyle,
+		"cache-s3-virtual-hosted-style",
+		cmd.DefaultOptions.CacheS3.VirtualHostedStyle,
+		"address the S3 bucket as the subdomain of the endpoint instead of the path segment",
+	)
+	flag.BoolVar(&opts.CacheRedirect, "cache-redirect", cmd.DefaultOptions.CacheRedirect, "redirect downloads of cached assets to the cache storage (registry blob or pre-signed object store URL)")
+
+	flag.DurationVar(&opts.CacheGC.Interval, "cache-gc-interval", cmd.DefaultOptions.CacheGC.Interval, "interval of the background garbage collection of the cache repository (0 to disable)")
+	flag.BoolVar(&opts.CacheGC.PruneVersions, "cache-gc-prune-versions", cmd.DefaultOptions.CacheGC.PruneVersions, "remove cached assets for Talos versions which are no longer offered")
+	flag.IntVar(&opts.CacheGC.UnusedDays, "cache-gc-unused-days", cmd.DefaultOptions.CacheGC.UnusedDays, "remove cached assets which were not accessed for the number of days (0 to disable)")
+	flag.BoolVar(&opts.CacheGC.DryRun, "cache-gc-dry-run", cmd.DefaultOptions.CacheGC.DryRun, "only log cached assets which would be removed by the garbage collection")
+
 	flag.StringVar(&opts.MetricsListenAddr, "metrics-listen-addr", cmd.DefaultOptions.MetricsListenAddr, "metrics listen address (set empty to disable)")
 
 	flag.BoolVar(&opts.SecureBoot.Enabled, "secureboot", cmd.DefaultOptions.SecureBoot.Enabled, "enable Secure Boot asset generation")
@@ -74,7 +123,26 @@ func initFlags() cmd.Options {
 	flag.StringVar(&opts.SecureBoot.AzureCertificateName, "secureboot-azure-certificate-name", cmd.DefaultOptions.SecureBoot.AzureCertificateName, "Secure Boot Azure Key Vault certificate name (use Azure PKI)") //nolint:lll
 	flag.StringVar(&opts.SecureBoot.AzureKeyName, "secureboot-azure-key-name", cmd.DefaultOptions.SecureBoot.AzureKeyName, "Secure Boot Azure Key Vault PCR key name (use Azure PKI)")
 
-	flag.Parse()
+	flag.BoolVar(&opts.Auth.Required, "auth-required", cmd.DefaultOptions.Auth.Required, "require authentication for creating schematics, building and downloading assets")
+	flag.StringVar(&opts.Auth.TokensPath, "auth-tokens-path", cmd.DefaultOptions.Auth.TokensPath, "path to the file with static bearer tokens, one <name>:<token> per line (optional)")
+	flag.StringVar(&opts.Auth.OIDCIssuer, "auth-oidc-issuer", cmd.DefaultOptions.Auth.OIDCIssuer, "OIDC issuer of the JWT bearer tokens (optional)")
+	flag.StringVar(&opts.Auth.OIDCJWKSURL, "auth-oidc-jwks-url", cmd.DefaultOptions.Auth.OIDCJWKSURL, "OIDC JWKS URL to verify the JWT bearer tokens")
+	flag.StringVar(&opts.Auth.OIDCAudience, "auth-oidc-audience", cmd.DefaultOptions.Auth.OIDCAudience, "expected audience of the OIDC JWT bearer tokens (optional)")
+	flag.StringVar(&opts.Auth.AdminSubjects, "auth-admin-subjects", cmd.DefaultOptions.Auth.AdminSubjects, "comma-separated subjects allowed to access the admin API, as static:<token name> or oidc:<JWT subject> (optional)")
+
+	flag.DurationVar(&opts.RateLimit.RequestsEvery, "rate-limit-requests-every", cmd.DefaultOptions.RateLimit.RequestsEvery, "per-client interval to refill the request budget (0 to disable)")
+	flag.IntVar(&opts.RateLimit.RequestsBurst, "rate-limit-requests-burst", cmd.DefaultOptions.RateLimit.RequestsBurst, "per-client request budget burst")
+	flag.DurationVar(&opts.RateLimit.BuildsEvery, "rate-limit-builds-every", cmd.DefaultOptions.RateLimit.BuildsEvery, "per-client interval to refill the fresh build budget (0 to disable)")
+	flag.IntVar(&opts.RateLimit.BuildsBurst, "rate-limit-builds-burst", cmd.DefaultOptions.RateLimit.BuildsBurst, "per-client fresh build budget burst")
+	flag.StringVar(&opts.RateLimit.ClientIPHeader, "rate-limit-client-ip-header", cmd.DefaultOptions.RateLimit.ClientIPHeader, "header with the client IP set by the reverse proxy, e.g. X-Forwarded-For (optional)") //nolint:lll
+
+	flag.BoolVar(&opts.Worker.Enabled, "worker", cmd.DefaultOptions.Worker.Enabled, "run as a build worker for the coordinator instead of serving the frontends")
+	flag.StringVar(&opts.Worker.ListenAddr, "worker-listen-addr", cmd.DefaultOptions.Worker.ListenAddr, "worker build endpoint listen address")
+	flag.StringVar(&opts.Worker.Endpoints, "worker-endpoints", cmd.DefaultOptions.Worker.Endpoints, "comma-separated base URLs of the build workers, if not set the assets are built locally")
+	flag.StringVar(&opts.Worker.Token, "worker-token", cmd.DefaultOptions.Worker.Token, "shared token to authenticate the coordinator to the build workers (required by the worker)")
+	flag.BoolVar(&opts.Worker.Insecure, "worker-insecure", cmd.DefaultOptions.Worker.Insecure, "accept the builds without the worker token (worker)")
+
+	flag.CommandLine.Parse(args) //nolint:errcheck
 
 	return opts
 }
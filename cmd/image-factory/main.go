@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"os/signal"
 
 	"github.com/siderolabs/go-debug"
@@ -16,6 +17,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/siderolabs/image-factory/cmd/image-factory/cmd"
+	"github.com/siderolabs/image-factory/internal/asset"
 )
 
 func main() {
@@ -40,6 +42,12 @@ func run() error {
 	ctx, cancel := signal.NotifyContext(context.Background(), unix.SIGINT, unix.SIGTERM)
 	defer cancel()
 
+	// `image-factory imager <output-path>` runs the Talos imager for the asset builder of the factory,
+	// it's started as a subprocess, so it doesn't start the debug server
+	if len(os.Args) == 3 && os.Args[1] == asset.ImagerCommand {
+		return cmd.RunImager(ctx, os.Args[2])
+	}
+
 	go runDebugServer(ctx)
 
 	return runWithContext(ctx)
@@ -51,7 +59,16 @@ func runWithContext(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize production logger: %w", err)
 	}
 
-	opts := initFlags()
+	// `image-factory cache gc [flags]` runs the cache garbage collection once
+	if len(os.Args) > 2 && os.Args[1] == "cache" && os.Args[2] == "gc" {
+		return cmd.RunCacheGC(ctx, logger, initFlags(os.Args[3:]))
+	}
+
+	opts := initFlags(os.Args[1:])
+
+	if opts.Worker.Enabled {
+		return cmd.RunWorker(ctx, logger, opts)
+	}
 
 	return cmd.RunFactory(ctx, logger, opts)
 }
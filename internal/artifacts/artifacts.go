@@ -6,6 +6,7 @@
 package artifacts
 
 import (
+	"errors"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -21,16 +22,34 @@ type Options struct { //nolint:govet
 	ImageRegistry string
 	// Option to allow using an image registry without TLS.
 	InsecureImageRegistry bool
+	// ImageSourcePath is the path to the local directory with images (air-gapped mode).
+	//
+	// If set, images are loaded from the local directory instead of the ImageRegistry.
+	ImageSourcePath string
 	// MinVersion is the minimum version of Talos to use.
 	MinVersion semver.Version
+	// VersionPolicy is the policy for Talos versions offered.
+	VersionPolicy VersionPolicy
 	// ImageVerifyOptions are the options for verifying the image signature.
 	ImageVerifyOptions []cosign.CheckOpts
+	// CustomExtensionVerifyOptions are the options for verifying the signature of custom (non-official) extensions.
+	//
+	// If empty, custom extensions are disabled.
+	CustomExtensionVerifyOptions []cosign.CheckOpts
 	// TalosVersionRecheckInterval is the interval for rechecking Talos versions.
 	TalosVersionRecheckInterval time.Duration
 	// RemoteOptions is the list of remote options for the puller.
 	RemoteOptions []remote.Option
 	// RegistryRefreshInterval is the interval for refreshing the image registry connections.
 	RegistryRefreshInterval time.Duration
+	// StoragePath is the path to the persistent storage for the artifacts.
+	//
+	// If empty, a temporary directory is used, which is removed on Close.
+	StoragePath string
+	// StorageMaxSize is the maximum size of the artifacts storage, in bytes.
+	//
+	// Least recently used artifacts are evicted when the limit is exceeded, zero means no limit.
+	StorageMaxSize int64
 }
 
 // Kind is the artifact kind.
@@ -58,6 +77,11 @@ const (
 // FetchTimeout controls overall timeout for fetching artifacts for a release.
 const FetchTimeout = 20 * time.Minute
 
+// BuildTimeout controls overall timeout for building an asset from the artifacts.
+//
+// The artifacts used by a build are not evicted from the storage until the build times out.
+const BuildTimeout = 20 * time.Minute
+
 // Various images.
 const (
 	InstallerBaseImage     = "siderolabs/installer-base"
@@ -69,5 +93,11 @@ const (
 
 const tmpSuffix = "-tmp"
 
+// imagerDigestFile records the digest of the imager image in the extracted artifacts directory.
+const imagerDigestFile = ".imager-digest"
+
+// ErrCustomExtensionsDisabled is returned when custom extensions are requested, but not enabled.
+var ErrCustomExtensionsDisabled = errors.New("custom extensions are disabled")
+
 // ErrNotFoundTag tags the errors when the artifact is not found.
 type ErrNotFoundTag = struct{}
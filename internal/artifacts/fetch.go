@@ -7,6 +7,8 @@ package artifacts
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -88,36 +90,32 @@ func (m *Manager) fetchImageByTag(imageName, tag string, architecture Arch, imag
 	defer cancel()
 
 	// light check first - if the image exists, and resolve the digest
-	// it's important to do further checks by digest exactly
 	repoRef := m.imageRegistry.Repo(imageName).Tag(tag)
 
 	m.logger.Debug("heading the image", zap.Stringer("image", repoRef))
 
-	descriptor, err := m.pullers[architecture].Head(ctx, repoRef)
+	ref, err := m.source.Resolve(ctx, repoRef, architecture)
 	if err != nil {
 		return err
 	}
 
-	digestRef := repoRef.Digest(descriptor.Digest.String())
-
-	return m.fetchImageByDigest(digestRef, architecture, imageHandler)
+	return m.fetchImage(ref, architecture, m.options.ImageVerifyOptions, imageHandler)
 }
 
-// fetchImageByDigest fetches an image by digest, verifies signatures, and exports it to the storage.
-func (m *Manager) fetchImageByDigest(digestRef name.Digest, architecture Arch, imageHandler imageHandler) error {
-	var err error
+// fetchImage fetches an image (by digest for remote images), verifies signatures, and exports it to the storage.
+func (m *Manager) fetchImage(ref name.Reference, architecture Arch, imageVerifyOptions []cosign.CheckOpts, imageHandler imageHandler) error {
 	// set a timeout for fetching, but don't bind it to any context, as we want fetch operation to finish
 	ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
 	defer cancel()
 
-	logger := m.logger.With(zap.Stringer("image", digestRef))
+	logger := m.logger.With(zap.Stringer("image", ref))
 
 	// verify the image signature, we only accept properly signed images
 	logger.Debug("verifying image signature")
 
-	_, bundleVerified, method, err := verifyImageSignatures(ctx, digestRef, m.options.ImageVerifyOptions)
+	method, bundleVerified, err := m.source.Verify(ctx, ref, architecture, imageVerifyOptions)
 	if err != nil {
-		return fmt.Errorf("failed to verify image signature for %s: %w", digestRef.Name(), err)
+		return fmt.Errorf("failed to verify image signature for %s: %w", ref.Name(), err)
 	}
 
 	logger.Info("image signature verified", zap.String("verification_method", method), zap.Bool("bundle_verified", bundleVerified))
@@ -125,26 +123,36 @@ func (m *Manager) fetchImageByDigest(digestRef name.Digest, architecture Arch, i
 	// pull down the image and extract the necessary parts
 	logger.Info("pulling the image")
 
-	desc, err := m.pullers[architecture].Get(ctx, digestRef)
-	if err != nil {
-		return fmt.Errorf("error pulling image %s: %w", digestRef, err)
-	}
-
-	img, err := desc.Image()
+	img, err := m.source.Image(ctx, ref, architecture)
 	if err != nil {
-		return fmt.Errorf("error creating image from descriptor: %w", err)
+		return err
 	}
 
 	return imageHandler(ctx, logger, img)
 }
 
 // fetchImager fetches 'imager' container, and saves to the storage path.
+//
+// The digest of the imager image is recorded next to the extracted artifacts.
 func (m *Manager) fetchImager(tag string) error {
 	destinationPath := filepath.Join(m.storagePath, tag)
 
-	if err := m.fetchImageByTag(ImagerImage, tag, ArchAmd64, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+	exportHandler := imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
 		return untarWithPrefix(logger, r, usrInstallPrefix, destinationPath+tmpSuffix)
-	})); err != nil {
+	})
+
+	if err := m.fetchImageByTag(ImagerImage, tag, ArchAmd64, func(ctx context.Context, logger *zap.Logger, img v1.Image) error {
+		if err := exportHandler(ctx, logger, img); err != nil {
+			return err
+		}
+
+		digest, err := img.Digest()
+		if err != nil {
+			return fmt.Errorf("error getting imager digest: %w", err)
+		}
+
+		return os.WriteFile(filepath.Join(destinationPath+tmpSuffix, imagerDigestFile), []byte(digest.String()), 0o644)
+	}); err != nil {
 		return err
 	}
 
@@ -157,7 +165,7 @@ func (m *Manager) extractOverlay(arch Arch, ref OverlayRef) error {
 
 	destinationPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest+"-overlay")
 
-	if err := m.fetchImageByDigest(imageRef, arch, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
+	if err := m.fetchImage(imageRef, arch, m.options.ImageVerifyOptions, imageExportHandler(func(logger *zap.Logger, r io.Reader) error {
 		return untarWithPrefix(logger, r, overlaysPrefix, destinationPath+tmpSuffix)
 	})); err != nil {
 		return err
@@ -170,7 +178,18 @@ func (m *Manager) extractOverlay(arch Arch, ref OverlayRef) error {
 func (m *Manager) fetchExtensionImage(arch Arch, ref ExtensionRef, destPath string) error {
 	imageRef := m.imageRegistry.Repo(ref.TaggedReference.RepositoryStr()).Digest(ref.Digest)
 
-	if err := m.fetchImageByDigest(imageRef, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+	if err := m.fetchImage(imageRef, arch, m.options.ImageVerifyOptions, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+		return err
+	}
+
+	return os.Rename(destPath+tmpSuffix, destPath)
+}
+
+// fetchCustomExtensionImage fetches a specified custom extension image and exports it to the storage as OCI.
+//
+// Custom extensions are verified with a separate set of verification options.
+func (m *Manager) fetchCustomExtensionImage(arch Arch, ref name.Digest, destPath string) error {
+	if err := m.fetchImage(ref, arch, m.options.CustomExtensionVerifyOptions, imageOCIHandler(destPath+tmpSuffix)); err != nil {
 		return err
 	}
 
@@ -181,7 +200,7 @@ func (m *Manager) fetchExtensionImage(arch Arch, ref ExtensionRef, destPath stri
 func (m *Manager) fetchOverlayImage(arch Arch, ref OverlayRef, destPath string) error {
 	imageRef := m.imageRegistry.Repo(ref.TaggedReference.RepositoryStr()).Digest(ref.Digest)
 
-	if err := m.fetchImageByDigest(imageRef, arch, imageOCIHandler(destPath+tmpSuffix)); err != nil {
+	if err := m.fetchImage(imageRef, arch, m.options.ImageVerifyOptions, imageOCIHandler(destPath+tmpSuffix)); err != nil {
 		return err
 	}
 
@@ -212,6 +231,7 @@ func untarWithPrefix(logger *zap.Logger, r io.Reader, prefix, destination string
 	tr := tar.NewReader(r)
 
 	size := int64(0)
+	checksums := map[string]string{}
 
 	for {
 		hdr, err := tr.Next()
@@ -243,7 +263,9 @@ func untarWithPrefix(logger *zap.Logger, r io.Reader, prefix, destination string
 			return fmt.Errorf("error creating file %q: %w", destPath, err)
 		}
 
-		_, err = io.Copy(f, tr)
+		hash := sha256.New()
+
+		_, err = io.Copy(io.MultiWriter(f, hash), tr)
 		if err != nil {
 			return fmt.Errorf("error copying data to %q: %w", destPath, err)
 		}
@@ -252,9 +274,16 @@ func untarWithPrefix(logger *zap.Logger, r io.Reader, prefix, destination string
 			return fmt.Errorf("error closing %q: %w", destPath, err)
 		}
 
+		checksums[hdr.Name[len(prefix):]] = hex.EncodeToString(hash.Sum(nil))
+
 		size += hdr.Size
 	}
 
+	// record checksums, so that the contents can be verified later
+	if err := writeChecksums(destination, checksums); err != nil {
+		return fmt.Errorf("error writing checksums: %w", err)
+	}
+
 	logger.Info("extracted the image", zap.Int64("size", size), zap.String("destination", destination))
 
 	return nil
@@ -272,16 +301,7 @@ func verifyImageSignatures(ctx context.Context, digestRef name.Reference, imageV
 	for _, ivo := range imageVerifyOptions {
 		_, bundleVerified, err := cosign.VerifyImageSignatures(ctx, digestRef, &ivo)
 		if err == nil {
-			// determine verification method
-			var verificationMethod string
-
-			if ivo.SigVerifier != nil {
-				verificationMethod = "public key"
-			} else {
-				verificationMethod = "certificate subject"
-			}
-
-			return &ivo, bundleVerified, verificationMethod, nil
+			return &ivo, bundleVerified, verificationMethod(&ivo), nil
 		}
 
 		multiErr = errors.Join(multiErr, err)
@@ -290,3 +310,12 @@ func verifyImageSignatures(ctx context.Context, digestRef name.Reference, imageV
 	// error will be not nil
 	return &cosign.CheckOpts{}, false, "", multiErr
 }
+
+// verificationMethod returns a human-readable verification method for the options.
+func verificationMethod(ivo *cosign.CheckOpts) string {
+	if ivo.SigVerifier != nil {
+		return "public key"
+	}
+
+	return "certificate subject"
+}
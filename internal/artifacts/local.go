@@ -0,0 +1,277 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+// Annotations used by `cosign save` to mark the image in the OCI layout.
+const (
+	cosignKindAnnotation        = "kind"
+	cosignImageKind             = "dev.cosignproject.cosign/image"
+	cosignImageIndexKind        = "dev.cosignproject.cosign/imageIndex"
+	localTarballSuffix          = ".tar"
+	localTarballSignatureSuffix = ".sig"
+)
+
+// localSource is a source of the images in a local directory, used in air-gapped environments.
+//
+// Images are looked up as `<path>/<repository>/<tag>`, or `<path>/<repository>/sha256-<hex>` for digest references.
+// Each image is either an OCI layout directory (as produced by `cosign save`, so that the signatures are included),
+// or a single-image `docker save` tarball with the `.tar` extension (optionally suffixed with the architecture, e.g. `-arm64.tar`).
+//
+// Tarballs are verified with the detached signature in a `.tar.sig` file (as produced by `cosign sign-blob`),
+// so only public key verification is supported for them.
+type localSource struct {
+	path string
+}
+
+type localImage struct {
+	path    string
+	tarball bool
+}
+
+// List implements imageSource.
+func (s *localSource) List(_ context.Context, repo name.Repository) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.path, filepath.FromSlash(repo.RepositoryStr())))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		tag := entry.Name()
+
+		if strings.HasPrefix(tag, digestDirPrefix) {
+			continue
+		}
+
+		if !entry.IsDir() {
+			if !strings.HasSuffix(tag, localTarballSuffix) {
+				continue
+			}
+
+			tag = strings.TrimSuffix(tag, localTarballSuffix)
+
+			for _, arch := range []Arch{ArchAmd64, ArchArm64} {
+				tag = strings.TrimSuffix(tag, "-"+string(arch))
+			}
+		}
+
+		tags = append(tags, tag)
+	}
+
+	slices.Sort(tags)
+
+	return slices.Compact(tags), nil
+}
+
+// Resolve implements imageSource.
+//
+// Local images are looked up by the tag directly.
+func (s *localSource) Resolve(_ context.Context, ref name.Tag, _ Arch) (name.Reference, error) {
+	return ref, nil
+}
+
+// Verify implements imageSource.
+func (s *localSource) Verify(ctx context.Context, ref name.Reference, arch Arch, imageVerifyOptions []cosign.CheckOpts) (string, bool, error) {
+	img, err := s.locate(ref, arch)
+	if err != nil {
+		return "", false, err
+	}
+
+	if len(imageVerifyOptions) == 0 {
+		return "", false, errors.New("no verification options provided")
+	}
+
+	if img.tarball {
+		return verifyTarballSignature(img.path, imageVerifyOptions)
+	}
+
+	var multiErr error
+
+	for _, ivo := range imageVerifyOptions {
+		_, bundleVerified, err := cosign.VerifyLocalImageSignatures(ctx, img.path, &ivo)
+		if err == nil {
+			return verificationMethod(&ivo), bundleVerified, nil
+		}
+
+		multiErr = errors.Join(multiErr, err)
+	}
+
+	return "", false, multiErr
+}
+
+// Image implements imageSource.
+func (s *localSource) Image(_ context.Context, ref name.Reference, arch Arch) (v1.Image, error) {
+	img, err := s.locate(ref, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	var image v1.Image
+
+	if img.tarball {
+		image, err = tarball.ImageFromPath(img.path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error loading image tarball %q: %w", img.path, err)
+		}
+	} else {
+		image, err = layoutImage(img.path, ref, arch)
+		if err != nil {
+			return nil, fmt.Errorf("error loading OCI layout %q: %w", img.path, err)
+		}
+	}
+
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("error reading image config: %w", err)
+	}
+
+	if config.Architecture != "" && config.Architecture != string(arch) {
+		return nil, fmt.Errorf("image %s has architecture %q, expected %q", ref, config.Architecture, arch)
+	}
+
+	return image, nil
+}
+
+// digestDirPrefix is the prefix for the local images stored by digest.
+const digestDirPrefix = "sha256-"
+
+func (s *localSource) locate(ref name.Reference, arch Arch) (localImage, error) {
+	base := filepath.Join(s.path, filepath.FromSlash(ref.Context().RepositoryStr()), strings.ReplaceAll(ref.Identifier(), ":", "-"))
+
+	if st, err := os.Stat(base); err == nil && st.IsDir() {
+		return localImage{path: base}, nil
+	}
+
+	for _, candidate := range []string{base + "-" + string(arch) + localTarballSuffix, base + localTarballSuffix} {
+		if _, err := os.Stat(candidate); err == nil {
+			return localImage{path: candidate, tarball: true}, nil
+		}
+	}
+
+	return localImage{}, xerrors.NewTaggedf[ErrNotFoundTag]("image %s not found in %q", ref, s.path)
+}
+
+// layoutImage loads the image from the OCI layout, picking the platform-specific image for the index.
+func layoutImage(path string, ref name.Reference, arch Arch) (v1.Image, error) {
+	idx, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var desc *v1.Descriptor
+
+	for i, manifest := range indexManifest.Manifests {
+		kind, ok := manifest.Annotations[cosignKindAnnotation]
+
+		if (ok && (kind == cosignImageKind || kind == cosignImageIndexKind)) || (!ok && len(indexManifest.Manifests) == 1) {
+			desc = &indexManifest.Manifests[i]
+
+			break
+		}
+	}
+
+	if desc == nil {
+		return nil, errors.New("failed to find the image in the layout")
+	}
+
+	if digestRef, ok := ref.(name.Digest); ok && desc.Digest.String() != digestRef.DigestStr() {
+		return nil, fmt.Errorf("digest mismatch: expected %s, got %s", digestRef.DigestStr(), desc.Digest)
+	}
+
+	if !desc.MediaType.IsIndex() {
+		return idx.Image(desc.Digest)
+	}
+
+	imageIdx, err := idx.ImageIndex(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	imageIndexManifest, err := imageIdx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifest := range imageIndexManifest.Manifests {
+		if manifest.Platform != nil && manifest.Platform.OS == "linux" && manifest.Platform.Architecture == string(arch) {
+			return imageIdx.Image(manifest.Digest)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find image for architecture %q", arch)
+}
+
+// verifyTarballSignature verifies the detached signature of the image tarball.
+func verifyTarballSignature(path string, imageVerifyOptions []cosign.CheckOpts) (string, bool, error) {
+	encodedSig, err := os.ReadFile(path + localTarballSignatureSuffix)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read tarball signature: %w", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSig)))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decode tarball signature: %w", err)
+	}
+
+	var multiErr error
+
+	for _, ivo := range imageVerifyOptions {
+		if ivo.SigVerifier == nil {
+			continue
+		}
+
+		err = func() error {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close() //nolint:errcheck
+
+			return ivo.SigVerifier.VerifySignature(bytes.NewReader(sig), f)
+		}()
+		if err == nil {
+			return verificationMethod(&ivo), false, nil
+		}
+
+		multiErr = errors.Join(multiErr, err)
+	}
+
+	if multiErr == nil {
+		return "", false, errors.New("image tarballs can be only verified with a public key")
+	}
+
+	return "", false, multiErr
+}
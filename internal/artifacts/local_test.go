@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func writeSignedTarball(t *testing.T, signer signature.Signer, path, tag string) {
+	t.Helper()
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(tag)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, tarball.WriteToFile(path, ref, img))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	sig, err := signer.SignMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path+".sig", []byte(base64.StdEncoding.EncodeToString(sig)), 0o644))
+}
+
+func TestLocalImageSource(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signerVerifier, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	require.NoError(t, err)
+
+	sourcePath := t.TempDir()
+
+	writeSignedTarball(t, signerVerifier, filepath.Join(sourcePath, "siderolabs", "imager", "v1.7.0.tar"), "ghcr.io/siderolabs/imager:v1.7.0")
+	writeSignedTarball(t, signerVerifier, filepath.Join(sourcePath, "siderolabs", "imager", "v1.6.0.tar"), "ghcr.io/siderolabs/imager:v1.6.0")
+	writeSignedTarball(t, signerVerifier, filepath.Join(sourcePath, "siderolabs", "installer", "v1.7.0-amd64.tar"), "ghcr.io/siderolabs/installer:v1.7.0")
+	writeSignedTarball(t, signerVerifier, filepath.Join(sourcePath, "siderolabs", "installer", "v1.6.0-amd64.tar"), "ghcr.io/siderolabs/installer:v1.6.0")
+
+	// break the signature of the v1.6.0 installer
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sourcePath, "siderolabs", "installer", "v1.6.0-amd64.tar.sig"),
+		[]byte(base64.StdEncoding.EncodeToString([]byte("invalid"))),
+		0o644,
+	))
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry:   "ghcr.io",
+		ImageSourcePath: sourcePath,
+		ImageVerifyOptions: []cosign.CheckOpts{
+			{
+				SigVerifier: signerVerifier,
+				Offline:     true,
+				IgnoreTlog:  true,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, manager.Close())
+	})
+
+	versions, err := manager.GetTalosVersions(t.Context())
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "1.6.0", versions[0].String())
+	assert.Equal(t, "1.7.0", versions[1].String())
+
+	installerPath, err := manager.GetInstallerImage(t.Context(), artifacts.ArchAmd64, "v1.7.0")
+	require.NoError(t, err)
+
+	_, err = layout.ImageIndexFromPath(installerPath)
+	require.NoError(t, err)
+
+	_, err = manager.GetInstallerImage(t.Context(), artifacts.ArchAmd64, "v1.6.0")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to verify image signature")
+
+	_, err = manager.GetInstallerImage(t.Context(), artifacts.ArchArm64, "v1.7.0")
+	require.Error(t, err)
+}
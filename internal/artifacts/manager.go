@@ -6,6 +6,7 @@ package artifacts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,8 +17,10 @@ import (
 	"github.com/blang/semver/v4"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/siderolabs/gen/xerrors"
+	"github.com/siderolabs/talos/pkg/machinery/imager/quirks"
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 
@@ -31,7 +34,8 @@ type Manager struct { //nolint:govet
 	schematicsPath string
 	logger         *zap.Logger
 	imageRegistry  name.Registry
-	pullers        map[Arch]remotewrap.Puller
+	source         imageSource
+	versionPolicy  *versionPolicy
 
 	sf singleflight.Group
 
@@ -44,18 +48,52 @@ type Manager struct { //nolint:govet
 	talosVersionsMu        sync.Mutex
 	talosVersions          []semver.Version
 	talosVersionsTimestamp time.Time
+
+	storage storageTracker
 }
 
+// schematicsDirectory is the directory (under the storage path) for schematic extensions.
+const schematicsDirectory = "schematics"
+
 // NewManager creates a new artifacts manager.
 func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
-	tmpDir, err := os.MkdirTemp("", "image-factory")
+	versionPolicy, err := parseVersionPolicy(options.VersionPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		return nil, fmt.Errorf("failed to parse version policy: %w", err)
+	}
+
+	storagePath := options.StoragePath
+	persistent := storagePath != ""
+
+	if persistent {
+		if err = os.MkdirAll(storagePath, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	} else {
+		storagePath, err = os.MkdirTemp("", "image-factory")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+	}
+
+	m := &Manager{
+		options:        options,
+		storagePath:    storagePath,
+		schematicsPath: filepath.Join(storagePath, schematicsDirectory),
+		logger:         logger,
+		versionPolicy:  versionPolicy,
+		storage: storageTracker{
+			entries: map[string]*storageEntry{},
+		},
 	}
 
-	schematicsPath := filepath.Join(tmpDir, "schematics")
+	if persistent {
+		if err = m.validateStorage(); err != nil {
+			return nil, fmt.Errorf("failed to validate storage: %w", err)
+		}
+	}
 
-	if err = os.Mkdir(schematicsPath, 0o700); err != nil {
+	if err = os.Mkdir(m.schematicsPath, 0o700); err != nil {
 		return nil, fmt.Errorf("failed to create schematics directory: %w", err)
 	}
 
@@ -64,11 +102,21 @@ func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
 		opts = append(opts, name.Insecure)
 	}
 
-	imageRegistry, err := name.NewRegistry(options.ImageRegistry, opts...)
+	m.imageRegistry, err = name.NewRegistry(options.ImageRegistry, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse image registry: %w", err)
 	}
 
+	if options.ImageSourcePath != "" {
+		logger.Info("using local image source", zap.String("path", options.ImageSourcePath))
+
+		m.source = &localSource{
+			path: options.ImageSourcePath,
+		}
+
+		return m, nil
+	}
+
 	pullers := make(map[Arch]remotewrap.Puller, 2)
 
 	for _, arch := range []Arch{ArchAmd64, ArchArm64} {
@@ -89,18 +137,21 @@ func NewManager(logger *zap.Logger, options Options) (*Manager, error) {
 		}
 	}
 
-	return &Manager{
-		options:        options,
-		storagePath:    tmpDir,
-		schematicsPath: schematicsPath,
-		logger:         logger,
-		imageRegistry:  imageRegistry,
-		pullers:        pullers,
-	}, nil
+	m.source = &remoteSource{
+		pullers: pullers,
+	}
+
+	return m, nil
 }
 
 // Close the manager.
+//
+// Temporary storage is removed, persistent storage is kept.
 func (m *Manager) Close() error {
+	if m.options.StoragePath != "" {
+		return nil
+	}
+
 	return os.RemoveAll(m.storagePath)
 }
 
@@ -128,27 +179,13 @@ func (m *Manager) Get(ctx context.Context, versionString string, arch Arch, kind
 		return "", err
 	}
 
-	tag := "v" + version.String()
-
-	// check if already extracted
-	if _, err = os.Stat(filepath.Join(m.storagePath, tag)); err != nil {
-		resultCh := m.sf.DoChan(tag, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchImager(tag)
-		})
-
-		// wait for the fetch to finish
-		select {
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", result.Err
-			}
-		case <-ctx.Done():
-			return "", ctx.Err()
-		}
+	imagerPath, err := m.fetchImagerOnce(ctx, "v"+version.String())
+	if err != nil {
+		return "", err
 	}
 
 	// build the path
-	path := filepath.Join(m.storagePath, tag, string(arch), string(kind))
+	path := filepath.Join(imagerPath, string(arch), string(kind))
 
 	_, err = os.Stat(path)
 	if err != nil {
@@ -158,6 +195,47 @@ func (m *Manager) Get(ctx context.Context, versionString string, arch Arch, kind
 	return path, nil
 }
 
+// GetImagerRef returns the digest reference of the imager image the artifacts for the given version were extracted from.
+//
+// If the digest wasn't recorded when the artifacts were extracted, an empty string is returned.
+func (m *Manager) GetImagerRef(ctx context.Context, versionString string) (string, error) {
+	version, err := semver.ParseTolerant(versionString)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse version: %w", err)
+	}
+
+	if err = m.validateTalosVersion(ctx, version); err != nil {
+		return "", err
+	}
+
+	imagerPath, err := m.fetchImagerOnce(ctx, "v"+version.String())
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := os.ReadFile(filepath.Join(imagerPath, imagerDigestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to read imager digest: %w", err)
+	}
+
+	return m.imageRegistry.Repo(ImagerImage).Digest(string(digest)).String(), nil
+}
+
+// fetchImagerOnce makes sure the imager artifacts for the given tag are extracted, and returns the path to them.
+func (m *Manager) fetchImagerOnce(ctx context.Context, tag string) (string, error) {
+	imagerPath := filepath.Join(m.storagePath, tag)
+
+	if err := m.useEntry(ctx, imagerPath, tag, func() error { return m.fetchImager(tag) }); err != nil {
+		return "", err
+	}
+
+	return imagerPath, nil
+}
+
 // GetTalosVersions returns a list of Talos versions available.
 func (m *Manager) GetTalosVersions(ctx context.Context) ([]semver.Version, error) {
 	m.talosVersionsMu.Lock()
@@ -275,43 +353,66 @@ func (m *Manager) GetInstallerImage(ctx context.Context, arch Arch, versionStrin
 
 	ociPath := filepath.Join(m.storagePath, string(arch)+"-installer-"+tag)
 
-	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchInstallerImage(arch, tag, ociPath)
-		})
-
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", result.Err
-			}
-		}
+	if err := m.useEntry(ctx, ociPath, ociPath, func() error { return m.fetchInstallerImage(arch, tag, ociPath) }); err != nil {
+		return "", err
 	}
 
 	return ociPath, nil
 }
 
+// GetInstallerImageRef returns the digest reference of the base installer image for the given arch and version.
+func (m *Manager) GetInstallerImageRef(ctx context.Context, arch Arch, versionString string) (string, error) {
+	ociPath, err := m.GetInstallerImage(ctx, arch, versionString)
+	if err != nil {
+		return "", err
+	}
+
+	imageIndex, err := layout.ImageIndexFromPath(ociPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read installer image layout: %w", err)
+	}
+
+	indexManifest, err := imageIndex.IndexManifest()
+	if err != nil {
+		return "", fmt.Errorf("failed to read installer image index: %w", err)
+	}
+
+	if len(indexManifest.Manifests) != 1 {
+		return "", fmt.Errorf("unexpected number of images in the installer image layout: %d", len(indexManifest.Manifests))
+	}
+
+	installerImage := InstallerImage
+
+	if quirks.New(versionString).SupportsUnifiedInstaller() {
+		installerImage = InstallerBaseImage
+	}
+
+	return m.imageRegistry.Repo(installerImage).Digest(indexManifest.Manifests[0].Digest.String()).String(), nil
+}
+
 // GetExtensionImage pulls and stores in OCI layout an extension image.
 func (m *Manager) GetExtensionImage(ctx context.Context, arch Arch, ref ExtensionRef) (string, error) {
 	ociPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest)
 
-	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchExtensionImage(arch, ref, ociPath)
-		})
-
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", result.Err
-			}
-		}
+	if err := m.useEntry(ctx, ociPath, ociPath, func() error { return m.fetchExtensionImage(arch, ref, ociPath) }); err != nil {
+		return "", err
+	}
+
+	return ociPath, nil
+}
+
+// GetCustomExtensionImage pulls and stores in OCI layout a custom (non-official) extension image.
+//
+// The reference should be pinned by digest.
+func (m *Manager) GetCustomExtensionImage(ctx context.Context, arch Arch, ref name.Digest) (string, error) {
+	if len(m.options.CustomExtensionVerifyOptions) == 0 {
+		return "", ErrCustomExtensionsDisabled
+	}
+
+	ociPath := filepath.Join(m.storagePath, string(arch)+"-custom-"+ref.DigestStr())
+
+	if err := m.useEntry(ctx, ociPath, ociPath, func() error { return m.fetchCustomExtensionImage(arch, ref, ociPath) }); err != nil {
+		return "", err
 	}
 
 	return ociPath, nil
@@ -321,20 +422,8 @@ func (m *Manager) GetExtensionImage(ctx context.Context, arch Arch, ref Extensio
 func (m *Manager) GetOverlayImage(ctx context.Context, arch Arch, ref OverlayRef) (string, error) {
 	ociPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest)
 
-	// check if already fetched
-	if _, err := os.Stat(ociPath); err != nil {
-		resultCh := m.sf.DoChan(ociPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.fetchOverlayImage(arch, ref, ociPath)
-		})
-
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", result.Err
-			}
-		}
+	if err := m.useEntry(ctx, ociPath, ociPath, func() error { return m.fetchOverlayImage(arch, ref, ociPath) }); err != nil {
+		return "", err
 	}
 
 	return ociPath, nil
@@ -344,21 +433,8 @@ func (m *Manager) GetOverlayImage(ctx context.Context, arch Arch, ref OverlayRef
 func (m *Manager) GetOverlayArtifact(ctx context.Context, arch Arch, ref OverlayRef, kind OverlayKind) (string, error) {
 	extractedPath := filepath.Join(m.storagePath, string(arch)+"-"+ref.Digest+"-overlay")
 
-	// check if already extracted
-	if _, err := os.Stat(extractedPath); err != nil {
-		resultCh := m.sf.DoChan(extractedPath, func() (any, error) { //nolint:contextcheck
-			return nil, m.extractOverlay(arch, ref)
-		})
-
-		// wait for the fetch to finish
-		select {
-		case result := <-resultCh:
-			if result.Err != nil {
-				return "", result.Err
-			}
-		case <-ctx.Done():
-			return "", ctx.Err()
-		}
+	if err := m.useEntry(ctx, extractedPath, extractedPath, func() error { return m.extractOverlay(arch, ref) }); err != nil {
+		return "", err
 	}
 
 	// build the path
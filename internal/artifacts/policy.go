@@ -0,0 +1,170 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/blang/semver/v4"
+)
+
+// VersionPolicy is a declarative policy for Talos versions offered by the image factory.
+//
+// Zero value of the policy offers all versions starting with the minimum version,
+// and pre-releases (alpha and beta) only for the latest minor release.
+type VersionPolicy struct {
+	// Allow is a list of semver ranges (e.g. ">=1.6.0 <1.8.0", "1.7.5"), if set, a version should match at least one of them.
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny is a list of semver ranges, a version matching any of them is not offered.
+	Deny []string `yaml:"deny,omitempty"`
+	// MaxVersion is the maximum version offered (inclusive).
+	MaxVersion string `yaml:"maxVersion,omitempty"`
+	// PreReleases controls whether pre-releases are offered per minor version (e.g. "1.8": true).
+	//
+	// For minor versions not listed, pre-releases (alpha and beta) are offered only for the latest minor release.
+	PreReleases map[string]bool `yaml:"preReleases,omitempty"`
+	// Pinned is a list of versions which are always offered (if available), regardless of other rules.
+	Pinned []string `yaml:"pinned,omitempty"`
+}
+
+// versionPolicy is the parsed version policy.
+type versionPolicy struct {
+	maxVersion  *semver.Version
+	preReleases map[string]bool
+	allow       []semver.Range
+	deny        []semver.Range
+	pinned      []semver.Version
+}
+
+func parseVersionPolicy(policy VersionPolicy) (*versionPolicy, error) {
+	parsed := &versionPolicy{
+		preReleases: make(map[string]bool, len(policy.PreReleases)),
+	}
+
+	for _, allow := range policy.Allow {
+		r, err := semver.ParseRange(allow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allowed version range %q: %w", allow, err)
+		}
+
+		parsed.allow = append(parsed.allow, r)
+	}
+
+	for _, deny := range policy.Deny {
+		r, err := semver.ParseRange(deny)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse denied version range %q: %w", deny, err)
+		}
+
+		parsed.deny = append(parsed.deny, r)
+	}
+
+	if policy.MaxVersion != "" {
+		maxVersion, err := semver.ParseTolerant(policy.MaxVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maximum version %q: %w", policy.MaxVersion, err)
+		}
+
+		parsed.maxVersion = &maxVersion
+	}
+
+	for minor, allowed := range policy.PreReleases {
+		version, err := semver.ParseTolerant(minor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pre-release minor version %q: %w", minor, err)
+		}
+
+		parsed.preReleases[minorVersion(version)] = allowed
+	}
+
+	for _, pinned := range policy.Pinned {
+		version, err := semver.ParseTolerant(pinned)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pinned version %q: %w", pinned, err)
+		}
+
+		parsed.pinned = append(parsed.pinned, version)
+	}
+
+	return parsed, nil
+}
+
+func minorVersion(version semver.Version) string {
+	return fmt.Sprintf("%d.%d", version.Major, version.Minor)
+}
+
+// filter returns the versions allowed by the policy, sorted.
+func (p *versionPolicy) filter(versions []semver.Version, minVersion semver.Version) []semver.Version {
+	// find "current" maximum version (below the configured maximum)
+	var latestVersion semver.Version
+
+	for _, version := range versions {
+		if p.maxVersion != nil && version.GT(*p.maxVersion) {
+			continue
+		}
+
+		if version.GT(latestVersion) {
+			latestVersion = version
+		}
+	}
+
+	filtered := make([]semver.Version, 0, len(versions))
+
+	for _, version := range versions {
+		if slices.ContainsFunc(p.pinned, version.Equals) || p.allowed(version, minVersion, latestVersion) {
+			filtered = append(filtered, version)
+		}
+	}
+
+	slices.SortFunc(filtered, semver.Version.Compare)
+
+	return filtered
+}
+
+func (p *versionPolicy) allowed(version, minVersion, latestVersion semver.Version) bool {
+	if version.LT(minVersion) {
+		return false // ignore versions below minimum
+	}
+
+	if p.maxVersion != nil && version.GT(*p.maxVersion) {
+		return false
+	}
+
+	if len(version.Pre) > 0 && !p.preReleaseAllowed(version, latestVersion) {
+		return false
+	}
+
+	if len(p.allow) > 0 && !slices.ContainsFunc(p.allow, func(r semver.Range) bool { return r(version) }) {
+		return false
+	}
+
+	if slices.ContainsFunc(p.deny, func(r semver.Range) bool { return r(version) }) {
+		return false
+	}
+
+	return true
+}
+
+func (p *versionPolicy) preReleaseAllowed(version, latestVersion semver.Version) bool {
+	if allowed, ok := p.preReleases[minorVersion(version)]; ok {
+		return allowed
+	}
+
+	// allow pre-release only for the "latest" release
+	if version.Major != latestVersion.Major || version.Minor != latestVersion.Minor {
+		return false
+	}
+
+	if len(version.Pre) != 2 {
+		return false
+	}
+
+	if version.Pre[0].VersionStr != "alpha" && version.Pre[0].VersionStr != "beta" {
+		return false
+	}
+
+	return version.Pre[1].IsNumeric()
+}
@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/siderolabs/gen/xslices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func TestVersionPolicy(t *testing.T) {
+	t.Parallel()
+
+	sourcePath := t.TempDir()
+
+	for _, tag := range []string{
+		"v1.5.5",
+		"v1.6.0",
+		"v1.6.1-alpha.0",
+		"v1.6.7",
+		"v1.7.0",
+		"v1.7.1",
+		"v1.7.2",
+		"v1.8.0-alpha.1",
+		"v1.8.0-beta.0",
+		"v1.8.0-rc.0",
+		"v1.8.0",
+		"v1.9.0-alpha.0",
+		"v1.9.0-alpha.1",
+		"v1.9.0-beta.0",
+		"v1.9.0-beta",
+		"latest",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sourcePath, artifacts.ImagerImage, tag), 0o755))
+	}
+
+	for _, test := range []struct {
+		name   string
+		policy artifacts.VersionPolicy
+
+		expected []string
+	}{
+		{
+			name: "default",
+
+			expected: []string{"1.6.0", "1.6.7", "1.7.0", "1.7.1", "1.7.2", "1.8.0", "1.9.0-alpha.0", "1.9.0-alpha.1", "1.9.0-beta.0"},
+		},
+		{
+			name: "allow and deny",
+			policy: artifacts.VersionPolicy{
+				Allow: []string{">=1.7.0 <1.9.0"},
+				Deny:  []string{"1.7.1"},
+			},
+
+			expected: []string{"1.7.0", "1.7.2", "1.8.0"},
+		},
+		{
+			name: "max version",
+			policy: artifacts.VersionPolicy{
+				MaxVersion: "1.8.0",
+			},
+
+			expected: []string{"1.6.0", "1.6.7", "1.7.0", "1.7.1", "1.7.2", "1.8.0-alpha.1", "1.8.0-beta.0", "1.8.0"},
+		},
+		{
+			name: "pre-releases",
+			policy: artifacts.VersionPolicy{
+				PreReleases: map[string]bool{
+					"1.8": true,
+					"1.9": false,
+				},
+			},
+
+			expected: []string{"1.6.0", "1.6.7", "1.7.0", "1.7.1", "1.7.2", "1.8.0-alpha.1", "1.8.0-beta.0", "1.8.0-rc.0", "1.8.0"},
+		},
+		{
+			name: "pinned",
+			policy: artifacts.VersionPolicy{
+				Allow:  []string{"1.7.2"},
+				Pinned: []string{"1.5.5", "1.6.1-alpha.0"},
+			},
+
+			expected: []string{"1.5.5", "1.6.1-alpha.0", "1.7.2"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+				ImageRegistry:   "ghcr.io",
+				ImageSourcePath: sourcePath,
+				MinVersion:      semver.MustParse("1.6.0"),
+				VersionPolicy:   test.policy,
+			})
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				require.NoError(t, manager.Close())
+			})
+
+			versions, err := manager.GetTalosVersions(t.Context())
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, xslices.Map(versions, semver.Version.String))
+		})
+	}
+}
+
+func TestVersionPolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry: "ghcr.io",
+		VersionPolicy: artifacts.VersionPolicy{
+			Allow: []string{">=foo"},
+		},
+	})
+	require.Error(t, err)
+}
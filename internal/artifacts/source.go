@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+
+	"github.com/siderolabs/image-factory/internal/remotewrap"
+)
+
+// imageSource is a source of the images: imager, extensions, overlays, etc.
+type imageSource interface {
+	// List returns the list of tags for the repository.
+	List(ctx context.Context, repo name.Repository) ([]string, error)
+	// Resolve resolves the tag to the reference which should be used to fetch the image.
+	Resolve(ctx context.Context, ref name.Tag, arch Arch) (name.Reference, error)
+	// Verify verifies the image signature, and returns the verification method used.
+	Verify(ctx context.Context, ref name.Reference, arch Arch, imageVerifyOptions []cosign.CheckOpts) (method string, bundleVerified bool, err error)
+	// Image returns the image for the specified architecture.
+	Image(ctx context.Context, ref name.Reference, arch Arch) (v1.Image, error)
+}
+
+// remoteSource is a source of the images in the container registry.
+type remoteSource struct {
+	pullers map[Arch]remotewrap.Puller
+}
+
+// List implements imageSource.
+func (s *remoteSource) List(ctx context.Context, repo name.Repository) ([]string, error) {
+	return s.pullers[ArchAmd64].List(ctx, repo)
+}
+
+// Resolve implements imageSource.
+//
+// It's important to do further checks by digest exactly, so the tag is resolved to the digest.
+func (s *remoteSource) Resolve(ctx context.Context, ref name.Tag, arch Arch) (name.Reference, error) {
+	descriptor, err := s.pullers[arch].Head(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return ref.Digest(descriptor.Digest.String()), nil
+}
+
+// Verify implements imageSource.
+func (s *remoteSource) Verify(ctx context.Context, ref name.Reference, _ Arch, imageVerifyOptions []cosign.CheckOpts) (string, bool, error) {
+	_, bundleVerified, method, err := verifyImageSignatures(ctx, ref, imageVerifyOptions)
+
+	return method, bundleVerified, err
+}
+
+// Image implements imageSource.
+func (s *remoteSource) Image(ctx context.Context, ref name.Reference, arch Arch) (v1.Image, error) {
+	desc, err := s.pullers[arch].Get(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("error pulling image %s: %w", ref, err)
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("error creating image from descriptor: %w", err)
+	}
+
+	return img, nil
+}
@@ -0,0 +1,401 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// checksumsFile is the name of the file with checksums of the extracted image contents.
+//
+// It is stored in the root of the extracted directory.
+const checksumsFile = ".image-factory-checksums.json"
+
+// evictionGracePeriod is the minimum time since last use before an entry can be evicted.
+//
+// It protects the artifacts which might be still used by the asset builds: the artifacts are used when the build starts,
+// and the build doesn't run longer than the build timeout, the margin covers the cleanup of the timed out builds
+// (e.g. the imager subprocess being killed).
+const evictionGracePeriod = 2 * BuildTimeout
+
+// storageEntry is a top-level entry in the storage directory.
+type storageEntry struct {
+	lastUsed time.Time
+	size     int64
+}
+
+// storageTracker tracks the usage of the storage entries for the LRU eviction.
+type storageTracker struct {
+	entries map[string]*storageEntry
+	mu      sync.Mutex
+}
+
+// validateStorage validates the contents of the persistent storage directory.
+//
+// Leftovers of incomplete fetches are removed, and the contents which fail validation are removed as well,
+// so that they are fetched again on the next use.
+func (m *Manager) validateStorage() error {
+	entries, err := os.ReadDir(m.storagePath)
+	if err != nil {
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	m.logger.Info("validating artifacts storage", zap.String("path", m.storagePath), zap.Int("entries", len(entries)))
+
+	var eg errgroup.Group
+
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(m.storagePath, name)
+
+		if name == schematicsDirectory {
+			// schematic extensions are cheap to re-build, so start from scratch
+			if err = os.RemoveAll(path); err != nil {
+				return fmt.Errorf("failed to clean up schematics directory: %w", err)
+			}
+
+			continue
+		}
+
+		eg.Go(func() error {
+			logger := m.logger.With(zap.String("path", path))
+
+			validationErr := validateStorageEntry(path, entry)
+			if validationErr == nil {
+				info, err := entry.Info()
+				if err != nil {
+					return err
+				}
+
+				size, err := diskUsage(path)
+				if err != nil {
+					return err
+				}
+
+				m.storage.mu.Lock()
+				m.storage.entries[name] = &storageEntry{
+					lastUsed: info.ModTime(),
+					size:     size,
+				}
+				m.storage.mu.Unlock()
+
+				return nil
+			}
+
+			logger.Warn("removing invalid artifact", zap.Error(validationErr))
+
+			return os.RemoveAll(path)
+		})
+	}
+
+	return eg.Wait()
+}
+
+// validateStorageEntry verifies a single top-level entry in the storage.
+func validateStorageEntry(path string, entry fs.DirEntry) error {
+	if strings.HasSuffix(entry.Name(), tmpSuffix) {
+		return errors.New("incomplete fetch")
+	}
+
+	if !entry.IsDir() {
+		return errors.New("unexpected file")
+	}
+
+	// OCI layout, verify image digests
+	if _, err := os.Stat(filepath.Join(path, "oci-layout")); err == nil {
+		idx, err := layout.ImageIndexFromPath(path)
+		if err != nil {
+			return fmt.Errorf("failed to open OCI layout: %w", err)
+		}
+
+		return validate.Index(idx)
+	}
+
+	// extracted image, verify the file checksums
+	return verifyChecksums(path)
+}
+
+// writeChecksums writes the checksums of the extracted image contents.
+func writeChecksums(destination string, checksums map[string]string) error {
+	data, err := json.Marshal(checksums)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(destination, 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(destination, checksumsFile), data, 0o644)
+}
+
+// verifyChecksums verifies the extracted image contents against the stored checksums.
+func verifyChecksums(path string) error {
+	data, err := os.ReadFile(filepath.Join(path, checksumsFile))
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	var checksums map[string]string
+
+	if err = json.Unmarshal(data, &checksums); err != nil {
+		return fmt.Errorf("failed to parse checksums: %w", err)
+	}
+
+	for name, expected := range checksums {
+		actual, err := fileChecksum(filepath.Join(path, name))
+		if err != nil {
+			return err
+		}
+
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %q: expected %s, got %s", name, expected, actual)
+		}
+	}
+
+	return nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hash := sha256.New()
+
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("error reading %q: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// diskUsage returns the total size of the files under the path.
+func diskUsage(path string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// useEntry makes sure the top-level storage entry is present, fetching it once for the key if needed, and marks it used.
+//
+// The entry is marked used before it's checked, and the eviction removes the entry under the same lock,
+// so the entry found present is not evicted before the grace period passes.
+func (m *Manager) useEntry(ctx context.Context, path, key string, fetch func() error) error {
+	if m.touch(path) {
+		return nil
+	}
+
+	resultCh := m.sf.DoChan(key, func() (any, error) { //nolint:contextcheck
+		// fetched by the previous call, but not tracked yet
+		if _, err := os.Stat(path); err == nil {
+			return nil, nil
+		}
+
+		return nil, fetch()
+	})
+
+	// wait for the fetch to finish
+	select {
+	case result := <-resultCh:
+		if result.Err != nil {
+			return result.Err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	m.markUsed(path)
+
+	return nil
+}
+
+// entryName returns the name of the top-level storage entry by its path.
+func (m *Manager) entryName(path string) (string, bool) {
+	name, err := filepath.Rel(m.storagePath, path)
+	if err != nil || strings.ContainsRune(name, filepath.Separator) {
+		return "", false
+	}
+
+	return name, true
+}
+
+// touch records the use of the tracked storage entry, and reports whether the entry is tracked.
+//
+// The tracked entries are present in the storage, as they are only removed with the storage lock held.
+func (m *Manager) touch(path string) bool {
+	name, ok := m.entryName(path)
+	if !ok {
+		return false
+	}
+
+	m.storage.mu.Lock()
+	defer m.storage.mu.Unlock()
+
+	entry, ok := m.storage.entries[name]
+	if ok {
+		entry.lastUsed = time.Now()
+	}
+
+	return ok
+}
+
+// markUsed records the use of the storage entry, and evicts least recently used entries if the storage is over the limit.
+//
+// The path should be a top-level entry in the storage directory.
+func (m *Manager) markUsed(path string) {
+	if m.touch(path) {
+		return
+	}
+
+	name, ok := m.entryName(path)
+	if !ok {
+		return
+	}
+
+	// new entry, calculate its size
+	size, err := diskUsage(path)
+	if err != nil {
+		m.logger.Warn("failed to calculate artifact size", zap.String("path", path), zap.Error(err))
+
+		return
+	}
+
+	m.storage.mu.Lock()
+
+	if entry, ok := m.storage.entries[name]; ok {
+		// tracked by the concurrent call
+		entry.lastUsed = time.Now()
+	} else {
+		m.storage.entries[name] = &storageEntry{
+			lastUsed: time.Now(),
+			size:     size,
+		}
+	}
+
+	tombstone := m.evictLocked()
+
+	m.storage.mu.Unlock()
+
+	if tombstone == "" {
+		return
+	}
+
+	if err = os.RemoveAll(tombstone); err != nil {
+		m.logger.Warn("failed to remove evicted artifacts", zap.String("path", tombstone), zap.Error(err))
+	}
+}
+
+// evictLocked evicts least recently used entries to fit into the storage size limit.
+//
+// Entries used recently are never evicted, so the limit is soft.
+//
+// The evicted entries are moved to the tombstone directory, which is returned to be removed without the lock held.
+// The tombstone directory has the temporary suffix, so it's removed on the next start if the removal doesn't complete.
+func (m *Manager) evictLocked() string {
+	if m.options.StorageMaxSize <= 0 {
+		return ""
+	}
+
+	var totalSize int64
+
+	for _, entry := range m.storage.entries {
+		totalSize += entry.size
+	}
+
+	if totalSize <= m.options.StorageMaxSize {
+		return ""
+	}
+
+	names := make([]string, 0, len(m.storage.entries))
+
+	for name := range m.storage.entries {
+		names = append(names, name)
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		return m.storage.entries[a].lastUsed.Compare(m.storage.entries[b].lastUsed)
+	})
+
+	var tombstone string
+
+	for _, name := range names {
+		if totalSize <= m.options.StorageMaxSize {
+			break
+		}
+
+		entry := m.storage.entries[name]
+
+		if time.Since(entry.lastUsed) < evictionGracePeriod {
+			break
+		}
+
+		if tombstone == "" {
+			var err error
+
+			tombstone, err = os.MkdirTemp(m.storagePath, "evicted-*"+tmpSuffix)
+			if err != nil {
+				m.logger.Warn("failed to create the directory for evicted artifacts", zap.Error(err))
+
+				return ""
+			}
+		}
+
+		m.logger.Info("evicting artifact", zap.String("name", name))
+
+		if err := os.Rename(filepath.Join(m.storagePath, name), filepath.Join(tombstone, name)); err != nil {
+			m.logger.Warn("failed to evict artifact", zap.String("name", name), zap.Error(err))
+
+			continue
+		}
+
+		totalSize -= entry.size
+
+		delete(m.storage.entries, name)
+	}
+
+	return tombstone
+}
@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package artifacts_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+)
+
+func writeOCILayout(t *testing.T, path string) {
+	t.Helper()
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	l, err := layout.Write(path, empty.Index)
+	require.NoError(t, err)
+
+	require.NoError(t, l.AppendImage(img))
+}
+
+func TestPersistentStorageValidation(t *testing.T) {
+	t.Parallel()
+
+	storagePath := t.TempDir()
+
+	// valid OCI layout
+	writeOCILayout(t, filepath.Join(storagePath, "amd64-sha256:valid"))
+
+	// corrupted OCI layout
+	corruptedPath := filepath.Join(storagePath, "amd64-sha256:corrupted")
+	writeOCILayout(t, corruptedPath)
+
+	blobs, err := filepath.Glob(filepath.Join(corruptedPath, "blobs", "sha256", "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, blobs)
+
+	for _, blob := range blobs {
+		require.NoError(t, os.WriteFile(blob, []byte("corrupted"), 0o644))
+	}
+
+	// incomplete fetch
+	writeOCILayout(t, filepath.Join(storagePath, "amd64-sha256:incomplete-tmp"))
+
+	// extracted image without checksums
+	require.NoError(t, os.MkdirAll(filepath.Join(storagePath, "v1.7.0", "amd64"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(storagePath, "v1.7.0", "amd64", "vmlinuz"), []byte("kernel"), 0o644))
+
+	// stale schematic extensions
+	require.NoError(t, os.MkdirAll(filepath.Join(storagePath, "schematics"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(storagePath, "schematics", "stale.tar"), []byte("stale"), 0o644))
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry: "ghcr.io",
+		StoragePath:   storagePath,
+	})
+	require.NoError(t, err)
+
+	assert.DirExists(t, filepath.Join(storagePath, "amd64-sha256:valid"))
+	assert.NoDirExists(t, corruptedPath)
+	assert.NoDirExists(t, filepath.Join(storagePath, "amd64-sha256:incomplete-tmp"))
+	assert.NoDirExists(t, filepath.Join(storagePath, "v1.7.0"))
+	assert.NoFileExists(t, filepath.Join(storagePath, "schematics", "stale.tar"))
+	assert.DirExists(t, filepath.Join(storagePath, "schematics"))
+
+	// persistent storage is kept on close
+	require.NoError(t, manager.Close())
+
+	assert.DirExists(t, filepath.Join(storagePath, "amd64-sha256:valid"))
+}
+
+func TestTemporaryStorage(t *testing.T) {
+	t.Parallel()
+
+	manager, err := artifacts.NewManager(zaptest.NewLogger(t), artifacts.Options{
+		ImageRegistry: "ghcr.io",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Close())
+}
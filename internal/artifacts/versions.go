@@ -11,13 +11,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"slices"
 	"strings"
 	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-containerregistry/pkg/name"
-	"github.com/siderolabs/gen/xslices"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
@@ -30,7 +28,7 @@ func (m *Manager) fetchTalosVersions() (any, error) {
 
 	repository := m.imageRegistry.Repo(ImagerImage)
 
-	candidates, err := m.pullers[ArchAmd64].List(ctx, repository)
+	candidates, err := m.source.List(ctx, repository)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Talos versions: %w", err)
 	}
@@ -46,37 +44,7 @@ func (m *Manager) fetchTalosVersions() (any, error) {
 		versions = append(versions, version)
 	}
 
-	// find "current" maximum version
-	maxVersion := slices.MaxFunc(versions, semver.Version.Compare)
-
-	// allow non-prerelease versions, and allow pre-release for the "latest" release (maxVersion)
-	versions = xslices.Filter(versions, func(version semver.Version) bool {
-		if version.LT(m.options.MinVersion) {
-			return false // ignore versions below minimum
-		}
-
-		if len(version.Pre) > 0 {
-			if version.Major != maxVersion.Major || version.Minor != maxVersion.Minor {
-				return false // ignore pre-releases for older versions
-			}
-
-			if len(version.Pre) != 2 {
-				return false
-			}
-
-			if version.Pre[0].VersionStr != "alpha" && version.Pre[0].VersionStr != "beta" {
-				return false
-			}
-
-			if !version.Pre[1].IsNumeric() {
-				return false
-			}
-		}
-
-		return true
-	})
-
-	slices.SortFunc(versions, semver.Version.Compare)
+	versions = m.versionPolicy.filter(versions, m.options.MinVersion)
 
 	m.talosVersionsMu.Lock()
 	m.talosVersions, m.talosVersionsTimestamp = versions, time.Now()
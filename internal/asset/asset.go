@@ -12,20 +12,24 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"time"
 
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/siderolabs/talos/pkg/imager"
+	"github.com/siderolabs/gen/xerrors"
 	"github.com/siderolabs/talos/pkg/imager/profile"
 	"github.com/siderolabs/talos/pkg/machinery/imager/quirks"
-	"github.com/siderolabs/talos/pkg/reporter"
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 
 	"github.com/siderolabs/image-factory/internal/artifacts"
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
 	"github.com/siderolabs/image-factory/internal/image/signer"
+	"github.com/siderolabs/image-factory/internal/objectstore"
 	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
 	"github.com/siderolabs/image-factory/internal/remotewrap"
 )
@@ -37,60 +41,169 @@ import (
 type BootAsset interface {
 	Size() int64
 	Reader() (io.ReadCloser, error)
+	// RangeReader returns a reader for the part of the asset starting at the offset.
+	RangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+	// Checksums returns the checksums of the asset.
+	Checksums(ctx context.Context) (Checksums, error)
+	// BuildInputs returns the inputs the asset was built from, as recorded at build time.
+	//
+	// The inputs are not available for the assets cached before they were recorded.
+	BuildInputs() (factoryprofile.BuildInputs, bool)
+}
+
+// RedirectableAsset is the cached boot asset which can be downloaded directly from the cache storage.
+type RedirectableAsset interface {
+	BootAsset
+	// RedirectURL returns the URL to download the asset from the cache storage, empty if the storage doesn't support it.
+	//
+	// The response headers are overridden with the content type and disposition, if the storage supports it.
+	RedirectURL(ctx context.Context, contentType, contentDisposition string) (string, error)
+}
+
+// BuildAdmitter decides whether a fresh build (asset missing in the cache) can be started for the request.
+type BuildAdmitter interface {
+	AdmitBuild(ctx context.Context) error
 }
 
 // Builder is the asset builder.
 type Builder struct {
 	logger           *zap.Logger
-	cache            *registryCache
+	cache            cacheBackend
 	artifactsManager *artifacts.Manager
+	talosVersions    func(context.Context) ([]semver.Version, error)
+	admitter         BuildAdmitter
+	remote           RemoteBuilder
+	leaseHolder      string
+	imagerCommand    []string
+	leaseTTL         time.Duration
 	sf               singleflight.Group
-	semaphore        chan struct{}
+	escalations      buildEscalations
+	scheduler        *scheduler.Scheduler
+	jobs             jobTracker
+	buildLogs        *buildLogStore
+	diskCache        *diskCache
+	access           accessTracker
 
 	metricAssetsCached, metricAssetsBuilt         *prometheus.CounterVec
 	metricAssetBytesCached, metricAssetBytesBuilt *prometheus.CounterVec
 	metricConcurrencyLatency, metricBuildLatency  prometheus.Histogram
+	metricGCRemovedAssets, metricGCRemovedBytes   prometheus.Counter
 }
 
 // Options configures the asset builder.
 type Options struct {
+	// RemoteKeychain is used to authenticate to the cache repository, both the pulls and pushes, and the ranged requests.
+	RemoteKeychain authn.Keychain
+
 	CacheRepository         name.Repository
 	CacheSigningKey         crypto.PrivateKey
-	RemoteOptions           []remote.Option
 	RegistryRefreshInterval time.Duration
 
+	// RemoteOptions are the additional options for the pulls and pushes of the cache repository.
+	RemoteOptions []remote.Option
+
+	// CacheStoragePath (optional) stores the cached assets in the directory instead of the cache repository.
+	//
+	// The directory might be shared between the replicas (e.g. NFS mount).
+	CacheStoragePath string
+	// CacheObjectStore (optional) stores the cached assets in the S3-compatible object store instead of the cache repository.
+	CacheObjectStore *objectstore.Client
+
+	// BuildAdmitter (optional) is consulted before starting a fresh build.
+	BuildAdmitter BuildAdmitter
+	// RemoteBuilder (optional) runs the builds on the remote workers instead of building locally.
+	RemoteBuilder RemoteBuilder
+
+	// DiskCachePath (optional) is the directory of the local disk cache in front of the cache repository.
+	//
+	// The assets served from the cache repository are copied to the disk, so that the next requests are served locally.
+	DiskCachePath string
+	// DiskCacheMaxSize is the maximum size of the local disk cache in bytes, zero means no limit.
+	//
+	// Least recently used assets are evicted when the limit is exceeded.
+	DiskCacheMaxSize int64
+
+	// BuildLeaseTTL is the TTL of the build lease shared across the replicas via the cache repository, zero disables leases.
+	//
+	// The replica holding the lease builds the asset, while other replicas wait for the asset to appear in the cache.
+	BuildLeaseTTL time.Duration
+
 	AllowedConcurrency int
+	// MaxQueueLength is the maximum number of builds waiting for the available workers, zero means no limit.
+	MaxQueueLength int
+
+	// ImagerCommand (optional) is the command which runs the Talos imager in the subprocess (see RunImager),
+	// the output path is appended to it, e.g. the factory binary with the imager subcommand.
+	//
+	// The output of the imager in the subprocess is captured into the build log.
+	// If not set, the imager runs in the factory process, and its output only goes to the process stderr.
+	ImagerCommand []string
 }
 
 // NewBuilder creates a new asset builder.
 func NewBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager, options Options) (*Builder, error) {
-	cache := &registryCache{
-		cacheRepository: options.CacheRepository,
-		logger:          logger.With(zap.String("component", "asset-cache")),
+	cache, err := newCacheBackend(logger.With(zap.String("component", "asset-cache")), options)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
+	return newBuilder(logger, artifactsManager, cache, options)
+}
 
-	cache.puller, err = remotewrap.NewPuller(options.RegistryRefreshInterval, options.RemoteOptions...)
+// newBuilder creates a new asset builder with the cache backend.
+func newBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager, cache cacheBackend, options Options) (*Builder, error) {
+	leaseHolder, err := newLeaseHolder()
 	if err != nil {
-		return nil, fmt.Errorf("error creating puller: %w", err)
+		return nil, fmt.Errorf("error generating lease holder: %w", err)
 	}
 
-	cache.pusher, err = remotewrap.NewPusher(options.RegistryRefreshInterval, options.RemoteOptions...)
-	if err != nil {
-		return nil, fmt.Errorf("error creating pusher: %w", err)
+	var localCache *diskCache
+
+	if options.DiskCachePath != "" {
+		localCache, err = newDiskCache(logger.With(zap.String("component", "asset-disk-cache")), options.DiskCachePath, options.DiskCacheMaxSize,
+			func(ctx context.Context, profileID string) error {
+				_, inspectErr := cache.Inspect(ctx, profileID)
+
+				return inspectErr
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error creating disk cache: %w", err)
+		}
 	}
 
-	cache.imageSigner, err = signer.NewSigner(options.CacheSigningKey)
-	if err != nil {
-		return nil, fmt.Errorf("error creating signer: %w", err)
+	var talosVersions func(context.Context) ([]semver.Version, error)
+
+	if artifactsManager != nil {
+		talosVersions = artifactsManager.GetTalosVersions
 	}
 
 	return &Builder{
 		logger:           logger.With(zap.String("component", "asset-builder")),
 		cache:            cache,
 		artifactsManager: artifactsManager,
-		semaphore:        make(chan struct{}, options.AllowedConcurrency),
+		talosVersions:    talosVersions,
+		admitter:         options.BuildAdmitter,
+		remote:           options.RemoteBuilder,
+		leaseHolder:      leaseHolder,
+		leaseTTL:         options.BuildLeaseTTL,
+		imagerCommand:    options.ImagerCommand,
+		scheduler: scheduler.New(scheduler.Options{
+			Concurrency:    options.AllowedConcurrency,
+			MaxQueueLength: options.MaxQueueLength,
+		}),
+		jobs: jobTracker{
+			jobs:    map[string]*Job{},
+			running: map[string]struct{}{},
+		},
+		escalations: buildEscalations{
+			entries: map[string]*buildEscalation{},
+		},
+		buildLogs: newBuildLogStore(),
+		diskCache: localCache,
+		access: accessTracker{
+			updated: map[string]time.Time{},
+		},
 
 		metricAssetsCached: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -134,6 +247,18 @@ func NewBuilder(logger *zap.Logger, artifactsManager *artifacts.Manager, options
 				Buckets: []float64{1, 10, 60, 180, 600},
 			},
 		),
+		metricGCRemovedAssets: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "image_factory_cache_gc_removed_assets_total",
+				Help: "Number of cached assets removed by the garbage collection.",
+			},
+		),
+		metricGCRemovedBytes: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "image_factory_cache_gc_removed_bytes_total",
+				Help: "Number of bytes of cached assets removed by the garbage collection.",
+			},
+		),
 	}, nil
 }
 
@@ -145,21 +270,83 @@ func (b *Builder) getBuildAsset(ctx context.Context, versionString, arch string,
 	return err
 }
 
+// newCacheBackend creates the cache backend: the cache repository, unless the cache storage is configured.
+func newCacheBackend(logger *zap.Logger, options Options) (cacheBackend, error) {
+	imageSigner, err := signer.NewSigner(options.CacheSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating signer: %w", err)
+	}
+
+	switch {
+	case options.CacheStoragePath != "":
+		store, storeErr := newFilesystemStore(options.CacheStoragePath)
+		if storeErr != nil {
+			return nil, storeErr
+		}
+
+		return &blobCache{
+			store:       store,
+			imageSigner: imageSigner,
+			logger:      logger,
+		}, nil
+	case options.CacheObjectStore != nil:
+		return &blobCache{
+			store:       objectStore{client: options.CacheObjectStore},
+			imageSigner: imageSigner,
+			logger:      logger,
+		}, nil
+	}
+
+	keychain := options.RemoteKeychain
+	if keychain == nil {
+		keychain = authn.DefaultKeychain
+	}
+
+	cache := &registryCache{
+		cacheRepository: options.CacheRepository,
+		imageSigner:     imageSigner,
+		logger:          logger,
+		rangeFetcher:    remotewrap.NewBlobRangeFetcher(options.RegistryRefreshInterval, keychain),
+		checksums:       newComputedChecksums(),
+	}
+
+	// the ranged requests use the same credentials as the pulls and pushes
+	remoteOptions := slices.Concat([]remote.Option{remote.WithAuthFromKeychain(keychain)}, options.RemoteOptions)
+
+	cache.puller, err = remotewrap.NewPuller(options.RegistryRefreshInterval, remoteOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating puller: %w", err)
+	}
+
+	cache.pusher, err = remotewrap.NewPusher(options.RegistryRefreshInterval, remoteOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pusher: %w", err)
+	}
+
+	return cache, nil
+}
+
 // Build the asset.
 //
-// First, check if the asset has already been built and cached then use the cached version.
+// First, check if the asset has already been built and cached (in the local disk cache, then in the cache repository) then use the cached version.
 // If the asset hasn't been built yet, build it and cache it honoring the concurrency limit, and push it to the cache.
-func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString string) (BootAsset, error) {
+//
+// The build is scheduled with the priority and for the client attached to the context (see WithPriority, WithClient).
+// If the build is already in flight, it is raised to the priority of the caller.
+// If the remote builder is configured, the asset is built by a remote worker from the source.
+func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString string, source Source) (BootAsset, error) {
 	profileHash, err := factoryprofile.Hash(prof)
 	if err != nil {
 		return nil, err
 	}
 
-	asset, err := b.cache.Get(ctx, profileHash)
+	asset, err := b.getCached(ctx, profileHash)
 	if err == nil {
 		b.metricAssetsCached.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Inc()
 		b.metricAssetBytesCached.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Add(float64(asset.Size()))
 
+		b.touch(profileHash)
+
 		return asset, nil
 	}
 
@@ -167,9 +354,27 @@ func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString
 		return nil, fmt.Errorf("error getting asset from cache: %w", err)
 	}
 
+	if b.admitter != nil {
+		if err = b.admitter.AdmitBuild(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req := scheduleRequest(ctx)
+
+	// the build might be already in flight with a lower priority (e.g. the asynchronous build), so raise it to the priority of the caller
+	_, leave := b.escalations.join(profileHash, req.Priority)
+	defer leave()
+
 	// nothing in cache, so build the asset, but make sure we do it only once
 	ch := b.sf.DoChan(profileHash, func() (any, error) { //nolint:contextcheck
-		return b.buildAndCache(profileHash, prof, versionString)
+		escalation, leaveBuild := b.escalations.join(profileHash, req.Priority)
+		defer leaveBuild()
+
+		buildReq := req
+		buildReq.Escalation = escalation
+
+		return b.buildAndCache(buildReq, profileHash, prof, versionString, source)
 	})
 
 	select {
@@ -193,12 +398,72 @@ func (b *Builder) Build(ctx context.Context, prof profile.Profile, versionString
 }
 
 // buildAndCache builds the asset and pushes it to the cache.
-func (b *Builder) buildAndCache(profileHash string, prof profile.Profile, versionString string) (BootAsset, error) {
+//
+// The build lease is held while building, so that other replicas wait for the asset instead of building it.
+// Remotely built assets are pushed to the cache by the worker.
+//
+// The build is logged to the build log, and the build errors carry the excerpt of the log.
+func (b *Builder) buildAndCache(req scheduler.Request, profileHash string, prof profile.Profile, versionString string, source Source) (BootAsset, error) {
+	log := b.buildLogs.New(profileHash, source.SchematicID)
+
+	asset, err := b.buildAndCacheLogged(req, profileHash, prof, versionString, source, log)
+	if xerrors.TagIs[scheduler.QueueFullErrorTag](err) {
+		// the build didn't start
+		return nil, err
+	}
+
+	if err != nil {
+		log.logger(b.logger).Error("asset build failed", zap.String("profile_hash", profileHash), zap.Error(err))
+
+		return nil, &BuildError{
+			Err:         err,
+			ProfileHash: profileHash,
+			LogExcerpt:  log.Excerpt(buildLogExcerptLines),
+		}
+	}
+
+	return asset, nil
+}
+
+func (b *Builder) buildAndCacheLogged(req scheduler.Request, profileHash string, prof profile.Profile, versionString string, source Source, log *buildLog) (BootAsset, error) {
 	// detach the context to make sure the asset is built no matter if the request is canceled
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), artifacts.BuildTimeout)
 	defer cancel()
 
-	asset, err := b.build(ctx, prof, versionString)
+	defer b.jobs.setRunning(profileHash, false)
+
+	logger := log.logger(b.logger)
+
+	releaseLease, cached, err := b.acquireLease(ctx, logger, profileHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil {
+		// built by another replica
+		b.metricAssetsCached.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Inc()
+		b.metricAssetBytesCached.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Add(float64(cached.Size()))
+
+		return cached, nil
+	}
+
+	defer releaseLease()
+
+	var asset BootAsset
+
+	if b.remote != nil {
+		asset, err = b.buildRemote(ctx, logger, req, profileHash, versionString, source, log)
+		if err != nil {
+			return nil, err
+		}
+
+		b.metricAssetsBuilt.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Inc()
+		b.metricAssetBytesBuilt.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Add(float64(asset.Size()))
+
+		return asset, nil
+	}
+
+	asset, err = b.build(ctx, logger, req, profileHash, prof, versionString, source, log)
 	if err != nil {
 		return nil, err
 	}
@@ -206,8 +471,8 @@ func (b *Builder) buildAndCache(profileHash string, prof profile.Profile, versio
 	b.metricAssetsBuilt.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Inc()
 	b.metricAssetBytesBuilt.WithLabelValues(versionString, prof.Output.Kind.String(), prof.Arch).Add(float64(asset.Size()))
 
-	if err = b.cache.Put(ctx, profileHash, asset); err != nil {
-		b.logger.Error("error putting asset to cache", zap.Error(err), zap.String("profile_hash", profileHash))
+	if err = b.cache.Put(ctx, profileHash, asset, cacheAnnotations(prof, versionString, source, asset)); err != nil {
+		logger.Error("error putting asset to cache", zap.Error(err), zap.String("profile_hash", profileHash))
 	}
 
 	return asset, nil
@@ -215,60 +480,81 @@ func (b *Builder) buildAndCache(profileHash string, prof profile.Profile, versio
 
 // build the asset using Talos imager.
 //
-// A concurrency limit is enforced.
-func (b *Builder) build(ctx context.Context, prof profile.Profile, versionString string) (BootAsset, error) {
+// A concurrency limit is enforced by the scheduler.
+//
+// The build inputs picked when the profile was enhanced are recorded with the asset (with the imager reference),
+// so that the SBOM describes the actual build.
+// The imager output is appended to the build log, see runImager.
+func (b *Builder) build(
+	ctx context.Context,
+	logger *zap.Logger,
+	req scheduler.Request,
+	profileHash string,
+	prof profile.Profile,
+	versionString string,
+	source Source,
+	log io.Writer,
+) (BootAsset, error) {
 	start := time.Now()
 
 	// enforce concurrency limit
-	select {
-	case b.semaphore <- struct{}{}:
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	release, err := b.scheduler.Acquire(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	defer func() {
-		<-b.semaphore
-	}()
+	defer release()
+
+	// the job stays running until the asset is pushed to the cache, see buildAndCache
+	b.jobs.setRunning(profileHash, true)
 
 	concurrencyLatency := time.Since(start)
-	b.logger.Info("building image asset", zap.Any("profile", prof), zap.String("version", versionString), zap.Duration("concurrency_latency", concurrencyLatency))
+	logger.Info("building image asset", zap.Any("profile", prof), zap.String("version", versionString), zap.Duration("concurrency_latency", concurrencyLatency))
 	b.metricConcurrencyLatency.Observe(concurrencyLatency.Seconds())
 
-	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindKernel, &prof.Input.Kernel); err != nil {
+	if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindKernel, &prof.Input.Kernel); err != nil {
 		return nil, fmt.Errorf("failed to get kernel: %w", err)
 	}
 
-	if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindInitramfs, &prof.Input.Initramfs); err != nil {
+	if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindInitramfs, &prof.Input.Initramfs); err != nil {
 		return nil, fmt.Errorf("failed to get initramfs: %w", err)
 	}
 
 	if quirks.New(versionString).SupportsUKI() {
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdBoot, &prof.Input.SDBoot); err != nil {
+		if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdBoot, &prof.Input.SDBoot); err != nil {
 			return nil, fmt.Errorf("failed to get systemd-boot: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdStub, &prof.Input.SDStub); err != nil {
+		if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindSystemdStub, &prof.Input.SDStub); err != nil {
 			return nil, fmt.Errorf("failed to get systemd-stub: %w", err)
 		}
 	}
 
 	if prof.Arch == string(artifacts.ArchArm64) && !quirks.New(versionString).SupportsOverlay() {
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindDTB, &prof.Input.DTB); err != nil {
+		if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindDTB, &prof.Input.DTB); err != nil {
 			return nil, fmt.Errorf("failed to get dtb: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindUBoot, &prof.Input.UBoot); err != nil {
+		if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindUBoot, &prof.Input.UBoot); err != nil {
 			return nil, fmt.Errorf("failed to get u-boot: %w", err)
 		}
 
-		if err := b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindRPiFirmware, &prof.Input.RPiFirmware); err != nil {
+		if err = b.getBuildAsset(ctx, versionString, prof.Arch, artifacts.KindRPiFirmware, &prof.Input.RPiFirmware); err != nil {
 			return nil, fmt.Errorf("failed to get rpi firmware: %w", err)
 		}
 	}
 
-	imgr, err := imager.New(prof)
-	if err != nil {
-		return nil, err
+	var inputs *factoryprofile.BuildInputs
+
+	if source.Inputs != nil {
+		resolved := *source.Inputs
+
+		// the imager is only used by the build
+		if resolved.ImagerRef, err = b.artifactsManager.GetImagerRef(ctx, "v"+versionString); err != nil {
+			return nil, fmt.Errorf("error getting imager reference: %w", err)
+		}
+
+		inputs = &resolved
 	}
 
 	tmpDir, err := newTmpDir()
@@ -276,7 +562,11 @@ func (b *Builder) build(ctx context.Context, prof profile.Profile, versionString
 		return nil, err
 	}
 
-	tmpDir.assetPath, err = imgr.Execute(ctx, tmpDir.directoryPath, reporter.New())
+	tmpDir.recordedInputs = recordedInputs{inputs: inputs}
+
+	logger.Info("running imager", zap.String("output", prof.Output.Kind.String()))
+
+	tmpDir.assetPath, err = b.runImager(ctx, prof, tmpDir.directoryPath, log)
 	if err != nil {
 		return nil, fmt.Errorf("error generating asset: %w", err)
 	}
@@ -288,8 +578,12 @@ func (b *Builder) build(ctx context.Context, prof profile.Profile, versionString
 
 	tmpDir.size = st.Size()
 
+	if err = tmpDir.computeChecksums(); err != nil {
+		return nil, fmt.Errorf("error computing asset checksums: %w", err)
+	}
+
 	buildLatency := time.Since(start) - concurrencyLatency
-	b.logger.Info("finished building image asset", zap.Any("profile", prof), zap.String("version", versionString), zap.Duration("build_latency", buildLatency))
+	logger.Info("finished building image asset", zap.Any("profile", prof), zap.String("version", versionString), zap.Duration("build_latency", buildLatency))
 	b.metricBuildLatency.Observe(buildLatency.Seconds())
 
 	return tmpDir, nil
@@ -310,6 +604,15 @@ func (b *Builder) Collect(ch chan<- prometheus.Metric) {
 
 	b.metricBuildLatency.Collect(ch)
 	b.metricConcurrencyLatency.Collect(ch)
+
+	b.metricGCRemovedAssets.Collect(ch)
+	b.metricGCRemovedBytes.Collect(ch)
+
+	if b.diskCache != nil {
+		b.diskCache.Collect(ch)
+	}
+
+	b.scheduler.Collect(ch)
 }
 
 var _ prometheus.Collector = &Builder{}
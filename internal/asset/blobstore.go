@@ -0,0 +1,260 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/siderolabs/image-factory/internal/objectstore"
+)
+
+// blobStore stores the blobs by the key, see blobCache.
+type blobStore interface {
+	// Get returns the reader for the part of the blob starting at the offset, negative length reads to the end.
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Size returns the size of the blob.
+	Size(ctx context.Context, key string) (int64, error)
+	// Put stores the blob, the readers never see the partially written blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Move replaces the blob with the other blob of the given size, the readers never see the partially written blob.
+	Move(ctx context.Context, fromKey, toKey string, size int64) error
+	// Delete removes the blob, the blob which doesn't exist is ignored.
+	Delete(ctx context.Context, key string) error
+	// List returns all blobs in the store.
+	List(ctx context.Context) ([]blobInfo, error)
+}
+
+// blobPresigner is implemented by the blob stores which can serve the blobs directly to the clients.
+type blobPresigner interface {
+	// PresignGet returns the pre-signed URL to download the blob with the extra query parameters.
+	PresignGet(ctx context.Context, key string, expires time.Duration, query url.Values) (string, error)
+}
+
+// blobInfo describes the blob in the store.
+type blobInfo struct {
+	modified time.Time
+	key      string
+}
+
+// errBlobNotFound is returned by the blob store for the blob which doesn't exist.
+var errBlobNotFound = errors.New("blob not found")
+
+// Check interface.
+var (
+	_ blobStore     = (*filesystemStore)(nil)
+	_ blobStore     = objectStore{}
+	_ blobPresigner = objectStore{}
+)
+
+// filesystemStore stores the blobs as the files in the directory.
+//
+// The directory might be shared between the replicas (e.g. NFS mount), the blobs are written
+// to the temporary files first and renamed into place.
+type filesystemStore struct {
+	path string
+}
+
+// filesystemTmpPrefix is the prefix of the temporary files being written.
+const filesystemTmpPrefix = ".tmp-"
+
+func newFilesystemStore(path string) (*filesystemStore, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache storage directory: %w", err)
+	}
+
+	return &filesystemStore{path: path}, nil
+}
+
+func (s *filesystemStore) Get(_ context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.path, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errBlobNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close() //nolint:errcheck
+
+		return nil, err
+	}
+
+	if length < 0 {
+		return f, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.LimitReader(f, length),
+		Closer: f,
+	}, nil
+}
+
+func (s *filesystemStore) Size(_ context.Context, key string) (int64, error) {
+	st, err := os.Stat(filepath.Join(s.path, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, errBlobNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return st.Size(), nil
+}
+
+func (s *filesystemStore) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	f, err := os.CreateTemp(s.path, filesystemTmpPrefix+key+"-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name()) //nolint:errcheck
+	defer f.Close()           //nolint:errcheck
+
+	written, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+
+	if written != size {
+		return fmt.Errorf("size mismatch: expected %d, got %d", size, written)
+	}
+
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	// temporary files are created with 0600 permissions
+	if err = os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(s.path, key))
+}
+
+func (s *filesystemStore) Move(_ context.Context, fromKey, toKey string, _ int64) error {
+	return os.Rename(filepath.Join(s.path, fromKey), filepath.Join(s.path, toKey))
+}
+
+func (s *filesystemStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.path, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *filesystemStore) List(context.Context) ([]blobInfo, error) {
+	dirEntries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make([]blobInfo, 0, len(dirEntries))
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.Type().IsRegular() || strings.HasPrefix(dirEntry.Name(), filesystemTmpPrefix) {
+			continue
+		}
+
+		info, infoErr := dirEntry.Info()
+		if errors.Is(infoErr, fs.ErrNotExist) {
+			// removed concurrently
+			continue
+		}
+
+		if infoErr != nil {
+			return nil, infoErr
+		}
+
+		blobs = append(blobs, blobInfo{
+			key:      dirEntry.Name(),
+			modified: info.ModTime(),
+		})
+	}
+
+	return blobs, nil
+}
+
+// objectStore stores the blobs as the objects in the S3-compatible object store.
+type objectStore struct {
+	client *objectstore.Client
+}
+
+func (s objectStore) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	rc, err := s.client.Get(ctx, key, offset, length)
+	if objectstore.IsNotFound(err) {
+		return nil, errBlobNotFound
+	}
+
+	return rc, err
+}
+
+func (s objectStore) Size(ctx context.Context, key string) (int64, error) {
+	info, err := s.client.Head(ctx, key)
+	if objectstore.IsNotFound(err) {
+		return 0, errBlobNotFound
+	}
+
+	return info.Size, err
+}
+
+func (s objectStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	return s.client.Put(ctx, key, r, size)
+}
+
+// Move copies the object, as the object stores can't rename the objects.
+func (s objectStore) Move(ctx context.Context, fromKey, toKey string, size int64) error {
+	if err := s.client.Copy(ctx, fromKey, toKey, size); err != nil {
+		return err
+	}
+
+	return s.client.Delete(ctx, fromKey)
+}
+
+func (s objectStore) Delete(ctx context.Context, key string) error {
+	return s.client.Delete(ctx, key)
+}
+
+func (s objectStore) PresignGet(ctx context.Context, key string, expires time.Duration, query url.Values) (string, error) {
+	return s.client.PresignGet(ctx, key, expires, query)
+}
+
+func (s objectStore) List(ctx context.Context) ([]blobInfo, error) {
+	objects, err := s.client.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make([]blobInfo, 0, len(objects))
+
+	for _, object := range objects {
+		blobs = append(blobs, blobInfo{
+			key:      object.Key,
+			modified: object.LastModified,
+		})
+	}
+
+	return blobs, nil
+}
@@ -0,0 +1,194 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// buildLogMaxSize is the maximum size of a single build log, only the tail of the log is kept.
+	buildLogMaxSize = 64 * 1024
+	// buildLogMaxEntries is the maximum number of build logs kept.
+	buildLogMaxEntries = 1024
+	// buildLogExcerptLines is the number of the last log lines included into the build error.
+	buildLogExcerptLines = 20
+)
+
+// BuildError is the error of the failed build, it carries the excerpt of the build log.
+type BuildError struct {
+	Err         error
+	ProfileHash string
+	// LogExcerpt is the tail of the build log.
+	LogExcerpt string
+}
+
+// Error implements error interface.
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("error building asset %s: %s", e.ProfileHash, e.Err)
+}
+
+// Unwrap implements errors.Unwrap interface.
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// buildLog is the log of a single build.
+//
+// The log has the messages of the factory itself for the build (inputs, cache, remote worker),
+// and the output of the Talos imager when it runs in the subprocess (see Options.ImagerCommand).
+//
+// The log is bounded, only the tail of the log is kept.
+type buildLog struct {
+	// schematicID is the schematic the asset was built for, it's used to authorize the access to the log.
+	schematicID string
+	buf         []byte
+	truncated   bool
+	mu          sync.Mutex
+}
+
+// Write implements io.Writer.
+func (l *buildLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+
+	if len(l.buf) > buildLogMaxSize {
+		tail := l.buf[len(l.buf)-buildLogMaxSize:]
+
+		// cut at the line boundary
+		if idx := bytes.IndexByte(tail, '\n'); idx != -1 {
+			tail = tail[idx+1:]
+		}
+
+		l.buf = append(l.buf[:0], tail...)
+		l.truncated = true
+	}
+
+	return len(p), nil
+}
+
+// String returns the log contents.
+func (l *buildLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.truncated {
+		return "[...]\n" + string(l.buf)
+	}
+
+	return string(l.buf)
+}
+
+// Excerpt returns the last lines of the log.
+func (l *buildLog) Excerpt(lines int) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	log := strings.TrimRight(string(l.buf), "\n")
+
+	idx := len(log)
+
+	for range lines {
+		idx = strings.LastIndexByte(log[:idx], '\n')
+		if idx == -1 {
+			return log
+		}
+	}
+
+	return log[idx+1:]
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (l *buildLog) Sync() error {
+	return nil
+}
+
+// logger returns the logger which writes both to the parent logger and to the build log.
+func (l *buildLog) logger(parent *zap.Logger) *zap.Logger {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	return parent.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), l, zapcore.DebugLevel))
+	}))
+}
+
+// buildLogStore keeps the logs of the recent builds by the profile hash.
+//
+// When the store is full, the oldest logs are evicted.
+type buildLogStore struct {
+	m  map[string]*list.Element
+	l  *list.List
+	mu sync.Mutex
+}
+
+type buildLogEntry struct {
+	log         *buildLog
+	profileHash string
+}
+
+func newBuildLogStore() *buildLogStore {
+	return &buildLogStore{
+		m: map[string]*list.Element{},
+		l: list.New(),
+	}
+}
+
+// New starts a new log for the build, replacing the previous log of the same profile.
+func (s *buildLogStore) New(profileHash, schematicID string) *buildLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.m[profileHash]; ok {
+		s.l.Remove(elem)
+	}
+
+	log := &buildLog{schematicID: schematicID}
+
+	s.m[profileHash] = s.l.PushBack(&buildLogEntry{profileHash: profileHash, log: log})
+
+	for s.l.Len() > buildLogMaxEntries {
+		oldest := s.l.Remove(s.l.Front()).(*buildLogEntry) //nolint:forcetypeassert,errcheck
+
+		delete(s.m, oldest.profileHash)
+	}
+
+	return log
+}
+
+// Get returns the log of the build.
+func (s *buildLogStore) Get(profileHash string) (*buildLog, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.m[profileHash]
+	if !ok {
+		return nil, false
+	}
+
+	return elem.Value.(*buildLogEntry).log, true //nolint:forcetypeassert,errcheck
+}
+
+// BuildLog returns the log of the latest build of the asset by the profile hash, and the schematic ID the asset was built for.
+//
+// Only the builds performed by this replica are available.
+func (b *Builder) BuildLog(profileHash string) (log, schematicID string, err error) {
+	buildLog, ok := b.buildLogs.Get(profileHash)
+	if !ok {
+		return "", "", xerrors.NewTaggedf[ErrNotFoundTag]("build log %q not found", profileHash)
+	}
+
+	return buildLog.String(), buildLog.schematicID, nil
+}
@@ -0,0 +1,122 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+func TestBuildLogTruncation(t *testing.T) {
+	t.Parallel()
+
+	log := asset.NewBuildLogStore().New("profile", "schematic")
+
+	line := strings.Repeat("x", 99) + "\n"
+
+	for i := range 2 * asset.BuildLogMaxSize / len(line) {
+		_, err := fmt.Fprintf(log, "%05d%s", i, line[5:])
+		require.NoError(t, err)
+	}
+
+	contents := log.String()
+
+	require.True(t, strings.HasPrefix(contents, "[...]\n"))
+
+	tail := strings.TrimPrefix(contents, "[...]\n")
+
+	assert.LessOrEqual(t, len(tail), asset.BuildLogMaxSize)
+	assert.Greater(t, len(tail), asset.BuildLogMaxSize-len(line))
+
+	// the log is cut at the line boundary
+	for _, l := range strings.Split(strings.TrimSuffix(tail, "\n"), "\n") {
+		assert.Len(t, l, len(line)-1)
+	}
+
+	// the tail is kept
+	assert.True(t, strings.HasSuffix(tail, fmt.Sprintf("%05d%s", 2*asset.BuildLogMaxSize/len(line)-1, line[5:])))
+}
+
+func TestBuildLogExcerpt(t *testing.T) {
+	t.Parallel()
+
+	log := asset.NewBuildLogStore().New("profile", "schematic")
+
+	assert.Empty(t, log.String())
+	assert.Empty(t, log.Excerpt(3))
+
+	_, err := log.Write([]byte("first\nsecond\n"))
+	require.NoError(t, err)
+
+	// fewer lines than requested
+	assert.Equal(t, "first\nsecond", log.Excerpt(3))
+
+	_, err = log.Write([]byte("third\nfourth\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "second\nthird\nfourth", log.Excerpt(3))
+	assert.Equal(t, "fourth", log.Excerpt(1))
+	assert.Equal(t, "first\nsecond\nthird\nfourth\n", log.String())
+}
+
+func TestBuildLogStoreEviction(t *testing.T) {
+	t.Parallel()
+
+	store := asset.NewBuildLogStore()
+
+	first := store.New("profile-0", "schematic")
+
+	_, err := first.Write([]byte("first build\n"))
+	require.NoError(t, err)
+
+	// the new build replaces the log of the same profile
+	second := store.New("profile-0", "schematic")
+
+	_, err = second.Write([]byte("second build\n"))
+	require.NoError(t, err)
+
+	log, ok := store.Get("profile-0")
+	require.True(t, ok)
+	assert.Equal(t, "second build\n", log.String())
+
+	for i := 1; i <= asset.BuildLogMaxEntries; i++ {
+		store.New(fmt.Sprintf("profile-%d", i), "schematic")
+	}
+
+	// the oldest log is evicted
+	_, ok = store.Get("profile-0")
+	assert.False(t, ok)
+
+	for _, profileHash := range []string{"profile-1", fmt.Sprintf("profile-%d", asset.BuildLogMaxEntries)} {
+		_, ok = store.Get(profileHash)
+		assert.True(t, ok, profileHash)
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("imager failed")
+
+	err := fmt.Errorf("wrapped: %w", &asset.BuildError{
+		Err:         cause,
+		ProfileHash: "abcd",
+		LogExcerpt:  "last line",
+	})
+
+	var buildErr *asset.BuildError
+
+	require.ErrorAs(t, err, &buildErr)
+	assert.Equal(t, "last line", buildErr.LogExcerpt)
+	assert.ErrorIs(t, err, cause)
+	assert.EqualError(t, buildErr, "error building asset abcd: imager failed")
+}
@@ -5,12 +5,16 @@
 package asset
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/partial"
@@ -22,17 +26,71 @@ import (
 	"github.com/siderolabs/image-factory/internal/remotewrap"
 )
 
+// cacheBackend stores the built assets signed with the cache signing key.
+//
+// Next to the assets, the backend stores the markers: the build leases and the last access records.
+type cacheBackend interface {
+	// Get returns the boot asset from the cache, errCacheNotFound if it's missing or the signature doesn't validate.
+	Get(ctx context.Context, profileID string) (BootAsset, error)
+	// Put stores and signs the boot asset, the annotations describe the asset (see CacheEntry).
+	Put(ctx context.Context, profileID string, asset BootAsset, annotations map[string]string) error
+
+	// List returns the assets in the cache.
+	List(ctx context.Context) ([]CacheEntry, error)
+	// Inspect returns the cache entry of the asset without verifying the signature, errCacheNotFound if it's missing.
+	Inspect(ctx context.Context, profileID string) (CacheEntry, error)
+	// Delete removes the asset and its signature from the cache.
+	Delete(ctx context.Context, entry CacheEntry) error
+
+	// GetLease returns the build lease for the profile, zero value if there is no lease.
+	GetLease(ctx context.Context, profileID string) (lease, error)
+	// PutLease writes the build lease for the profile.
+	PutLease(ctx context.Context, profileID string, l lease) error
+
+	// GetLastAccess returns the last access time of the asset and the digest of the record, zero value if there is no record.
+	GetLastAccess(ctx context.Context, profileID string) (time.Time, string, error)
+	// PutLastAccess records the last access time of the asset.
+	PutLastAccess(ctx context.Context, profileID string, lastAccess time.Time) error
+	// DeleteLastAccess removes the last access record by the digest, the record updated since is kept.
+	DeleteLastAccess(ctx context.Context, profileID, digest string) error
+
+	// RemoveOrphans removes the markers and the signatures left from the assets which are no longer in the cache.
+	//
+	// The live assets are kept by the garbage collection, so their markers are kept as well.
+	RemoveOrphans(ctx context.Context, live map[string]struct{}, now time.Time, dryRun bool) (int, error)
+}
+
 // registryCache is using OCI registry to cache assets.
 type registryCache struct {
 	puller          remotewrap.Puller
 	pusher          remotewrap.Pusher
+	rangeFetcher    remotewrap.BlobRangeFetcher
+	checksums       *computedChecksums
 	imageSigner     *signer.Signer
 	logger          *zap.Logger
 	cacheRepository name.Repository
 }
 
+// Check interface.
+var _ cacheBackend = (*registryCache)(nil)
+
 var errCacheNotFound = errors.New("not found in cache")
 
+// checksumSHA512Annotation is the cache image manifest annotation with the SHA-512 checksum of the asset.
+//
+// SHA-256 checksum is the digest of the layer.
+const checksumSHA512Annotation = "org.siderolabs.image-factory.checksum.sha512"
+
+// Cache image manifest annotations describing the cached asset, see CacheEntry.
+const (
+	schematicAnnotation = "org.siderolabs.image-factory.schematic"
+	versionAnnotation   = "org.siderolabs.image-factory.talos-version"
+	kindAnnotation      = "org.siderolabs.image-factory.output-kind"
+	archAnnotation      = "org.siderolabs.image-factory.arch"
+	profileAnnotation   = "org.siderolabs.image-factory.profile"
+	createdAnnotation   = "org.opencontainers.image.created"
+)
+
 // Get returns the boot asset from the cache.
 func (r *registryCache) Get(ctx context.Context, profileID string) (BootAsset, error) {
 	taggedRef := r.cacheRepository.Tag(profileID)
@@ -68,6 +126,11 @@ func (r *registryCache) Get(ctx context.Context, profileID string) (BootAsset, e
 	r.logger.Info("using cached image", zap.Stringer("ref", taggedRef))
 
 	imgDesc, err := r.puller.Get(ctx, digestRef)
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+		// removed by the garbage collection after the head request
+		return nil, errCacheNotFound
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to pull cache image: %w", err)
 	}
@@ -77,6 +140,11 @@ func (r *registryCache) Get(ctx context.Context, profileID string) (BootAsset, e
 		return nil, fmt.Errorf("failed to create cache image from descriptor: %w", err)
 	}
 
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache image manifest: %w", err)
+	}
+
 	layers, err := img.Layers()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cache image layers: %w", err)
@@ -93,20 +161,94 @@ func (r *registryCache) Get(ctx context.Context, profileID string) (BootAsset, e
 		return nil, fmt.Errorf("failed to get cache image layer size: %w", err)
 	}
 
+	layerDigest, err := layer.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache image layer digest: %w", err)
+	}
+
 	return &remoteAsset{
-		layer: layer,
-		size:  size,
+		layer:        layer,
+		size:         size,
+		ref:          r.cacheRepository.Digest(layerDigest.String()),
+		rangeFetcher: r.rangeFetcher,
+		checksums: Checksums{
+			SHA256: layerDigest.Hex,
+			SHA512: manifest.Annotations[checksumSHA512Annotation],
+		},
+		recordedInputs:    recordedInputsFromAnnotations(manifest.Annotations),
+		computedChecksums: r.checksums,
 	}, nil
 }
 
+// storedAnnotations returns the annotations of the stored asset with the SHA-512 checksum and the creation time.
+//
+// Checksums are stored, so that they are computed only once.
+func storedAnnotations(annotations map[string]string, checksums Checksums) map[string]string {
+	annotations = maps.Clone(annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[checksumSHA512Annotation] = checksums.SHA512
+	annotations[createdAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	return annotations
+}
+
+// getMarker returns the annotations and the digest of the marker (empty image) by the tag, nil annotations if the tag doesn't exist.
+//
+// Markers (build leases, last access records) are stored next to the cached assets in the cache repository.
+func (r *registryCache) getMarker(ctx context.Context, tag string) (map[string]string, string, error) {
+	desc, err := r.puller.Get(ctx, r.cacheRepository.Tag(tag))
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound, http.StatusForbidden) {
+		return nil, "", nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	manifest, err := v1.ParseManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse marker manifest: %w", err)
+	}
+
+	if manifest.Annotations == nil {
+		manifest.Annotations = map[string]string{}
+	}
+
+	return manifest.Annotations, desc.Digest.String(), nil
+}
+
+// putMarker writes the marker (empty image) with the annotations by the tag.
+func (r *registryCache) putMarker(ctx context.Context, tag string, annotations map[string]string) error {
+	img, ok := mutate.Annotations(empty.Image, annotations).(v1.Image)
+	if !ok {
+		return errors.New("unexpected annotated image type")
+	}
+
+	return r.pusher.Push(ctx, r.cacheRepository.Tag(tag), img)
+}
+
 // Put uploads the boot asset to the registry.
-func (r *registryCache) Put(ctx context.Context, profileID string, asset BootAsset) error {
+//
+// The annotations describing the asset are stored in the manifest.
+func (r *registryCache) Put(ctx context.Context, profileID string, asset BootAsset, annotations map[string]string) error {
 	taggedRef := r.cacheRepository.Tag(profileID)
 
 	r.logger.Info("pushing cached image", zap.Stringer("ref", taggedRef))
 
+	checksums, err := asset.Checksums(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get asset checksums: %w", err)
+	}
+
 	layer, err := partial.CompressedToLayer(&layerWrapper{
 		src: asset,
+		digest: v1.Hash{
+			Algorithm: "sha256",
+			Hex:       checksums.SHA256,
+		},
 	})
 	if err != nil {
 		return err
@@ -119,6 +261,11 @@ func (r *registryCache) Put(ctx context.Context, profileID string, asset BootAss
 		return err
 	}
 
+	img, ok := mutate.Annotations(img, storedAnnotations(annotations, checksums)).(v1.Image)
+	if !ok {
+		return errors.New("unexpected annotated image type")
+	}
+
 	if err = r.pusher.Push(ctx, taggedRef, img); err != nil {
 		return fmt.Errorf("failed to push cache image: %w", err)
 	}
@@ -0,0 +1,297 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/siderolabs/talos/pkg/imager/profile"
+	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/image-factory/internal/regtransport"
+)
+
+// CacheEntry describes the asset in the cache.
+//
+// The assets cached before the metadata was recorded only have the profile hash, digest and size set.
+type CacheEntry struct {
+	Created time.Time
+
+	ProfileHash string
+	SchematicID string
+	Version     string
+	Kind        string
+	Arch        string
+	// Profile is the YAML profile the asset was built from, before it was enhanced from the schematic.
+	Profile string
+	// Digest is the digest of the cache image manifest.
+	Digest string
+	Size   int64
+}
+
+// CacheFilter selects the cache entries.
+//
+// The entry matches the filter if it matches all the set fields.
+type CacheFilter struct {
+	ProfileHash string
+	SchematicID string
+	Version     string
+}
+
+// IsEmpty returns true if the filter matches all entries.
+func (f CacheFilter) IsEmpty() bool {
+	return f.ProfileHash == "" && f.SchematicID == "" && f.Version == ""
+}
+
+// Matches returns true if the entry matches the filter.
+func (f CacheFilter) Matches(entry CacheEntry) bool {
+	if f.ProfileHash != "" && f.ProfileHash != entry.ProfileHash {
+		return false
+	}
+
+	if f.SchematicID != "" && f.SchematicID != entry.SchematicID {
+		return false
+	}
+
+	if f.Version != "" && strings.TrimPrefix(f.Version, "v") != strings.TrimPrefix(entry.Version, "v") {
+		return false
+	}
+
+	return true
+}
+
+// profileTagRe matches the cache image tags, other tags in the cache repository are the leases and the signatures.
+var profileTagRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// cacheAnnotations returns the cache image annotations describing the asset.
+//
+// The build inputs recorded with the built asset are stored as well.
+func cacheAnnotations(prof profile.Profile, versionString string, source Source, asset BootAsset) map[string]string {
+	annotations := map[string]string{
+		schematicAnnotation: source.SchematicID,
+		versionAnnotation:   versionString,
+		kindAnnotation:      prof.Output.Kind.String(),
+		archAnnotation:      prof.Arch,
+	}
+
+	if profileYAML, err := yaml.Marshal(source.Profile); err == nil {
+		annotations[profileAnnotation] = string(profileYAML)
+	}
+
+	if inputs, ok := asset.BuildInputs(); ok {
+		if inputsJSON, err := json.Marshal(inputs); err == nil {
+			annotations[buildInputsAnnotation] = string(inputsJSON)
+		}
+	}
+
+	return annotations
+}
+
+// listTags returns all tags in the cache repository.
+func (r *registryCache) listTags(ctx context.Context) ([]string, error) {
+	tags, err := r.puller.List(ctx, r.cacheRepository)
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+		// the repository doesn't exist yet
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache repository: %w", err)
+	}
+
+	return tags, nil
+}
+
+// List returns the assets in the cache.
+func (r *registryCache) List(ctx context.Context) ([]CacheEntry, error) {
+	tags, err := r.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.inspectTags(ctx, tags)
+}
+
+// inspectTags returns the cache entries for the asset tags, other tags are skipped.
+func (r *registryCache) inspectTags(ctx context.Context, tags []string) ([]CacheEntry, error) {
+	var entries []CacheEntry
+
+	for _, tag := range tags {
+		if !profileTagRe.MatchString(tag) {
+			continue
+		}
+
+		entry, err := r.Inspect(ctx, tag)
+		if errors.Is(err, errCacheNotFound) {
+			// removed concurrently
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Inspect returns the cache entry of the asset.
+//
+// The signature of the cache image is not verified.
+func (r *registryCache) Inspect(ctx context.Context, profileID string) (CacheEntry, error) {
+	desc, err := r.puller.Get(ctx, r.cacheRepository.Tag(profileID))
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound, http.StatusForbidden) {
+		return CacheEntry{}, errCacheNotFound
+	}
+
+	if err != nil {
+		return CacheEntry{}, fmt.Errorf("failed to get cache image: %w", err)
+	}
+
+	manifest, err := v1.ParseManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return CacheEntry{}, fmt.Errorf("failed to parse cache image manifest: %w", err)
+	}
+
+	if len(manifest.Layers) != 1 {
+		return CacheEntry{}, fmt.Errorf("unexpected number of cache image layers: %d", len(manifest.Layers))
+	}
+
+	entry := CacheEntry{
+		ProfileHash: profileID,
+		SchematicID: manifest.Annotations[schematicAnnotation],
+		Version:     manifest.Annotations[versionAnnotation],
+		Kind:        manifest.Annotations[kindAnnotation],
+		Arch:        manifest.Annotations[archAnnotation],
+		Profile:     manifest.Annotations[profileAnnotation],
+		Digest:      desc.Digest.String(),
+		Size:        manifest.Layers[0].Size,
+	}
+
+	// the malformed timestamp is ignored
+	entry.Created, _ = time.Parse(time.RFC3339, manifest.Annotations[createdAnnotation]) //nolint:errcheck
+
+	return entry, nil
+}
+
+// Delete removes the cache image by the manifest digest and its signature from the cache.
+//
+// Registries remove all the tags of the deleted manifest, so the assets with identical contents are removed together.
+func (r *registryCache) Delete(ctx context.Context, entry CacheEntry) error {
+	digest := entry.Digest
+	digestRef := r.cacheRepository.Digest(digest)
+
+	r.logger.Info("deleting cached image", zap.Stringer("ref", digestRef))
+
+	if err := r.deleteManifest(ctx, digest); err != nil {
+		return fmt.Errorf("failed to delete cache image: %w", err)
+	}
+
+	signatureTag, err := cosignremote.SignatureTag(digestRef)
+	if err != nil {
+		return fmt.Errorf("error generating signature tag: %w", err)
+	}
+
+	signatureDesc, err := r.puller.Head(ctx, signatureTag)
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound, http.StatusForbidden) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to head cache image signature: %w", err)
+	}
+
+	if err = r.deleteManifest(ctx, signatureDesc.Digest.String()); err != nil {
+		return fmt.Errorf("failed to delete cache image signature: %w", err)
+	}
+
+	return nil
+}
+
+// deleteManifest deletes the manifest by the digest, the manifest which doesn't exist is ignored.
+//
+// Deleting by the digest is safe against the concurrent writers: if the tag was updated, the new manifest is kept.
+func (r *registryCache) deleteManifest(ctx context.Context, digest string) error {
+	err := r.pusher.Delete(ctx, r.cacheRepository.Digest(digest))
+	if regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// ListCache returns the assets in the cache.
+func (b *Builder) ListCache(ctx context.Context) ([]CacheEntry, error) {
+	return b.cache.List(ctx)
+}
+
+// InspectCache returns the cache entry of the asset by the profile hash.
+func (b *Builder) InspectCache(ctx context.Context, profileHash string) (CacheEntry, error) {
+	entry, err := b.cache.Inspect(ctx, profileHash)
+	if errors.Is(err, errCacheNotFound) {
+		return CacheEntry{}, xerrors.NewTaggedf[ErrNotFoundTag]("asset %q not found in the cache", profileHash)
+	}
+
+	return entry, err
+}
+
+// PurgeCache removes the assets matching the filter from the cache, and returns the removed entries.
+//
+// The next request for the removed asset builds it again.
+func (b *Builder) PurgeCache(ctx context.Context, filter CacheFilter) ([]CacheEntry, error) {
+	if filter.IsEmpty() {
+		return nil, errors.New("purge filter is empty")
+	}
+
+	entries, err := b.cache.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := map[string]struct{}{}
+
+	for _, entry := range entries {
+		if filter.Matches(entry) {
+			digests[entry.Digest] = struct{}{}
+		}
+	}
+
+	var purged []CacheEntry
+
+	for _, entry := range entries {
+		if _, ok := digests[entry.Digest]; ok {
+			purged = append(purged, entry)
+		}
+	}
+
+	for _, entry := range purged {
+		if err = b.cache.Delete(ctx, entry); err != nil {
+			return nil, err
+		}
+
+		b.removeLocal(entry.ProfileHash)
+	}
+
+	if len(purged) > 0 {
+		b.logger.Info("purged cached assets", zap.Int("count", len(purged)), zap.String("profile_hash", filter.ProfileHash),
+			zap.String("schematic_id", filter.SchematicID), zap.String("version", filter.Version))
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,755 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/image/signer"
+)
+
+const (
+	// blobMarkerMaxSize is the maximum size of the metadata, signature and marker blobs.
+	blobMarkerMaxSize = 1 << 20
+	// blobOrphanGracePeriod is the minimum age of the orphaned data and signature blobs to be removed.
+	//
+	// The data and the signature are stored before the metadata, so the blobs of the asset being stored look orphaned.
+	// The data of the removed asset is kept for the grace period after it's found orphaned, so that the readers can finish.
+	blobOrphanGracePeriod = 24 * time.Hour
+	// blobRedirectExpiration is the expiration of the pre-signed redirect URLs.
+	blobRedirectExpiration = time.Hour
+)
+
+var (
+	// metadataKeyRe matches the metadata blobs of the cached assets.
+	metadataKeyRe = regexp.MustCompile(`^([0-9a-f]{64})\.json$`)
+	// dataKeyRe matches the data blobs of the cached assets.
+	dataKeyRe = regexp.MustCompile(`^sha256-([0-9a-f]{64})$`)
+	// orphanedDataKeyRe matches the markers of the orphaned data blobs.
+	orphanedDataKeyRe = regexp.MustCompile(`^sha256-([0-9a-f]{64})-orphaned$`)
+	// uploadDataKeyRe matches the data blobs being uploaded.
+	uploadDataKeyRe = regexp.MustCompile(`^sha256-([0-9a-f]{64})-upload-[0-9a-f]{16}$`)
+)
+
+// orphanedAnnotation is the orphaned data marker annotation with the time the data was found orphaned.
+const orphanedAnnotation = "org.siderolabs.image-factory.orphaned"
+
+// blobCache is using the blob store (directory, S3-compatible object store) to cache assets.
+//
+// The layout follows the cache repository:
+//   - sha256-<asset digest> is the asset data, shared by the profiles with identical assets;
+//   - sha256-<asset digest>-upload-<random> is the asset data being uploaded, it's moved to the data key once verified;
+//   - <profile hash>.json is the asset metadata (annotations, checksums, size) referencing the data by the digest;
+//   - sha256-<metadata digest>.sig is the signature of the metadata (compatible with `cosign verify-blob`);
+//   - <profile hash>-lease and <profile hash>-access are the markers (annotations as JSON);
+//   - sha256-<asset digest>-orphaned is the marker of the data no longer referenced by any metadata.
+//
+// The metadata is stored last, so the asset is in the cache once its metadata exists.
+// The data blobs are never overwritten with different contents, so the readers are not affected by the concurrent writers,
+// and the data is verified against the signed digest when the whole asset is read.
+type blobCache struct {
+	store       blobStore
+	imageSigner *signer.Signer
+	logger      *zap.Logger
+}
+
+// Check interface.
+var (
+	_ cacheBackend      = (*blobCache)(nil)
+	_ RedirectableAsset = (*blobAsset)(nil)
+)
+
+// blobCacheMetadata is the metadata of the cached asset, the signed part of the cache entry.
+type blobCacheMetadata struct {
+	Annotations map[string]string `json:"annotations"`
+	SHA256      string            `json:"sha256"`
+	Size        int64             `json:"size"`
+}
+
+func metadataKey(profileID string) string {
+	return profileID + ".json"
+}
+
+// dataKey is the key of the asset data by its digest.
+func dataKey(sha256Hex string) string {
+	return "sha256-" + sha256Hex
+}
+
+// newUploadDataKey returns the unique key to upload the asset data to before it's verified.
+func newUploadDataKey(sha256Hex string) (string, error) {
+	var buf [8]byte
+
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+
+	return dataKey(sha256Hex) + "-upload-" + hex.EncodeToString(buf[:]), nil
+}
+
+// orphanedDataKey is the key of the marker of the orphaned data, see isOrphanedData.
+func orphanedDataKey(sha256Hex string) string {
+	return dataKey(sha256Hex) + "-orphaned"
+}
+
+// signatureKey is the key of the signature by the metadata digest, it matches the cosign signature tag.
+func signatureKey(digestHex string) string {
+	return "sha256-" + digestHex + ".sig"
+}
+
+// deleteUpload removes the data upload which failed, the leftovers are removed by the garbage collection.
+func (c *blobCache) deleteUpload(ctx context.Context, key string) {
+	if err := c.store.Delete(ctx, key); err != nil {
+		c.logger.Warn("failed to remove cached asset upload", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// readBlob reads the small blob (metadata, signature, marker).
+func (c *blobCache) readBlob(ctx context.Context, key string) ([]byte, error) {
+	rc, err := c.store.Get(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	return io.ReadAll(io.LimitReader(rc, blobMarkerMaxSize))
+}
+
+// getMetadata returns the metadata of the asset and its digest, errCacheNotFound if it's missing.
+func (c *blobCache) getMetadata(ctx context.Context, profileID string) (blobCacheMetadata, [sha256.Size]byte, error) {
+	data, err := c.readBlob(ctx, metadataKey(profileID))
+	if errors.Is(err, errBlobNotFound) {
+		return blobCacheMetadata{}, [sha256.Size]byte{}, errCacheNotFound
+	}
+
+	if err != nil {
+		return blobCacheMetadata{}, [sha256.Size]byte{}, fmt.Errorf("failed to get cached asset metadata: %w", err)
+	}
+
+	var metadata blobCacheMetadata
+
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		return blobCacheMetadata{}, [sha256.Size]byte{}, fmt.Errorf("failed to parse cached asset metadata: %w", err)
+	}
+
+	return metadata, sha256.Sum256(data), nil
+}
+
+// Get returns the boot asset from the cache.
+func (c *blobCache) Get(ctx context.Context, profileID string) (BootAsset, error) {
+	c.logger.Debug("getting cached asset metadata", zap.String("profile_hash", profileID))
+
+	metadata, digest, err := c.getMetadata(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.verify(ctx, digest); err != nil {
+		// signature doesn't validate, skip the cache, but keep building
+		c.logger.Info("cached asset signature doesn't validate", zap.Error(err), zap.String("profile_hash", profileID))
+
+		return nil, errCacheNotFound
+	}
+
+	if !profileTagRe.MatchString(metadata.SHA256) {
+		c.logger.Info("cached asset metadata has invalid digest", zap.String("profile_hash", profileID), zap.String("sha256", metadata.SHA256))
+
+		return nil, errCacheNotFound
+	}
+
+	c.logger.Info("using cached asset", zap.String("profile_hash", profileID))
+
+	size, err := c.store.Size(ctx, dataKey(metadata.SHA256))
+	if errors.Is(err, errBlobNotFound) {
+		// removed by the garbage collection after the metadata was read
+		return nil, errCacheNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached asset size: %w", err)
+	}
+
+	if size != metadata.Size {
+		c.logger.Info("cached asset size doesn't match the metadata", zap.String("profile_hash", profileID), zap.Int64("size", size), zap.Int64("expected", metadata.Size))
+
+		return nil, errCacheNotFound
+	}
+
+	return &blobAsset{
+		store: c.store,
+		key:   dataKey(metadata.SHA256),
+		size:  size,
+		checksums: Checksums{
+			SHA256: metadata.SHA256,
+			SHA512: metadata.Annotations[checksumSHA512Annotation],
+		},
+		recordedInputs: recordedInputsFromAnnotations(metadata.Annotations),
+	}, nil
+}
+
+// verify verifies the signature of the metadata by its digest.
+func (c *blobCache) verify(ctx context.Context, digest [sha256.Size]byte) error {
+	encoded, err := c.readBlob(ctx, signatureKey(hex.EncodeToString(digest[:])))
+	if err != nil {
+		return fmt.Errorf("failed to get signature: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	return c.imageSigner.VerifyBlobDigest(digest[:], signature)
+}
+
+// Put stores the boot asset data, the metadata and its signature.
+//
+// The data is stored by its digest, so storing the same asset again (or the identical asset of another profile)
+// overwrites the data blob with the same contents.
+// The data is uploaded under the temporary key first, and it's moved to the data key once its digest is verified,
+// so the data blob is never replaced with the contents not matching the digest.
+// The annotations describing the asset are stored in the metadata.
+func (c *blobCache) Put(ctx context.Context, profileID string, asset BootAsset, annotations map[string]string) error {
+	c.logger.Info("storing cached asset", zap.String("profile_hash", profileID))
+
+	checksums, err := asset.Checksums(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get asset checksums: %w", err)
+	}
+
+	rc, err := asset.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	uploadKey, err := newUploadDataKey(checksums.SHA256)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+
+	if err = c.store.Put(ctx, uploadKey, io.TeeReader(rc, hash), asset.Size()); err != nil {
+		return fmt.Errorf("failed to store cached asset: %w", err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != checksums.SHA256 {
+		c.deleteUpload(ctx, uploadKey)
+
+		return fmt.Errorf("cached asset digest mismatch: expected %s, got %s", checksums.SHA256, actual)
+	}
+
+	if err = c.store.Move(ctx, uploadKey, dataKey(checksums.SHA256), asset.Size()); err != nil {
+		c.deleteUpload(ctx, uploadKey)
+
+		return fmt.Errorf("failed to store cached asset: %w", err)
+	}
+
+	metadata, err := json.Marshal(blobCacheMetadata{
+		Annotations: storedAnnotations(annotations, checksums),
+		SHA256:      checksums.SHA256,
+		Size:        asset.Size(),
+	})
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(metadata)
+
+	c.logger.Info("signing cached asset", zap.String("profile_hash", profileID), zap.String("digest", hex.EncodeToString(digest[:])))
+
+	signature, err := c.imageSigner.SignBlobDigest(digest[:])
+	if err != nil {
+		return fmt.Errorf("error signing cached asset: %w", err)
+	}
+
+	encoded := []byte(base64.StdEncoding.EncodeToString(signature))
+
+	if err = c.store.Put(ctx, signatureKey(hex.EncodeToString(digest[:])), bytes.NewReader(encoded), int64(len(encoded))); err != nil {
+		return fmt.Errorf("failed to store cached asset signature: %w", err)
+	}
+
+	if err = c.store.Put(ctx, metadataKey(profileID), bytes.NewReader(metadata), int64(len(metadata))); err != nil {
+		return fmt.Errorf("failed to store cached asset metadata: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the assets in the cache.
+func (c *blobCache) List(ctx context.Context) ([]CacheEntry, error) {
+	blobs, err := c.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache storage: %w", err)
+	}
+
+	var entries []CacheEntry
+
+	for _, blob := range blobs {
+		match := metadataKeyRe.FindStringSubmatch(blob.key)
+		if match == nil {
+			continue
+		}
+
+		entry, inspectErr := c.Inspect(ctx, match[1])
+		if errors.Is(inspectErr, errCacheNotFound) {
+			// removed concurrently
+			continue
+		}
+
+		if inspectErr != nil {
+			return nil, inspectErr
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Inspect returns the cache entry of the asset.
+//
+// The signature of the metadata is not verified.
+func (c *blobCache) Inspect(ctx context.Context, profileID string) (CacheEntry, error) {
+	metadata, digest, err := c.getMetadata(ctx, profileID)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	entry := CacheEntry{
+		ProfileHash: profileID,
+		SchematicID: metadata.Annotations[schematicAnnotation],
+		Version:     metadata.Annotations[versionAnnotation],
+		Kind:        metadata.Annotations[kindAnnotation],
+		Arch:        metadata.Annotations[archAnnotation],
+		Profile:     metadata.Annotations[profileAnnotation],
+		Digest:      "sha256:" + hex.EncodeToString(digest[:]),
+		Size:        metadata.Size,
+	}
+
+	// the malformed timestamp is ignored
+	entry.Created, _ = time.Parse(time.RFC3339, metadata.Annotations[createdAnnotation]) //nolint:errcheck
+
+	return entry, nil
+}
+
+// Delete removes the asset metadata and the signature from the cache.
+//
+// The data might be shared with other profiles or still being read, so it's removed by RemoveOrphans
+// once no metadata references it for the grace period.
+// The asset stored again since the entry was listed (the metadata digest doesn't match) is kept.
+func (c *blobCache) Delete(ctx context.Context, entry CacheEntry) error {
+	_, digest, err := c.getMetadata(ctx, entry.ProfileHash)
+	if errors.Is(err, errCacheNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	digestHex := hex.EncodeToString(digest[:])
+
+	if "sha256:"+digestHex != entry.Digest {
+		c.logger.Info("cached asset was stored again, keeping it", zap.String("profile_hash", entry.ProfileHash))
+
+		return nil
+	}
+
+	c.logger.Info("deleting cached asset", zap.String("profile_hash", entry.ProfileHash))
+
+	// the metadata goes first, so that the asset is missing from the cache right away
+	for _, key := range []string{metadataKey(entry.ProfileHash), signatureKey(digestHex)} {
+		if err = c.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to delete %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// getMarker returns the annotations and the digest of the marker, nil annotations if the marker doesn't exist.
+func (c *blobCache) getMarker(ctx context.Context, key string) (map[string]string, string, error) {
+	data, err := c.readBlob(ctx, key)
+	if errors.Is(err, errBlobNotFound) {
+		return nil, "", nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	annotations := map[string]string{}
+
+	if err = json.Unmarshal(data, &annotations); err != nil {
+		return nil, "", fmt.Errorf("failed to parse marker: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+
+	return annotations, "sha256:" + hex.EncodeToString(digest[:]), nil
+}
+
+// putMarker writes the marker with the annotations.
+func (c *blobCache) putMarker(ctx context.Context, key string, annotations map[string]string) error {
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)))
+}
+
+// GetLease returns the build lease for the profile, zero value if there is no lease.
+func (c *blobCache) GetLease(ctx context.Context, profileID string) (lease, error) {
+	annotations, _, err := c.getMarker(ctx, leaseTag(profileID))
+	if err != nil {
+		return lease{}, fmt.Errorf("failed to get lease: %w", err)
+	}
+
+	return parseLease(annotations), nil
+}
+
+// PutLease writes the build lease for the profile.
+func (c *blobCache) PutLease(ctx context.Context, profileID string, l lease) error {
+	if err := c.putMarker(ctx, leaseTag(profileID), map[string]string{
+		leaseHolderAnnotation:  l.holder,
+		leaseExpiresAnnotation: l.expires.UTC().Format(time.RFC3339Nano),
+	}); err != nil {
+		return fmt.Errorf("failed to store lease: %w", err)
+	}
+
+	return nil
+}
+
+// GetLastAccess returns the last access time of the asset and the digest of the record, zero value if there is no record.
+func (c *blobCache) GetLastAccess(ctx context.Context, profileID string) (time.Time, string, error) {
+	annotations, digest, err := c.getMarker(ctx, accessTag(profileID))
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to get last access: %w", err)
+	}
+
+	// the malformed record is ignored
+	lastAccess, _ := time.Parse(time.RFC3339, annotations[lastAccessAnnotation]) //nolint:errcheck
+
+	return lastAccess, digest, nil
+}
+
+// PutLastAccess records the last access time of the asset.
+func (c *blobCache) PutLastAccess(ctx context.Context, profileID string, lastAccess time.Time) error {
+	if err := c.putMarker(ctx, accessTag(profileID), map[string]string{
+		lastAccessAnnotation: lastAccess.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to store last access: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteLastAccess removes the last access record, unless it was updated since it was read with the digest.
+//
+// Blob stores have no conditional deletes, so the record updated between the check and the removal is lost,
+// and the asset is considered unused starting from its creation time.
+func (c *blobCache) DeleteLastAccess(ctx context.Context, profileID, digest string) error {
+	_, currentDigest, err := c.getMarker(ctx, accessTag(profileID))
+	if err != nil {
+		return err
+	}
+
+	if currentDigest != digest {
+		return nil
+	}
+
+	return c.store.Delete(ctx, accessTag(profileID))
+}
+
+// RemoveOrphans removes the blobs left from the assets which are no longer in the cache:
+// the last access records, the expired build leases, the signatures without the metadata and the data not referenced by any metadata
+// for the grace period.
+func (c *blobCache) RemoveOrphans(ctx context.Context, live map[string]struct{}, now time.Time, dryRun bool) (int, error) {
+	blobs, err := c.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cache storage: %w", err)
+	}
+
+	signed := map[string]struct{}{}
+	referenced := map[string]struct{}{}
+	stored := map[string]struct{}{}
+
+	for _, blob := range blobs {
+		if match := dataKeyRe.FindStringSubmatch(blob.key); match != nil {
+			stored[match[1]] = struct{}{}
+		}
+
+		if match := metadataKeyRe.FindStringSubmatch(blob.key); match != nil {
+			var (
+				metadata blobCacheMetadata
+				digest   [sha256.Size]byte
+			)
+
+			metadata, digest, err = c.getMetadata(ctx, match[1])
+			if errors.Is(err, errCacheNotFound) {
+				// removed concurrently
+				continue
+			}
+
+			if err != nil {
+				return 0, err
+			}
+
+			signed[hex.EncodeToString(digest[:])] = struct{}{}
+			referenced[metadata.SHA256] = struct{}{}
+		}
+	}
+
+	var (
+		removed int
+		errs    []error
+	)
+
+	for _, blob := range blobs {
+		var orphaned bool
+
+		keys := []string{blob.key}
+
+		if match := dataKeyRe.FindStringSubmatch(blob.key); match != nil {
+			orphaned, err = c.isOrphanedData(ctx, blob, match[1], referenced, now, dryRun)
+			keys = append(keys, orphanedDataKey(match[1]))
+		} else {
+			orphaned, err = c.isOrphan(ctx, blob, signed, referenced, stored, live, now)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if !orphaned {
+			continue
+		}
+
+		if !dryRun {
+			if err = c.deleteBlobs(ctx, keys); err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+		}
+
+		c.logger.Debug("removed orphaned blob", zap.String("key", blob.key), zap.Bool("dry_run", dryRun))
+
+		removed++
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// deleteBlobs removes the orphaned blobs in order.
+func (c *blobCache) deleteBlobs(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := c.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to remove orphaned blob %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// isOrphan returns true if the blob is left from the asset which is no longer in the cache.
+func (c *blobCache) isOrphan(ctx context.Context, blob blobInfo, signed, referenced, stored, live map[string]struct{}, now time.Time) (bool, error) {
+	if match := accessTagRe.FindStringSubmatch(blob.key); match != nil {
+		_, ok := live[match[1]]
+
+		return !ok, nil
+	}
+
+	if match := leaseTagRe.FindStringSubmatch(blob.key); match != nil {
+		if _, ok := live[match[1]]; ok {
+			return false, nil
+		}
+
+		annotations, _, err := c.getMarker(ctx, blob.key)
+		if err != nil || annotations == nil {
+			return false, err
+		}
+
+		// the asset might be being built
+		return !parseLease(annotations).active(now), nil
+	}
+
+	if now.Sub(blob.modified) < blobOrphanGracePeriod {
+		return false, nil
+	}
+
+	if match := signatureTagRe.FindStringSubmatch(blob.key); match != nil {
+		_, ok := signed[match[1]]
+
+		return !ok, nil
+	}
+
+	if match := orphanedDataKeyRe.FindStringSubmatch(blob.key); match != nil {
+		// the data was removed or stored again
+		_, isStored := stored[match[1]]
+		_, isReferenced := referenced[match[1]]
+
+		return !isStored || isReferenced, nil
+	}
+
+	if uploadDataKeyRe.MatchString(blob.key) {
+		// left from the upload which failed
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// isOrphanedData returns true if the data blob was not referenced by any metadata for the grace period.
+//
+// The readers which looked up the asset before it was removed from the cache keep reading the data,
+// so the data not referenced by any metadata is marked as orphaned first, and it's removed once the marker is older than the grace period.
+func (c *blobCache) isOrphanedData(ctx context.Context, blob blobInfo, sha256Hex string, referenced map[string]struct{}, now time.Time, dryRun bool) (bool, error) {
+	if _, ok := referenced[sha256Hex]; ok {
+		return false, nil
+	}
+
+	if now.Sub(blob.modified) < blobOrphanGracePeriod {
+		// the asset might be being stored
+		return false, nil
+	}
+
+	annotations, _, err := c.getMarker(ctx, orphanedDataKey(sha256Hex))
+	if err != nil {
+		return false, err
+	}
+
+	// the malformed marker is written again
+	orphaned, _ := time.Parse(time.RFC3339, annotations[orphanedAnnotation]) //nolint:errcheck
+
+	if orphaned.IsZero() || blob.modified.After(orphaned) {
+		// the data stored again since it was marked starts the grace period over
+		if dryRun {
+			return false, nil
+		}
+
+		if err = c.putMarker(ctx, orphanedDataKey(sha256Hex), map[string]string{
+			orphanedAnnotation: now.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return false, fmt.Errorf("failed to mark orphaned data: %w", err)
+		}
+
+		return false, nil
+	}
+
+	return now.Sub(orphaned) >= blobOrphanGracePeriod, nil
+}
+
+// blobAsset is the asset served from the blob store.
+type blobAsset struct {
+	recordedInputs
+
+	store     blobStore
+	key       string
+	checksums Checksums
+	size      int64
+}
+
+func (a *blobAsset) Size() int64 {
+	return a.size
+}
+
+func (a *blobAsset) Reader() (io.ReadCloser, error) {
+	return a.RangeReader(context.Background(), 0, -1)
+}
+
+// RangeReader returns the reader for the part of the asset, the whole asset is verified against the signed digest.
+func (a *blobAsset) RangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	rc, err := a.store.Get(ctx, a.key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > 0 || (length >= 0 && length < a.size) {
+		return rc, nil
+	}
+
+	return &verifyingReader{
+		ReadCloser: limitReadCloser(rc, a.size),
+		hash:       sha256.New(),
+		expected:   a.checksums.SHA256,
+		size:       a.size,
+	}, nil
+}
+
+func (a *blobAsset) Checksums(context.Context) (Checksums, error) {
+	return a.checksums, nil
+}
+
+// RedirectURL returns the pre-signed URL of the asset, if the blob store supports it.
+func (a *blobAsset) RedirectURL(ctx context.Context, contentType, contentDisposition string) (string, error) {
+	presigner, ok := a.store.(blobPresigner)
+	if !ok {
+		return "", nil
+	}
+
+	query := url.Values{}
+
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+
+	if contentDisposition != "" {
+		query.Set("response-content-disposition", contentDisposition)
+	}
+
+	return presigner.PresignGet(ctx, a.key, blobRedirectExpiration, query)
+}
+
+// verifyingReader reads the whole asset and fails on EOF if the size or the digest doesn't match.
+type verifyingReader struct {
+	io.ReadCloser
+
+	hash     hash.Hash
+	expected string
+	size     int64
+	read     int64
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n]) //nolint:errcheck
+	r.read += int64(n)
+
+	if !errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	if r.read != r.size {
+		return n, fmt.Errorf("cached asset size mismatch: expected %d, got %d", r.size, r.read)
+	}
+
+	if actual := hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+		return n, fmt.Errorf("cached asset digest mismatch: expected %s, got %s", r.expected, actual)
+	}
+
+	return n, err
+}
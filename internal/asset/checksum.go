@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"sync"
+)
+
+// Checksums are the hex-encoded checksums of the boot asset.
+type Checksums struct {
+	SHA256 string
+	SHA512 string
+}
+
+// computeChecksums computes all checksums in a single pass over the asset contents.
+func computeChecksums(r io.Reader) (Checksums, error) {
+	sha256Hash, sha512Hash := sha256.New(), sha512.New()
+
+	if _, err := io.Copy(io.MultiWriter(sha256Hash, sha512Hash), r); err != nil {
+		return Checksums{}, err
+	}
+
+	return Checksums{
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+		SHA512: hex.EncodeToString(sha512Hash.Sum(nil)),
+	}, nil
+}
+
+// computedChecksums keeps the SHA-512 checksums computed for the cache entries which don't have them stored, keyed by the SHA-256 checksum.
+//
+// The cache entries are looked up on every request, so the checksums computed for one lookup are kept for the process lifetime
+// to avoid reading the whole asset again. Only the older cache entries lack the stored checksums, so the map doesn't grow unbounded.
+type computedChecksums struct {
+	mu     sync.Mutex
+	sha512 map[string]string
+}
+
+func newComputedChecksums() *computedChecksums {
+	return &computedChecksums{
+		sha512: map[string]string{},
+	}
+}
+
+// get fills the SHA-512 checksum in, if it was computed before.
+func (c *computedChecksums) get(checksums Checksums) (Checksums, bool) {
+	if c == nil {
+		return checksums, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sum, ok := c.sha512[checksums.SHA256]
+	if ok {
+		checksums.SHA512 = sum
+	}
+
+	return checksums, ok
+}
+
+// set records the computed checksums.
+func (c *computedChecksums) set(checksums Checksums) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sha512[checksums.SHA256] = checksums.SHA512
+}
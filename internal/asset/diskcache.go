@@ -0,0 +1,629 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+
+	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
+)
+
+const (
+	// diskCacheAssetFile is the name of the asset file in the disk cache entry directory.
+	diskCacheAssetFile = "asset"
+	// diskCacheChecksumsFile is the name of the file with the asset checksums (and the recorded build inputs) in the disk cache entry directory.
+	diskCacheChecksumsFile = "checksums.json"
+	// diskCacheTmpSuffix is the suffix of the disk cache entries being filled.
+	diskCacheTmpSuffix = "-tmp"
+	// diskCacheFillTimeout is the timeout to copy the asset from the cache repository to the disk.
+	diskCacheFillTimeout = 30 * time.Minute
+	// diskCacheRevalidateInterval is the interval to check that the asset served from the disk is still in the cache repository.
+	diskCacheRevalidateInterval = 5 * time.Minute
+)
+
+// diskCache is the local disk tier in front of the cache repository.
+//
+// Every entry is a directory named by the profile hash with the asset and its checksums.
+// The asset is verified against the digest of the cache image layer when it's copied to the disk,
+// and when the disk cache is loaded on startup.
+//
+// The assets removed from the cache repository (by the garbage collection, or via the admin API of another replica)
+// are removed from the disk cache once the entry is revalidated, see diskCacheRevalidateInterval.
+type diskCache struct {
+	logger *zap.Logger
+
+	// revalidate returns errCacheNotFound if the asset is no longer in the cache repository.
+	revalidate func(ctx context.Context, profileID string) error
+
+	metricSize                                     prometheus.Gauge
+	metricHits, metricMisses, metricHitBytes       prometheus.Counter
+	metricFills, metricFillFailures, metricEvicted prometheus.Counter
+
+	sf      singleflight.Group
+	entries map[string]*list.Element
+	lru     *list.List
+	mu      sync.Mutex
+
+	path          string
+	maxSize, size int64
+}
+
+// diskCacheEntry is stored in the LRU list.
+type diskCacheEntry struct {
+	recordedInputs
+
+	// validated is the time the asset was last seen in the cache repository, zero for the entries loaded on startup.
+	validated time.Time
+
+	checksums Checksums
+	profileID string
+	size      int64
+}
+
+// diskCacheChecksums is the contents of the checksums file of the disk cache entry.
+type diskCacheChecksums struct {
+	BuildInputs *factoryprofile.BuildInputs `json:"buildInputs,omitempty"`
+
+	SHA256 string `json:"sha256"`
+	SHA512 string `json:"sha512"`
+	Size   int64  `json:"size"`
+}
+
+// newDiskCache creates the disk cache in the directory, and loads the valid entries left from the previous run.
+//
+// Zero max size means no limit.
+func newDiskCache(logger *zap.Logger, path string, maxSize int64, revalidate func(ctx context.Context, profileID string) error) (*diskCache, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create disk cache directory: %w", err)
+	}
+
+	c := &diskCache{
+		logger:     logger,
+		revalidate: revalidate,
+		path:       path,
+		maxSize:    maxSize,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		metricSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "image_factory_asset_disk_cache_size_bytes",
+			Help: "Size of the assets in the local disk cache.",
+		}),
+		metricHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_hits_total",
+			Help: "Number of cached asset lookups served from the local disk cache.",
+		}),
+		metricMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_misses_total",
+			Help: "Number of cached asset lookups not found in the local disk cache.",
+		}),
+		metricHitBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_hit_bytes_total",
+			Help: "Number of bytes of the assets served from the local disk cache.",
+		}),
+		metricFills: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_fills_total",
+			Help: "Number of assets copied from the cache repository to the local disk cache.",
+		}),
+		metricFillFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_fill_failures_total",
+			Help: "Number of assets which failed to be copied to the local disk cache (including digest mismatches).",
+		}),
+		metricEvicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_asset_disk_cache_evictions_total",
+			Help: "Number of assets evicted from the local disk cache due to the size limit.",
+		}),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load validates the contents of the disk cache directory and loads the valid entries.
+//
+// Leftovers of incomplete fills and the entries which fail the verification are removed.
+func (c *diskCache) load() error {
+	dirEntries, err := os.ReadDir(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read disk cache directory: %w", err)
+	}
+
+	c.logger.Info("validating disk cache", zap.String("path", c.path), zap.Int("entries", len(dirEntries)))
+
+	type loadedEntry struct {
+		lastUsed time.Time
+		entry    *diskCacheEntry
+	}
+
+	var (
+		eg     errgroup.Group
+		loaded []loadedEntry
+		mu     sync.Mutex
+	)
+
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		path := filepath.Join(c.path, name)
+
+		eg.Go(func() error {
+			entry, validationErr := validateDiskCacheEntry(path, dirEntry)
+			if validationErr != nil {
+				c.logger.Warn("removing invalid disk cache entry", zap.String("path", path), zap.Error(validationErr))
+
+				return os.RemoveAll(path)
+			}
+
+			info, infoErr := dirEntry.Info()
+			if infoErr != nil {
+				return infoErr
+			}
+
+			mu.Lock()
+			loaded = append(loaded, loadedEntry{lastUsed: info.ModTime(), entry: entry})
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err = eg.Wait(); err != nil {
+		return fmt.Errorf("failed to validate disk cache: %w", err)
+	}
+
+	// most recently used entries go to the front
+	slices.SortFunc(loaded, func(a, b loadedEntry) int {
+		return a.lastUsed.Compare(b.lastUsed)
+	})
+
+	for _, l := range loaded {
+		c.add(l.entry)
+	}
+
+	return nil
+}
+
+// validateDiskCacheEntry verifies a single entry in the disk cache directory.
+func validateDiskCacheEntry(path string, dirEntry os.DirEntry) (*diskCacheEntry, error) {
+	if strings.HasSuffix(dirEntry.Name(), diskCacheTmpSuffix) {
+		return nil, errors.New("incomplete fill")
+	}
+
+	if !dirEntry.IsDir() || !profileTagRe.MatchString(dirEntry.Name()) {
+		return nil, errors.New("unexpected entry")
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, diskCacheChecksumsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	var checksums diskCacheChecksums
+
+	if err = json.Unmarshal(data, &checksums); err != nil {
+		return nil, fmt.Errorf("failed to parse checksums: %w", err)
+	}
+
+	f, err := os.Open(filepath.Join(path, diskCacheAssetFile))
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	if size != checksums.Size {
+		return nil, fmt.Errorf("size mismatch: expected %d, got %d", checksums.Size, size)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != checksums.SHA256 {
+		return nil, fmt.Errorf("digest mismatch: expected %s, got %s", checksums.SHA256, actual)
+	}
+
+	return &diskCacheEntry{
+		profileID: dirEntry.Name(),
+		size:      checksums.Size,
+		checksums: Checksums{
+			SHA256: checksums.SHA256,
+			SHA512: checksums.SHA512,
+		},
+		recordedInputs: recordedInputs{inputs: checksums.BuildInputs},
+	}, nil
+}
+
+// Get returns the asset from the disk cache.
+//
+// The entry which was not revalidated for diskCacheRevalidateInterval is checked against the cache repository first,
+// and removed if the asset is gone. If the cache repository is not available, the entry is served as is.
+func (c *diskCache) Get(ctx context.Context, profileID string) (BootAsset, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+
+	el, ok := c.entries[profileID]
+	if !ok {
+		c.mu.Unlock()
+		c.metricMisses.Inc()
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	entry := el.Value.(*diskCacheEntry) //nolint:forcetypeassert,errcheck
+
+	revalidate := now.Sub(entry.validated) > diskCacheRevalidateInterval
+	if revalidate {
+		// concurrent readers don't revalidate the same entry
+		entry.validated = now
+	}
+
+	c.mu.Unlock()
+
+	if revalidate {
+		err := c.revalidate(ctx, profileID)
+
+		switch {
+		case errors.Is(err, errCacheNotFound):
+			c.logger.Debug("removing disk cache entry missing in the cache repository", zap.String("profile_hash", profileID))
+
+			c.Remove(profileID)
+			c.metricMisses.Inc()
+
+			return nil, false
+		case err != nil:
+			// the cache repository is not available, keep serving the local copy
+			c.logger.Warn("failed to revalidate disk cache entry", zap.String("profile_hash", profileID), zap.Error(err))
+		}
+	}
+
+	entryPath := filepath.Join(c.path, profileID)
+
+	if _, err := os.Stat(filepath.Join(entryPath, diskCacheAssetFile)); err != nil {
+		c.logger.Warn("failed to stat disk cache entry", zap.String("profile_hash", profileID), zap.Error(err))
+
+		c.Remove(profileID)
+		c.metricMisses.Inc()
+
+		return nil, false
+	}
+
+	// the modification time of the entry records the last use across restarts
+	os.Chtimes(entryPath, now, now) //nolint:errcheck
+
+	c.metricHits.Inc()
+	c.metricHitBytes.Add(float64(entry.size))
+
+	return newDiskAsset(filepath.Join(entryPath, diskCacheAssetFile), entry), true
+}
+
+// Fill copies the asset from the cache repository to the disk cache in the background.
+//
+// The asset is only stored if its contents match the digest of the cache image layer.
+func (c *diskCache) Fill(profileID string, asset BootAsset) {
+	if c.maxSize > 0 && asset.Size() > c.maxSize {
+		return
+	}
+
+	go c.sf.Do(profileID, func() (any, error) { //nolint:errcheck
+		c.mu.Lock()
+		_, ok := c.entries[profileID]
+		c.mu.Unlock()
+
+		if ok {
+			return nil, nil //nolint:nilnil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), diskCacheFillTimeout)
+		defer cancel()
+
+		if err := c.fill(ctx, profileID, asset); err != nil {
+			c.metricFillFailures.Inc()
+			c.logger.Warn("failed to fill disk cache", zap.String("profile_hash", profileID), zap.Error(err))
+
+			return nil, err
+		}
+
+		c.metricFills.Inc()
+		c.logger.Debug("filled disk cache", zap.String("profile_hash", profileID), zap.Int64("size", asset.Size()))
+
+		return nil, nil //nolint:nilnil
+	})
+}
+
+func (c *diskCache) fill(ctx context.Context, profileID string, asset BootAsset) error {
+	checksums, err := asset.Checksums(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get checksums: %w", err)
+	}
+
+	tmpPath := filepath.Join(c.path, profileID+diskCacheTmpSuffix)
+
+	if err = os.RemoveAll(tmpPath); err != nil {
+		return err
+	}
+
+	if err = os.Mkdir(tmpPath, 0o700); err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpPath) //nolint:errcheck
+
+	if err = copyVerified(ctx, filepath.Join(tmpPath, diskCacheAssetFile), asset, checksums.SHA256); err != nil {
+		return err
+	}
+
+	var inputs *factoryprofile.BuildInputs
+
+	if recorded, ok := asset.BuildInputs(); ok {
+		inputs = &recorded
+	}
+
+	data, err := json.Marshal(diskCacheChecksums{
+		SHA256:      checksums.SHA256,
+		SHA512:      checksums.SHA512,
+		Size:        asset.Size(),
+		BuildInputs: inputs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(filepath.Join(tmpPath, diskCacheChecksumsFile), data, 0o600); err != nil {
+		return err
+	}
+
+	entryPath := filepath.Join(c.path, profileID)
+
+	// stale entry, e.g. after the failed removal
+	if err = os.RemoveAll(entryPath); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, entryPath); err != nil {
+		return err
+	}
+
+	c.add(&diskCacheEntry{
+		validated:      time.Now(),
+		profileID:      profileID,
+		size:           asset.Size(),
+		checksums:      checksums,
+		recordedInputs: recordedInputs{inputs: inputs},
+	})
+
+	return nil
+}
+
+// copyVerified copies the asset to the file, and verifies the SHA-256 digest of the contents.
+func copyVerified(ctx context.Context, path string, asset BootAsset, expectedSHA256 string) error {
+	rc, err := asset.RangeReader(ctx, 0, asset.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(f, hash), rc)
+	if err != nil {
+		return fmt.Errorf("failed to copy asset: %w", err)
+	}
+
+	if size != asset.Size() {
+		return fmt.Errorf("size mismatch: expected %d, got %d", asset.Size(), size)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expectedSHA256 {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", expectedSHA256, actual)
+	}
+
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// add puts the entry into the cache, evicting least recently used entries if needed.
+func (c *diskCache) add(entry *diskCacheEntry) {
+	c.mu.Lock()
+
+	if el, ok := c.entries[entry.profileID]; ok {
+		c.size -= el.Value.(*diskCacheEntry).size //nolint:forcetypeassert,errcheck
+		c.lru.Remove(el)
+	}
+
+	c.entries[entry.profileID] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	var evicted []string
+
+	for c.maxSize > 0 && c.size > c.maxSize && c.lru.Len() > 1 {
+		el := c.lru.Back()
+		oldest := el.Value.(*diskCacheEntry) //nolint:forcetypeassert,errcheck
+
+		c.lru.Remove(el)
+		delete(c.entries, oldest.profileID)
+		c.size -= oldest.size
+
+		evicted = append(evicted, oldest.profileID)
+	}
+
+	c.metricSize.Set(float64(c.size))
+
+	c.mu.Unlock()
+
+	for _, profileID := range evicted {
+		c.metricEvicted.Inc()
+		c.logger.Debug("evicting disk cache entry", zap.String("profile_hash", profileID))
+
+		// open assets are still served after the removal
+		if err := os.RemoveAll(filepath.Join(c.path, profileID)); err != nil {
+			c.logger.Warn("failed to evict disk cache entry", zap.String("profile_hash", profileID), zap.Error(err))
+		}
+	}
+}
+
+// Remove removes the asset from the disk cache.
+func (c *diskCache) Remove(profileID string) {
+	c.mu.Lock()
+
+	if el, ok := c.entries[profileID]; ok {
+		c.size -= el.Value.(*diskCacheEntry).size //nolint:forcetypeassert,errcheck
+		c.lru.Remove(el)
+		delete(c.entries, profileID)
+
+		c.metricSize.Set(float64(c.size))
+	}
+
+	c.mu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(c.path, profileID)); err != nil {
+		c.logger.Warn("failed to remove disk cache entry", zap.String("profile_hash", profileID), zap.Error(err))
+	}
+}
+
+// Describe implements prom.Collector interface.
+func (c *diskCache) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect implements prom.Collector interface.
+func (c *diskCache) Collect(ch chan<- prometheus.Metric) {
+	c.metricSize.Collect(ch)
+
+	c.metricHits.Collect(ch)
+	c.metricMisses.Collect(ch)
+	c.metricHitBytes.Collect(ch)
+
+	c.metricFills.Collect(ch)
+	c.metricFillFailures.Collect(ch)
+	c.metricEvicted.Collect(ch)
+}
+
+var _ prometheus.Collector = &diskCache{}
+
+// diskAsset is the asset served from the disk cache.
+type diskAsset struct {
+	recordedInputs
+
+	path      string
+	checksums Checksums
+	size      int64
+}
+
+// Check interface.
+var _ BootAsset = (*diskAsset)(nil)
+
+// newDiskAsset creates the asset over the file in the disk cache.
+func newDiskAsset(path string, entry *diskCacheEntry) *diskAsset {
+	return &diskAsset{
+		path:           path,
+		checksums:      entry.checksums,
+		size:           entry.size,
+		recordedInputs: entry.recordedInputs,
+	}
+}
+
+// Size returns the size of the boot asset.
+func (a *diskAsset) Size() int64 {
+	return a.size
+}
+
+// Reader returns a reader for the boot asset.
+func (a *diskAsset) Reader() (io.ReadCloser, error) {
+	return a.RangeReader(context.Background(), 0, a.size)
+}
+
+// RangeReader returns a reader for the part of the boot asset.
+//
+// Every reader opens the file, and the file is closed with the reader. The open readers keep working if the entry
+// is evicted, but the readers opened after it fail (which is unlikely, as the entry was just moved to the front of the LRU list).
+func (a *diskAsset) RangeReader(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.NewSectionReader(f, offset, length),
+		Closer: f,
+	}, nil
+}
+
+// Checksums returns the checksums of the boot asset.
+func (a *diskAsset) Checksums(context.Context) (Checksums, error) {
+	return a.checksums, nil
+}
+
+// getCached returns the asset from the local disk cache, and falls back to the cache repository.
+//
+// The assets found in the cache repository are copied to the local disk cache.
+func (b *Builder) getCached(ctx context.Context, profileHash string) (BootAsset, error) {
+	if b.diskCache == nil {
+		return b.cache.Get(ctx, profileHash)
+	}
+
+	if asset, ok := b.diskCache.Get(ctx, profileHash); ok {
+		return asset, nil
+	}
+
+	asset, err := b.cache.Get(ctx, profileHash)
+	if err != nil {
+		return nil, err
+	}
+
+	b.diskCache.Fill(profileHash, asset)
+
+	return asset, nil
+}
+
+// removeLocal removes the asset from the local disk cache, if enabled.
+//
+// The disk caches of other replicas drop the asset when they revalidate it, see diskCacheRevalidateInterval.
+func (b *Builder) removeLocal(profileHash string) {
+	if b.diskCache != nil {
+		b.diskCache.Remove(profileHash)
+	}
+}
@@ -0,0 +1,263 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+// mismatchedAsset reports the checksum which doesn't match its contents.
+type mismatchedAsset struct {
+	*fakeAsset
+}
+
+func (mismatchedAsset) Checksums(context.Context) (asset.Checksums, error) {
+	return asset.Checksums{SHA256: strings.Repeat("0", 64)}, nil
+}
+
+func diskCacheProfileID(i int) string {
+	return fmt.Sprintf("%064x", i)
+}
+
+// inCache is the revalidation of the disk cache entries which are all still in the cache repository.
+func inCache(context.Context, string) error {
+	return nil
+}
+
+func newTestDiskCache(t *testing.T, path string, maxSize int64, revalidate func(context.Context, string) error) *asset.DiskCache {
+	t.Helper()
+
+	c, err := asset.NewDiskCache(zaptest.NewLogger(t), path, maxSize, revalidate)
+	require.NoError(t, err)
+
+	return c
+}
+
+func readAsset(t *testing.T, a asset.BootAsset) string {
+	t.Helper()
+
+	r, err := a.Reader()
+	require.NoError(t, err)
+
+	defer r.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestDiskCacheDigestMismatch(t *testing.T) {
+	t.Parallel()
+
+	path := t.TempDir()
+	c := newTestDiskCache(t, path, 0, inCache)
+
+	err := c.FillSync(t.Context(), diskCacheProfileID(1), mismatchedAsset{&fakeAsset{data: []byte("corrupted")}})
+	require.ErrorContains(t, err, "digest mismatch")
+
+	_, ok := c.Get(t.Context(), diskCacheProfileID(1))
+	assert.False(t, ok)
+
+	// nothing is left on the disk
+	dirEntries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	assert.Empty(t, dirEntries)
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	path := t.TempDir()
+
+	// fits two assets
+	c := newTestDiskCache(t, path, 25, inCache)
+
+	for i := range 2 {
+		require.NoError(t, c.FillSync(ctx, diskCacheProfileID(i), &fakeAsset{data: fmt.Appendf(nil, "asset-%04d", i)}))
+	}
+
+	// the first asset is used, so the second one is the least recently used
+	_, ok := c.Get(ctx, diskCacheProfileID(0))
+	require.True(t, ok)
+
+	lru, ok := c.Get(ctx, diskCacheProfileID(1))
+	require.True(t, ok)
+
+	_, ok = c.Get(ctx, diskCacheProfileID(0))
+	require.True(t, ok)
+
+	// the reader opened before the eviction keeps working
+	r, err := lru.Reader()
+	require.NoError(t, err)
+
+	defer r.Close() //nolint:errcheck
+
+	require.NoError(t, c.FillSync(ctx, diskCacheProfileID(2), &fakeAsset{data: []byte("asset-0002")}))
+
+	_, ok = c.Get(ctx, diskCacheProfileID(1))
+	assert.False(t, ok)
+
+	_, err = os.Stat(filepath.Join(path, diskCacheProfileID(1)))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	for _, i := range []int{0, 2} {
+		a, found := c.Get(ctx, diskCacheProfileID(i))
+		require.True(t, found, i)
+
+		assert.Equal(t, fmt.Sprintf("asset-%04d", i), readAsset(t, a))
+	}
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "asset-0001", string(data))
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP image_factory_asset_disk_cache_evictions_total Number of assets evicted from the local disk cache due to the size limit.
+# TYPE image_factory_asset_disk_cache_evictions_total counter
+image_factory_asset_disk_cache_evictions_total 1
+# HELP image_factory_asset_disk_cache_size_bytes Size of the assets in the local disk cache.
+# TYPE image_factory_asset_disk_cache_size_bytes gauge
+image_factory_asset_disk_cache_size_bytes 20
+`), "image_factory_asset_disk_cache_evictions_total", "image_factory_asset_disk_cache_size_bytes"))
+}
+
+func TestDiskCacheReload(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	path := t.TempDir()
+
+	c := newTestDiskCache(t, path, 0, inCache)
+
+	for i := range 3 {
+		require.NoError(t, c.FillSync(ctx, diskCacheProfileID(i), &fakeAsset{data: fmt.Appendf(nil, "asset-%04d", i)}))
+	}
+
+	// the last use is recorded in the modification time of the entry
+	for i := range 3 {
+		usedAt := time.Now().Add(time.Duration(i-3) * time.Hour)
+
+		require.NoError(t, os.Chtimes(filepath.Join(path, diskCacheProfileID(i)), usedAt, usedAt))
+	}
+
+	// the entry corrupted on the disk
+	require.NoError(t, os.WriteFile(filepath.Join(path, diskCacheProfileID(0), "asset"), []byte("corrupted!"), 0o600))
+
+	// leftovers of an interrupted fill and unexpected files
+	require.NoError(t, os.Mkdir(filepath.Join(path, diskCacheProfileID(3)+"-tmp"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "unexpected"), nil, 0o600))
+
+	// the restarted cache fits a single asset, so the most recently used one is kept
+	c = newTestDiskCache(t, path, 15, inCache)
+
+	_, ok := c.Get(ctx, diskCacheProfileID(0))
+	assert.False(t, ok, "corrupted entry should be removed")
+
+	_, ok = c.Get(ctx, diskCacheProfileID(1))
+	assert.False(t, ok, "least recently used entry should be evicted")
+
+	a, ok := c.Get(ctx, diskCacheProfileID(2))
+	require.True(t, ok)
+
+	assert.Equal(t, "asset-0002", readAsset(t, a))
+
+	dirEntries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	require.Len(t, dirEntries, 1)
+	assert.Equal(t, diskCacheProfileID(2), dirEntries[0].Name())
+}
+
+func TestDiskCacheRevalidate(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	path := t.TempDir()
+
+	c := newTestDiskCache(t, path, 0, inCache)
+
+	for i := range 3 {
+		require.NoError(t, c.FillSync(ctx, diskCacheProfileID(i), &fakeAsset{data: fmt.Appendf(nil, "asset-%04d", i)}))
+	}
+
+	// the entries loaded on startup are revalidated on the first use
+	c = newTestDiskCache(t, path, 0, func(_ context.Context, profileID string) error {
+		switch profileID {
+		case diskCacheProfileID(0):
+			return nil
+		case diskCacheProfileID(1):
+			return asset.ErrCacheNotFound
+		default:
+			return errors.New("registry is not available")
+		}
+	})
+
+	_, ok := c.Get(ctx, diskCacheProfileID(0))
+	assert.True(t, ok)
+
+	// the asset was removed from the cache repository
+	_, ok = c.Get(ctx, diskCacheProfileID(1))
+	assert.False(t, ok)
+
+	_, err := os.Stat(filepath.Join(path, diskCacheProfileID(1)))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// the local copy is served if the cache repository is not available
+	_, ok = c.Get(ctx, diskCacheProfileID(2))
+	assert.True(t, ok)
+}
+
+func TestDiskCacheMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	c := newTestDiskCache(t, t.TempDir(), 0, inCache)
+
+	_, ok := c.Get(ctx, diskCacheProfileID(0))
+	require.False(t, ok)
+
+	require.NoError(t, c.FillSync(ctx, diskCacheProfileID(0), &fakeAsset{data: []byte("asset")}))
+	require.Error(t, c.FillSync(ctx, diskCacheProfileID(1), mismatchedAsset{&fakeAsset{data: []byte("corrupted")}}))
+
+	for range 2 {
+		_, ok = c.Get(ctx, diskCacheProfileID(0))
+		require.True(t, ok)
+	}
+
+	_, ok = c.Get(ctx, diskCacheProfileID(1))
+	require.False(t, ok)
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP image_factory_asset_disk_cache_hit_bytes_total Number of bytes of the assets served from the local disk cache.
+# TYPE image_factory_asset_disk_cache_hit_bytes_total counter
+image_factory_asset_disk_cache_hit_bytes_total 10
+# HELP image_factory_asset_disk_cache_hits_total Number of cached asset lookups served from the local disk cache.
+# TYPE image_factory_asset_disk_cache_hits_total counter
+image_factory_asset_disk_cache_hits_total 2
+# HELP image_factory_asset_disk_cache_misses_total Number of cached asset lookups not found in the local disk cache.
+# TYPE image_factory_asset_disk_cache_misses_total counter
+image_factory_asset_disk_cache_misses_total 2
+# HELP image_factory_asset_disk_cache_size_bytes Size of the assets in the local disk cache.
+# TYPE image_factory_asset_disk_cache_size_bytes gauge
+image_factory_asset_disk_cache_size_bytes 5
+`), "image_factory_asset_disk_cache_hit_bytes_total", "image_factory_asset_disk_cache_hits_total",
+		"image_factory_asset_disk_cache_misses_total", "image_factory_asset_disk_cache_size_bytes"))
+}
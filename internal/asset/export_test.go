@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+)
+
+// Build log limits.
+const (
+	BuildLogMaxSize    = buildLogMaxSize
+	BuildLogMaxEntries = buildLogMaxEntries
+)
+
+// NewBuildLogStore exposes newBuildLogStore for the tests.
+var NewBuildLogStore = newBuildLogStore
+
+// CacheBackend exposes cacheBackend for the tests.
+type CacheBackend = cacheBackend
+
+// Lease exposes lease for the tests.
+type Lease = lease
+
+// NewLease creates the lease held by the holder until it expires.
+func NewLease(holder string, expires time.Time) Lease {
+	return lease{holder: holder, expires: expires}
+}
+
+// LeaseHolder returns the holder of the lease.
+func LeaseHolder(l Lease) string {
+	return l.holder
+}
+
+// LeaseActive returns true if the lease is held at the time.
+func LeaseActive(l Lease, now time.Time) bool {
+	return l.active(now)
+}
+
+// ErrCacheNotFound exposes errCacheNotFound for the tests.
+var ErrCacheNotFound = errCacheNotFound
+
+// NewTestBuilder creates the builder with the cache backend, talosVersions (optional) lists the Talos versions offered.
+func NewTestBuilder(logger *zap.Logger, cache CacheBackend, options Options, talosVersions func(context.Context) ([]semver.Version, error)) (*Builder, error) {
+	b, err := newBuilder(logger, nil, cache, options)
+	if err != nil {
+		return nil, err
+	}
+
+	b.talosVersions = talosVersions
+
+	return b, nil
+}
+
+// AcquireLease exposes acquireLease for the tests.
+func (b *Builder) AcquireLease(ctx context.Context, profileHash string) (func(), BootAsset, error) {
+	return b.acquireLease(ctx, b.logger, profileHash)
+}
+
+// LeaseHolder returns the lease holder ID of the builder.
+func (b *Builder) LeaseHolder() string {
+	return b.leaseHolder
+}
+
+// DiskCache exposes diskCache for the tests.
+type DiskCache = diskCache
+
+// NewDiskCache exposes newDiskCache for the tests.
+var NewDiskCache = newDiskCache
+
+// FillSync copies the asset to the disk cache, unlike Fill it waits for the copy to finish.
+func (c *diskCache) FillSync(ctx context.Context, profileID string, asset BootAsset) error {
+	return c.fill(ctx, profileID, asset)
+}
+
+// JoinEscalation exposes the priority escalation of the build in flight for the tests.
+func (b *Builder) JoinEscalation(profileHash string, priority scheduler.Priority) (*scheduler.Escalation, func()) {
+	return b.escalations.join(profileHash, priority)
+}
@@ -0,0 +1,303 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
+)
+
+// fakeAsset is the boot asset held in memory.
+type fakeAsset struct {
+	data []byte
+}
+
+func (a *fakeAsset) Size() int64 {
+	return int64(len(a.data))
+}
+
+func (a *fakeAsset) Reader() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(a.data)), nil
+}
+
+func (a *fakeAsset) RangeReader(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(bytes.NewReader(a.data), offset, length)), nil
+}
+
+func (a *fakeAsset) Checksums(context.Context) (asset.Checksums, error) {
+	sum := sha256.Sum256(a.data)
+
+	return asset.Checksums{SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+func (a *fakeAsset) BuildInputs() (factoryprofile.BuildInputs, bool) {
+	return factoryprofile.BuildInputs{}, false
+}
+
+// fakeAccessRecord is the last access record in the fake cache.
+type fakeAccessRecord struct {
+	lastAccess time.Time
+	digest     string
+}
+
+// fakeCache is the in-memory cache backend.
+//
+// Like with the cache repository, the entries with the same digest share the cache image, so they are deleted together.
+type fakeCache struct {
+	entries map[string]asset.CacheEntry
+	assets  map[string]asset.BootAsset
+	leases  map[string]asset.Lease
+	access  map[string]fakeAccessRecord
+
+	// onGetLastAccess (optional) is called after the last access record is read.
+	onGetLastAccess func(profileID string)
+	// onPutLease (optional) is called after the lease is written.
+	onPutLease func(profileID string, l asset.Lease)
+
+	// live is the set of the live assets passed to the last RemoveOrphans call.
+	live map[string]struct{}
+
+	leaseHistory []string
+	recordSeq    int
+	mu           sync.Mutex
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		entries: map[string]asset.CacheEntry{},
+		assets:  map[string]asset.BootAsset{},
+		leases:  map[string]asset.Lease{},
+		access:  map[string]fakeAccessRecord{},
+	}
+}
+
+// Check interface.
+var _ asset.CacheBackend = (*fakeCache)(nil)
+
+// add puts the cache entry without the asset contents.
+func (c *fakeCache) add(entries ...asset.CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range entries {
+		c.entries[entry.ProfileHash] = entry
+	}
+}
+
+// has returns true if the cache entry exists.
+func (c *fakeCache) has(profileID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.entries[profileID]
+
+	return ok
+}
+
+// lastAccessRecord returns the last access record of the asset.
+func (c *fakeCache) lastAccessRecord(profileID string) (fakeAccessRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	record, ok := c.access[profileID]
+
+	return record, ok
+}
+
+// liveAssets returns the live assets passed to the last RemoveOrphans call.
+func (c *fakeCache) liveAssets() map[string]struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.live)
+}
+
+// leaseWriters returns the holders of the written leases, in the order of writes.
+func (c *fakeCache) leaseWriters() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.leaseHistory...)
+}
+
+func (c *fakeCache) nextDigest(prefix string) string {
+	c.recordSeq++
+
+	return fmt.Sprintf("%s-%d", prefix, c.recordSeq)
+}
+
+func (c *fakeCache) Get(_ context.Context, profileID string) (asset.BootAsset, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, ok := c.assets[profileID]
+	if !ok {
+		return nil, asset.ErrCacheNotFound
+	}
+
+	return a, nil
+}
+
+func (c *fakeCache) Put(ctx context.Context, profileID string, a asset.BootAsset, _ map[string]string) error {
+	checksums, err := a.Checksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.assets[profileID] = a
+	c.entries[profileID] = asset.CacheEntry{
+		ProfileHash: profileID,
+		Digest:      "sha256:" + checksums.SHA256,
+		Size:        a.Size(),
+		Created:     time.Now(),
+	}
+
+	return nil
+}
+
+func (c *fakeCache) List(context.Context) ([]asset.CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]asset.CacheEntry, 0, len(c.entries))
+
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (c *fakeCache) Inspect(_ context.Context, profileID string) (asset.CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[profileID]
+	if !ok {
+		return asset.CacheEntry{}, asset.ErrCacheNotFound
+	}
+
+	return entry, nil
+}
+
+func (c *fakeCache) Delete(_ context.Context, entry asset.CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for profileID, stored := range c.entries {
+		if stored.Digest == entry.Digest {
+			delete(c.entries, profileID)
+			delete(c.assets, profileID)
+		}
+	}
+
+	return nil
+}
+
+func (c *fakeCache) GetLease(_ context.Context, profileID string) (asset.Lease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.leases[profileID], nil
+}
+
+func (c *fakeCache) PutLease(_ context.Context, profileID string, l asset.Lease) error {
+	c.mu.Lock()
+	c.leases[profileID] = l
+	c.leaseHistory = append(c.leaseHistory, asset.LeaseHolder(l))
+	hook := c.onPutLease
+	c.mu.Unlock()
+
+	if hook != nil {
+		hook(profileID, l)
+	}
+
+	return nil
+}
+
+func (c *fakeCache) GetLastAccess(_ context.Context, profileID string) (time.Time, string, error) {
+	c.mu.Lock()
+	record := c.access[profileID]
+	hook := c.onGetLastAccess
+	c.mu.Unlock()
+
+	if hook != nil {
+		hook(profileID)
+	}
+
+	return record.lastAccess, record.digest, nil
+}
+
+func (c *fakeCache) PutLastAccess(_ context.Context, profileID string, lastAccess time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.access[profileID] = fakeAccessRecord{
+		lastAccess: lastAccess,
+		digest:     c.nextDigest("access"),
+	}
+
+	return nil
+}
+
+func (c *fakeCache) DeleteLastAccess(_ context.Context, profileID, digest string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.access[profileID].digest == digest {
+		delete(c.access, profileID)
+	}
+
+	return nil
+}
+
+// RemoveOrphans removes the last access records and the expired leases of the assets which are not live.
+func (c *fakeCache) RemoveOrphans(_ context.Context, live map[string]struct{}, now time.Time, dryRun bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.live = maps.Clone(live)
+
+	var removed int
+
+	for profileID := range c.access {
+		if _, ok := live[profileID]; ok {
+			continue
+		}
+
+		if !dryRun {
+			delete(c.access, profileID)
+		}
+
+		removed++
+	}
+
+	for profileID, l := range c.leases {
+		if _, ok := live[profileID]; ok || asset.LeaseActive(l, now) {
+			continue
+		}
+
+		if !dryRun {
+			delete(c.leases, profileID)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,455 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/regtransport"
+)
+
+// lastAccessAnnotation is the last access record manifest annotation with the last access time of the cached asset.
+const lastAccessAnnotation = "org.siderolabs.image-factory.last-access"
+
+const (
+	// accessUpdateInterval is the minimum interval between the updates of the last access time of the asset by the replica.
+	accessUpdateInterval = time.Hour
+	// accessTrackerMaxSize is the maximum number of assets tracked to throttle the last access time updates.
+	accessTrackerMaxSize = 16384
+)
+
+// Orphaned tags in the cache repository, see CollectGarbage.
+var (
+	accessTagRe    = regexp.MustCompile(`^([0-9a-f]{64})-access$`)
+	leaseTagRe     = regexp.MustCompile(`^([0-9a-f]{64})-lease$`)
+	signatureTagRe = regexp.MustCompile(`^sha256-([0-9a-f]{64})\.sig$`)
+)
+
+// accessTag is the tag of the last access record for the profile.
+//
+// Cache images are immutable (and signed), so the last access time is recorded next to the cached asset.
+func accessTag(profileID string) string {
+	return profileID + "-access"
+}
+
+// GetLastAccess returns the last access time of the asset and the digest of the record, zero value if there is no record.
+func (r *registryCache) GetLastAccess(ctx context.Context, profileID string) (time.Time, string, error) {
+	annotations, digest, err := r.getMarker(ctx, accessTag(profileID))
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to get last access: %w", err)
+	}
+
+	// the malformed record is ignored
+	lastAccess, _ := time.Parse(time.RFC3339, annotations[lastAccessAnnotation]) //nolint:errcheck
+
+	return lastAccess, digest, nil
+}
+
+// PutLastAccess records the last access time of the asset.
+func (r *registryCache) PutLastAccess(ctx context.Context, profileID string, lastAccess time.Time) error {
+	if err := r.putMarker(ctx, accessTag(profileID), map[string]string{
+		lastAccessAnnotation: lastAccess.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to push last access: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteLastAccess removes the last access record by the manifest digest, the record updated since is kept.
+func (r *registryCache) DeleteLastAccess(ctx context.Context, _, digest string) error {
+	return r.deleteManifest(ctx, digest)
+}
+
+// accessTracker throttles the updates of the last access time of the cached assets.
+type accessTracker struct {
+	updated map[string]time.Time
+	mu      sync.Mutex
+}
+
+// shouldUpdate returns true if the last access time of the asset should be updated now.
+func (t *accessTracker) shouldUpdate(profileID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.updated[profileID]; ok && now.Sub(last) < accessUpdateInterval {
+		return false
+	}
+
+	if len(t.updated) >= accessTrackerMaxSize {
+		clear(t.updated)
+	}
+
+	t.updated[profileID] = now
+
+	return true
+}
+
+// touch records the access to the cached asset, so that the garbage collection keeps the asset.
+//
+// The update is asynchronous and best-effort, and it is throttled to once per accessUpdateInterval per replica.
+func (b *Builder) touch(profileHash string) {
+	if !b.access.shouldUpdate(profileHash, time.Now()) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := b.cache.PutLastAccess(ctx, profileHash, time.Now()); err != nil {
+			b.logger.Warn("failed to record cached asset access", zap.String("profile_hash", profileHash), zap.Error(err))
+		}
+	}()
+}
+
+// GCOptions configures the garbage collection of the asset cache.
+type GCOptions struct {
+	// MaxUnusedAge removes the assets which were not accessed for longer, zero disables.
+	MaxUnusedAge time.Duration
+	// PruneVersions removes the assets for the Talos versions which are no longer offered.
+	PruneVersions bool
+	// DryRun only reports the assets which would be removed.
+	DryRun bool
+}
+
+// GCResult is the result of the garbage collection.
+type GCResult struct {
+	// Removed are the removed assets (or the assets to be removed in the dry run mode).
+	Removed []CacheEntry
+	// Kept is the number of the kept assets.
+	Kept int
+	// OrphansRemoved is the number of the removed orphaned tags (signatures, build leases and last access records).
+	OrphansRemoved int
+}
+
+// gcCandidate is the cache entry considered by the garbage collection.
+type gcCandidate struct {
+	reason       string
+	accessDigest string
+	entry        CacheEntry
+}
+
+// CollectGarbage removes the assets for the Talos versions which are no longer offered,
+// and the assets which were not accessed for longer than the max unused age.
+//
+// The last access time is the latest of the asset creation and the last access record (see touch).
+// The assets without both (cached by older versions) get the last access record on the first run.
+//
+// Garbage collection is safe to run concurrently with the readers and the writers (and other garbage collections):
+//   - the assets are removed by the digest, so the asset pushed again since the listing is kept;
+//   - the last access time is checked again right before removing the asset;
+//   - the reader which looked up the asset before it was removed sees it as missing from the cache, or keeps
+//     streaming the layer, as the registry removes the blobs only when its own garbage collection runs
+//     (with the filesystem or S3 cache storage, the data is removed by the later runs once it stays unreferenced for a day).
+func (b *Builder) CollectGarbage(ctx context.Context, opts GCOptions) (GCResult, error) {
+	var result GCResult
+
+	offered, err := b.offeredVersions(ctx, opts)
+	if err != nil {
+		return result, err
+	}
+
+	entries, err := b.cache.List(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	candidates := make([]gcCandidate, 0, len(entries))
+
+	for _, entry := range entries {
+		candidate := gcCandidate{entry: entry}
+
+		if offered != nil && entry.Version != "" {
+			if _, ok := offered[strings.TrimPrefix(entry.Version, "v")]; !ok {
+				candidate.reason = "version no longer offered"
+			}
+		}
+
+		if opts.MaxUnusedAge > 0 {
+			var lastAccess time.Time
+
+			lastAccess, candidate.accessDigest, err = b.lastAccess(ctx, entry, now, opts.DryRun)
+			if err != nil {
+				return result, err
+			}
+
+			if candidate.reason == "" && now.Sub(lastAccess) > opts.MaxUnusedAge {
+				candidate.reason = "unused"
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	// assets with identical contents share the cache image, so it's removed only if all of them are garbage
+	shared := map[string]bool{}
+
+	for _, candidate := range candidates {
+		if garbage, ok := shared[candidate.entry.Digest]; !ok || garbage {
+			shared[candidate.entry.Digest] = candidate.reason != ""
+		}
+	}
+
+	live := map[string]struct{}{}
+
+	var errs []error
+
+	for _, candidate := range candidates {
+		if !shared[candidate.entry.Digest] {
+			live[candidate.entry.ProfileHash] = struct{}{}
+			result.Kept++
+
+			continue
+		}
+
+		if !opts.DryRun {
+			var removed bool
+
+			removed, err = b.removeGarbage(ctx, candidate, now, opts)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			if !removed {
+				live[candidate.entry.ProfileHash] = struct{}{}
+				result.Kept++
+
+				continue
+			}
+		}
+
+		b.logger.Info("removed cached asset", zap.String("profile_hash", candidate.entry.ProfileHash), zap.String("version", candidate.entry.Version),
+			zap.String("reason", candidate.reason), zap.Bool("dry_run", opts.DryRun))
+
+		result.Removed = append(result.Removed, candidate.entry)
+	}
+
+	result.OrphansRemoved, err = b.cache.RemoveOrphans(ctx, live, now, opts.DryRun)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if !opts.DryRun {
+		for _, entry := range result.Removed {
+			b.metricGCRemovedAssets.Inc()
+			b.metricGCRemovedBytes.Add(float64(entry.Size))
+		}
+	}
+
+	b.logger.Info("cache garbage collection finished", zap.Int("removed", len(result.Removed)), zap.Int("kept", result.Kept),
+		zap.Int("orphans_removed", result.OrphansRemoved), zap.Bool("dry_run", opts.DryRun))
+
+	return result, errors.Join(errs...)
+}
+
+// offeredVersions returns the set of Talos versions offered, nil if the versions are not pruned.
+func (b *Builder) offeredVersions(ctx context.Context, opts GCOptions) (map[string]struct{}, error) {
+	if !opts.PruneVersions {
+		return nil, nil //nolint:nilnil
+	}
+
+	if b.talosVersions == nil {
+		return nil, errors.New("artifacts manager is required to prune Talos versions")
+	}
+
+	versions, err := b.talosVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Talos versions: %w", err)
+	}
+
+	if len(versions) == 0 {
+		// something is wrong with the image registry, don't remove everything
+		return nil, errors.New("no Talos versions are offered, refusing to prune versions")
+	}
+
+	offered := make(map[string]struct{}, len(versions))
+
+	for _, version := range versions {
+		offered[version.String()] = struct{}{}
+	}
+
+	return offered, nil
+}
+
+// lastAccess returns the last access time of the cached asset and the digest of the last access record.
+//
+// If the last access time is not known, the record is created (unless in the dry run mode).
+func (b *Builder) lastAccess(ctx context.Context, entry CacheEntry, now time.Time, dryRun bool) (time.Time, string, error) {
+	lastAccess, digest, err := b.cache.GetLastAccess(ctx, entry.ProfileHash)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	if entry.Created.After(lastAccess) {
+		lastAccess = entry.Created
+	}
+
+	if lastAccess.IsZero() {
+		// start counting from now
+		lastAccess = now
+
+		if !dryRun {
+			if err = b.cache.PutLastAccess(ctx, entry.ProfileHash, now); err != nil {
+				return time.Time{}, "", err
+			}
+		}
+	}
+
+	return lastAccess, digest, nil
+}
+
+// removeGarbage removes the cached asset, its signature and the last access record.
+//
+// The unused asset is kept if it was accessed since the listing.
+func (b *Builder) removeGarbage(ctx context.Context, candidate gcCandidate, now time.Time, opts GCOptions) (bool, error) {
+	if candidate.reason == "unused" {
+		lastAccess, digest, err := b.cache.GetLastAccess(ctx, candidate.entry.ProfileHash)
+		if err != nil {
+			return false, err
+		}
+
+		if digest != candidate.accessDigest && now.Sub(lastAccess) <= opts.MaxUnusedAge {
+			return false, nil
+		}
+	}
+
+	if err := b.cache.Delete(ctx, candidate.entry); err != nil {
+		return false, fmt.Errorf("failed to remove cached asset %q: %w", candidate.entry.ProfileHash, err)
+	}
+
+	b.removeLocal(candidate.entry.ProfileHash)
+
+	if candidate.accessDigest != "" {
+		if err := b.cache.DeleteLastAccess(ctx, candidate.entry.ProfileHash, candidate.accessDigest); err != nil {
+			return true, fmt.Errorf("failed to remove last access record of %q: %w", candidate.entry.ProfileHash, err)
+		}
+	}
+
+	return true, nil
+}
+
+// RemoveOrphans removes the tags left from the assets which are no longer in the cache:
+// the last access records, the expired build leases and the signatures.
+func (r *registryCache) RemoveOrphans(ctx context.Context, live map[string]struct{}, now time.Time, dryRun bool) (int, error) {
+	tags, err := r.listTags(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		removed int
+		errs    []error
+	)
+
+	for _, tag := range tags {
+		var digest string
+
+		digest, err = r.orphanDigest(ctx, tag, live, now)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if digest == "" {
+			continue
+		}
+
+		if !dryRun {
+			if err = r.deleteManifest(ctx, digest); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove orphaned tag %q: %w", tag, err))
+
+				continue
+			}
+		}
+
+		r.logger.Debug("removed orphaned tag", zap.String("tag", tag), zap.Bool("dry_run", dryRun))
+
+		removed++
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// orphanDigest returns the manifest digest of the orphaned tag, empty if the tag is not orphaned.
+func (r *registryCache) orphanDigest(ctx context.Context, tag string, live map[string]struct{}, now time.Time) (string, error) {
+	if match := accessTagRe.FindStringSubmatch(tag); match != nil {
+		if _, ok := live[match[1]]; ok {
+			return "", nil
+		}
+
+		_, digest, err := r.getMarker(ctx, tag)
+
+		return digest, err
+	}
+
+	if match := leaseTagRe.FindStringSubmatch(tag); match != nil {
+		if _, ok := live[match[1]]; ok {
+			return "", nil
+		}
+
+		annotations, digest, err := r.getMarker(ctx, tag)
+		if err != nil || parseLease(annotations).active(now) {
+			// the asset might be being built
+			return "", err
+		}
+
+		return digest, nil
+	}
+
+	if match := signatureTagRe.FindStringSubmatch(tag); match != nil {
+		// the signature is pushed after the signed image, so it's orphaned only if the image is gone
+		_, err := r.puller.Head(ctx, r.cacheRepository.Digest("sha256:"+match[1]))
+		if err == nil {
+			return "", nil
+		}
+
+		if !regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+			return "", err
+		}
+
+		desc, err := r.puller.Head(ctx, r.cacheRepository.Tag(tag))
+		if regtransport.IsStatusCodeError(err, http.StatusNotFound) {
+			return "", nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		return desc.Digest.String(), nil
+	}
+
+	return "", nil
+}
+
+// RunGarbageCollector runs the garbage collection of the asset cache periodically until the context is canceled.
+func (b *Builder) RunGarbageCollector(ctx context.Context, interval time.Duration, opts GCOptions) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if _, err := b.CollectGarbage(ctx, opts); err != nil && ctx.Err() == nil {
+			b.logger.Error("cache garbage collection failed", zap.Error(err))
+		}
+	}
+}
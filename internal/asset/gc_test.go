@@ -0,0 +1,269 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+const maxUnusedAge = 24 * time.Hour
+
+func newGCBuilder(t *testing.T, cache *fakeCache, offered ...string) *asset.Builder {
+	t.Helper()
+
+	var talosVersions func(context.Context) ([]semver.Version, error)
+
+	if offered != nil {
+		talosVersions = func(context.Context) ([]semver.Version, error) {
+			versions := make([]semver.Version, 0, len(offered))
+
+			for _, version := range offered {
+				versions = append(versions, semver.MustParse(version))
+			}
+
+			return versions, nil
+		}
+	}
+
+	b, err := asset.NewTestBuilder(zaptest.NewLogger(t), cache, asset.Options{}, talosVersions)
+	require.NoError(t, err)
+
+	return b
+}
+
+func profileHashes(entries []asset.CacheEntry) []string {
+	hashes := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		hashes = append(hashes, entry.ProfileHash)
+	}
+
+	return hashes
+}
+
+func TestCollectGarbagePruneVersions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(
+		asset.CacheEntry{ProfileHash: "offered", Version: "v1.9.0", Digest: "sha256:1", Created: now},
+		asset.CacheEntry{ProfileHash: "dropped", Version: "v1.8.0", Digest: "sha256:2", Created: now},
+		asset.CacheEntry{ProfileHash: "unknown-version", Digest: "sha256:3", Created: now},
+	)
+
+	result, err := newGCBuilder(t, cache, "1.9.0", "1.10.0").CollectGarbage(t.Context(), asset.GCOptions{PruneVersions: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dropped"}, profileHashes(result.Removed))
+	assert.Equal(t, 2, result.Kept)
+
+	assert.False(t, cache.has("dropped"))
+	assert.True(t, cache.has("offered"))
+	assert.True(t, cache.has("unknown-version"))
+}
+
+func TestCollectGarbagePruneVersionsNotOffered(t *testing.T) {
+	t.Parallel()
+
+	cache := newFakeCache()
+	cache.add(asset.CacheEntry{ProfileHash: "dropped", Version: "v1.8.0", Digest: "sha256:1", Created: time.Now()})
+
+	// the registry doesn't return any versions, so nothing is pruned
+	_, err := newGCBuilder(t, cache, []string{}...).CollectGarbage(t.Context(), asset.GCOptions{PruneVersions: true})
+	require.ErrorContains(t, err, "no Talos versions are offered")
+
+	// the versions are not known
+	_, err = newGCBuilder(t, cache).CollectGarbage(t.Context(), asset.GCOptions{PruneVersions: true})
+	require.Error(t, err)
+
+	assert.True(t, cache.has("dropped"))
+}
+
+func TestCollectGarbageUnused(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(
+		asset.CacheEntry{ProfileHash: "fresh", Digest: "sha256:1", Created: now.Add(-time.Hour)},
+		asset.CacheEntry{ProfileHash: "stale", Digest: "sha256:2", Created: now.Add(-3 * maxUnusedAge)},
+		asset.CacheEntry{ProfileHash: "accessed", Digest: "sha256:3", Created: now.Add(-3 * maxUnusedAge)},
+		asset.CacheEntry{ProfileHash: "legacy", Digest: "sha256:4"},
+	)
+
+	require.NoError(t, cache.PutLastAccess(ctx, "stale", now.Add(-2*maxUnusedAge)))
+	require.NoError(t, cache.PutLastAccess(ctx, "accessed", now.Add(-time.Hour)))
+
+	result, err := newGCBuilder(t, cache).CollectGarbage(ctx, asset.GCOptions{MaxUnusedAge: maxUnusedAge})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"stale"}, profileHashes(result.Removed))
+	assert.Equal(t, 3, result.Kept)
+
+	assert.False(t, cache.has("stale"))
+
+	_, ok := cache.lastAccessRecord("stale")
+	assert.False(t, ok, "last access record of the removed asset should be removed")
+
+	// the asset without the creation time and the last access record starts counting from now
+	record, ok := cache.lastAccessRecord("legacy")
+	require.True(t, ok)
+	assert.WithinDuration(t, now, record.lastAccess, time.Minute)
+}
+
+func TestCollectGarbageAccessedDuringCollection(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(asset.CacheEntry{ProfileHash: "stale", Digest: "sha256:1", Created: now.Add(-3 * maxUnusedAge)})
+
+	require.NoError(t, cache.PutLastAccess(ctx, "stale", now.Add(-2*maxUnusedAge)))
+
+	var once sync.Once
+
+	// the asset is accessed after the listing, but before the removal
+	cache.onGetLastAccess = func(profileID string) {
+		once.Do(func() {
+			require.NoError(t, cache.PutLastAccess(ctx, profileID, time.Now()))
+		})
+	}
+
+	result, err := newGCBuilder(t, cache).CollectGarbage(ctx, asset.GCOptions{MaxUnusedAge: maxUnusedAge})
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Removed)
+	assert.Equal(t, 1, result.Kept)
+
+	assert.True(t, cache.has("stale"))
+
+	_, ok := cache.lastAccessRecord("stale")
+	assert.True(t, ok)
+	assert.Contains(t, cache.liveAssets(), "stale")
+}
+
+func TestCollectGarbageSharedDigest(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(
+		// same contents, one of them is still used
+		asset.CacheEntry{ProfileHash: "shared-used", Digest: "sha256:1", Created: now.Add(-time.Hour)},
+		asset.CacheEntry{ProfileHash: "shared-unused", Digest: "sha256:1", Created: now.Add(-3 * maxUnusedAge)},
+		// same contents, both unused
+		asset.CacheEntry{ProfileHash: "unused-1", Digest: "sha256:2", Created: now.Add(-3 * maxUnusedAge)},
+		asset.CacheEntry{ProfileHash: "unused-2", Digest: "sha256:2", Created: now.Add(-3 * maxUnusedAge)},
+	)
+
+	result, err := newGCBuilder(t, cache).CollectGarbage(ctx, asset.GCOptions{MaxUnusedAge: maxUnusedAge})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"unused-1", "unused-2"}, profileHashes(result.Removed))
+	assert.Equal(t, 2, result.Kept)
+
+	assert.True(t, cache.has("shared-used"))
+	assert.True(t, cache.has("shared-unused"))
+	assert.False(t, cache.has("unused-1"))
+	assert.False(t, cache.has("unused-2"))
+}
+
+func TestCollectGarbageDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(
+		asset.CacheEntry{ProfileHash: "stale", Version: "v1.9.0", Digest: "sha256:1", Created: now.Add(-3 * maxUnusedAge)},
+		asset.CacheEntry{ProfileHash: "dropped", Version: "v1.8.0", Digest: "sha256:2", Created: now},
+		asset.CacheEntry{ProfileHash: "legacy", Version: "v1.9.0", Digest: "sha256:3"},
+	)
+
+	require.NoError(t, cache.PutLastAccess(ctx, "orphaned", now))
+
+	result, err := newGCBuilder(t, cache, "1.9.0").CollectGarbage(ctx, asset.GCOptions{
+		MaxUnusedAge:  maxUnusedAge,
+		PruneVersions: true,
+		DryRun:        true,
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"stale", "dropped"}, profileHashes(result.Removed))
+	assert.Equal(t, 1, result.Kept)
+	assert.Equal(t, 1, result.OrphansRemoved)
+
+	// nothing is changed
+	for _, profileHash := range []string{"stale", "dropped", "legacy"} {
+		assert.True(t, cache.has(profileHash), profileHash)
+	}
+
+	_, ok := cache.lastAccessRecord("legacy")
+	assert.False(t, ok, "last access record should not be created in the dry run mode")
+
+	_, ok = cache.lastAccessRecord("orphaned")
+	assert.True(t, ok, "orphans should not be removed in the dry run mode")
+}
+
+func TestCollectGarbageOrphans(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	now := time.Now()
+
+	cache := newFakeCache()
+	cache.add(
+		asset.CacheEntry{ProfileHash: "used", Digest: "sha256:1", Created: now},
+		asset.CacheEntry{ProfileHash: "stale", Digest: "sha256:2", Created: now.Add(-3 * maxUnusedAge)},
+	)
+
+	require.NoError(t, cache.PutLastAccess(ctx, "used", now))
+	require.NoError(t, cache.PutLastAccess(ctx, "stale", now.Add(-2*maxUnusedAge)))
+
+	// markers left from the assets which are gone
+	require.NoError(t, cache.PutLastAccess(ctx, "gone", now))
+	require.NoError(t, cache.PutLease(ctx, "gone", asset.NewLease("replica", now.Add(-time.Minute))))
+
+	// the asset is being built
+	require.NoError(t, cache.PutLease(ctx, "building", asset.NewLease("replica", now.Add(time.Minute))))
+
+	result, err := newGCBuilder(t, cache).CollectGarbage(ctx, asset.GCOptions{MaxUnusedAge: maxUnusedAge})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"stale"}, profileHashes(result.Removed))
+	assert.Equal(t, 2, result.OrphansRemoved)
+
+	// only the kept assets are live
+	assert.Equal(t, map[string]struct{}{"used": {}}, cache.liveAssets())
+
+	_, ok := cache.lastAccessRecord("used")
+	assert.True(t, ok)
+
+	_, ok = cache.lastAccessRecord("gone")
+	assert.False(t, ok)
+
+	lease, err := cache.GetLease(ctx, "building")
+	require.NoError(t, err)
+	assert.True(t, asset.LeaseActive(lease, now))
+}
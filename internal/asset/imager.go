@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/talos/pkg/imager"
+	"github.com/siderolabs/talos/pkg/imager/profile"
+	"github.com/siderolabs/talos/pkg/reporter"
+	"gopkg.in/yaml.v3"
+)
+
+// ImagerCommand is the subcommand of the factory binary which runs the Talos imager, see RunImager.
+const ImagerCommand = "imager"
+
+// RunImager runs the Talos imager for the profile read from r, and writes the path of the built asset to w.
+//
+// It is run in the subprocess of the asset builder (see Options.ImagerCommand): the Talos reporter always writes
+// to the process stderr, and the builder captures the stderr of the subprocess into the build log.
+func RunImager(ctx context.Context, outputPath string, r io.Reader, w io.Writer) error {
+	var prof profile.Profile
+
+	if err := yaml.NewDecoder(r).Decode(&prof); err != nil {
+		return fmt.Errorf("error decoding profile: %w", err)
+	}
+
+	imgr, err := imager.New(prof)
+	if err != nil {
+		return err
+	}
+
+	assetPath, err := imgr.Execute(ctx, outputPath, reporter.New())
+	if err != nil {
+		return err
+	}
+
+	// the asset path goes last, as the imager might write to the stdout as well
+	_, err = fmt.Fprintln(w, assetPath)
+
+	return err
+}
+
+// runImager runs the Talos imager, and returns the path of the built asset in the output directory.
+//
+// With the imager command configured, the imager runs in the subprocess, and its output goes both to the build log
+// and to the stderr of the factory; otherwise the imager runs in the factory process, and its output only goes to the stderr.
+func (b *Builder) runImager(ctx context.Context, prof profile.Profile, outputPath string, log io.Writer) (string, error) {
+	if len(b.imagerCommand) == 0 {
+		imgr, err := imager.New(prof)
+		if err != nil {
+			return "", err
+		}
+
+		return imgr.Execute(ctx, outputPath, reporter.New())
+	}
+
+	profileYAML, err := yaml.Marshal(prof)
+	if err != nil {
+		return "", fmt.Errorf("error encoding profile: %w", err)
+	}
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, b.imagerCommand[0], slices.Concat(b.imagerCommand[1:], []string{outputPath})...) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(profileYAML)
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.MultiWriter(log, os.Stderr)
+
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("error running imager: %w", err)
+	}
+
+	// the asset path is the last line of the output
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+
+	assetPath := strings.TrimSpace(lines[len(lines)-1])
+	if assetPath == "" {
+		return "", fmt.Errorf("imager didn't report the asset path")
+	}
+
+	return assetPath, nil
+}
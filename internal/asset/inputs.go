@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"encoding/json"
+
+	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
+)
+
+// buildInputsAnnotation is the cache image manifest annotation with the build inputs (JSON) recorded when the asset was built.
+const buildInputsAnnotation = "org.siderolabs.image-factory.build-inputs"
+
+// recordedInputs holds the build inputs of the asset recorded at build time.
+//
+// The inputs are missing for the assets cached before they were recorded.
+type recordedInputs struct {
+	inputs *factoryprofile.BuildInputs
+}
+
+// BuildInputs returns the inputs the asset was built from.
+func (r recordedInputs) BuildInputs() (factoryprofile.BuildInputs, bool) {
+	if r.inputs == nil {
+		return factoryprofile.BuildInputs{}, false
+	}
+
+	return *r.inputs, true
+}
+
+// recordedInputsFromAnnotations reads the build inputs from the cache annotations.
+//
+// Invalid inputs are ignored, as if they were not recorded.
+func recordedInputsFromAnnotations(annotations map[string]string) recordedInputs {
+	data, ok := annotations[buildInputsAnnotation]
+	if !ok {
+		return recordedInputs{}
+	}
+
+	var inputs factoryprofile.BuildInputs
+
+	if err := json.Unmarshal([]byte(data), &inputs); err != nil {
+		return recordedInputs{}
+	}
+
+	return recordedInputs{inputs: &inputs}
+}
@@ -0,0 +1,208 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/siderolabs/talos/pkg/imager/profile"
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
+)
+
+// ErrNotFoundTag tags the errors when the build job, the build log or the cache entry is not found.
+type ErrNotFoundTag = struct{}
+
+// JobStatus is the status of the build job.
+type JobStatus string
+
+// Job statuses.
+const (
+	JobStatusQueued  JobStatus = "queued"
+	JobStatusRunning JobStatus = "running"
+	JobStatusDone    JobStatus = "done"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+// jobRetention is the time a finished job is kept for status polling.
+const jobRetention = time.Hour
+
+// Job is an asynchronous build job.
+//
+// Jobs are tracked per schematic: the callers submitting the same asset for the same schematic share the job,
+// while the jobs for other schematics building the same asset (the same profile hash) are separate, but the asset is still built once.
+type Job struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ID          string
+	ProfileHash string
+	SchematicID string
+	Status      JobStatus
+	// Error is set for the failed job.
+	Error string
+	// Location is an opaque reference to the built asset (e.g. a download URL), as passed on submit.
+	Location string
+}
+
+// jobID returns the ID of the build job of the asset for the schematic.
+func jobID(schematicID, profileHash string) string {
+	hash := sha256.Sum256([]byte(schematicID + "/" + profileHash))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// jobTracker keeps track of the build jobs.
+type jobTracker struct {
+	jobs map[string]*Job
+	// running is the set of profile hashes being built (holding the build slot).
+	running map[string]struct{}
+
+	mu sync.Mutex
+}
+
+func (t *jobTracker) setRunning(profileHash string, running bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if running {
+		t.running[profileHash] = struct{}{}
+	} else {
+		delete(t.running, profileHash)
+	}
+
+	for _, job := range t.jobs {
+		if job.ProfileHash == profileHash && job.Status != JobStatusDone && job.Status != JobStatusFailed {
+			job.UpdatedAt = time.Now()
+		}
+	}
+}
+
+// Submit submits an asynchronous build of the asset.
+//
+// If the job for the same profile and schematic is already queued, running or done, it is returned as is;
+// failed jobs are restarted.
+//
+// Submitting a new job is subject to the build admission (the context is only used for it and for the client identity),
+// and is rejected early if the build queue is full. Jobs are built with the background priority.
+func (b *Builder) Submit(ctx context.Context, prof profile.Profile, versionString string, source Source, location string) (Job, error) {
+	profileHash, err := factoryprofile.Hash(prof)
+	if err != nil {
+		return Job{}, err
+	}
+
+	b.jobs.mu.Lock()
+	defer b.jobs.mu.Unlock()
+
+	b.jobs.expireLocked()
+
+	id := jobID(source.SchematicID, profileHash)
+
+	if job, ok := b.jobs.jobs[id]; ok && job.Status != JobStatusFailed {
+		return b.jobs.statusLocked(job), nil
+	}
+
+	if b.scheduler.QueueFull() {
+		return Job{}, xerrors.NewTaggedf[scheduler.QueueFullErrorTag]("build queue is full")
+	}
+
+	if b.admitter != nil {
+		if err = b.admitter.AdmitBuild(ctx); err != nil {
+			return Job{}, err
+		}
+	}
+
+	now := time.Now()
+
+	job := &Job{
+		ID:          id,
+		ProfileHash: profileHash,
+		SchematicID: source.SchematicID,
+		Status:      JobStatusQueued,
+		Location:    location,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	b.jobs.jobs[id] = job
+
+	req := scheduleRequest(ctx)
+	req.Priority = scheduler.PriorityBackground
+
+	go b.runJob(req, id, prof, versionString, source)
+
+	return *job, nil
+}
+
+// Job returns the status of the build job.
+func (b *Builder) Job(id string) (Job, error) {
+	b.jobs.mu.Lock()
+	defer b.jobs.mu.Unlock()
+
+	b.jobs.expireLocked()
+
+	job, ok := b.jobs.jobs[id]
+	if !ok {
+		return Job{}, xerrors.NewTaggedf[ErrNotFoundTag]("build job %q not found", id)
+	}
+
+	return b.jobs.statusLocked(job), nil
+}
+
+func (b *Builder) runJob(req scheduler.Request, id string, prof profile.Profile, versionString string, source Source) {
+	// the build itself is detached from the request context and has a timeout, see buildAndCache
+	ctx := WithClient(WithPriority(context.Background(), req.Priority), req.Client)
+
+	_, err := b.Build(ctx, prof, versionString, source)
+
+	b.jobs.mu.Lock()
+	defer b.jobs.mu.Unlock()
+
+	job, ok := b.jobs.jobs[id]
+	if !ok {
+		return
+	}
+
+	job.UpdatedAt = time.Now()
+
+	if err != nil {
+		b.logger.Error("build job failed", zap.String("job_id", id), zap.String("profile_hash", job.ProfileHash), zap.Error(err))
+
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+
+		return
+	}
+
+	job.Status = JobStatusDone
+}
+
+// statusLocked returns the copy of the job with the actual status.
+func (t *jobTracker) statusLocked(job *Job) Job {
+	result := *job
+
+	if result.Status == JobStatusQueued {
+		if _, running := t.running[job.ProfileHash]; running {
+			result.Status = JobStatusRunning
+		}
+	}
+
+	return result
+}
+
+func (t *jobTracker) expireLocked() {
+	for id, job := range t.jobs {
+		if (job.Status == JobStatusDone || job.Status == JobStatusFailed) && time.Since(job.UpdatedAt) > jobRetention {
+			delete(t.jobs, id)
+		}
+	}
+}
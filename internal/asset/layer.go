@@ -5,21 +5,39 @@
 package asset
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"net/url"
+	"sync"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/opencontainers/go-digest"
+
+	"github.com/siderolabs/image-factory/internal/remotewrap"
 )
 
 // remoteAsset holds a cached image layer which contains the asset.
 type remoteAsset struct {
-	layer v1.Layer
-	size  int64
+	recordedInputs
+
+	layer        v1.Layer
+	rangeFetcher remotewrap.BlobRangeFetcher
+	ref          name.Digest
+	checksums    Checksums
+	size         int64
+
+	// computedChecksums are shared by all assets of the cache, see Checksums.
+	computedChecksums *computedChecksums
+
+	// checksumsMu guards checksums, as the asset is shared by the concurrent requests.
+	checksumsMu sync.Mutex
 }
 
 // Check interface.
-var _ BootAsset = (*remoteAsset)(nil)
+var _ RedirectableAsset = (*remoteAsset)(nil)
 
 // Size returns the size of the boot asset.
 func (r *remoteAsset) Size() int64 {
@@ -31,6 +49,89 @@ func (r *remoteAsset) Reader() (io.ReadCloser, error) {
 	return r.layer.Compressed()
 }
 
+// RangeReader returns a reader for the part of the boot asset.
+//
+// The range is fetched directly from the registry, without pulling the whole layer.
+// The whole asset is read as the layer, so that its digest is verified.
+func (r *remoteAsset) RangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length == r.size {
+		return r.layer.Compressed()
+	}
+
+	if r.rangeFetcher != nil {
+		rc, err := r.rangeFetcher.BlobRange(ctx, r.ref, offset, length)
+		if err != nil {
+			return nil, err
+		}
+
+		return limitReadCloser(rc, length), nil
+	}
+
+	rc, err := r.layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = io.CopyN(io.Discard, rc, offset); err != nil {
+		rc.Close() //nolint:errcheck
+
+		return nil, fmt.Errorf("failed to skip to the offset: %w", err)
+	}
+
+	return limitReadCloser(rc, length), nil
+}
+
+// RedirectURL returns the URL of the layer blob in the cache repository, like handleBlob does for the installer layers.
+//
+// The registry serves the blob with its own headers, so the content type and disposition are not preserved.
+func (r *remoteAsset) RedirectURL(context.Context, string, string) (string, error) {
+	repository := r.ref.Context()
+
+	var redirectURL url.URL
+
+	redirectURL.Scheme = repository.Scheme()
+	redirectURL.Host = repository.Registry.Name()
+
+	return redirectURL.JoinPath("v2", repository.RepositoryStr(), "blobs", r.ref.DigestStr()).String(), nil
+}
+
+// Checksums returns the checksums of the boot asset.
+//
+// Checksums are stored in the cache image manifest, but older cache entries don't have them,
+// so they are computed on the fly once per process (the failed computation is retried on the next call).
+func (r *remoteAsset) Checksums(ctx context.Context) (Checksums, error) {
+	r.checksumsMu.Lock()
+	defer r.checksumsMu.Unlock()
+
+	if r.checksums.SHA256 != "" && r.checksums.SHA512 != "" {
+		return r.checksums, nil
+	}
+
+	if checksums, ok := r.computedChecksums.get(r.checksums); ok {
+		r.checksums = checksums
+
+		return checksums, nil
+	}
+
+	// the whole layer is read, so the digest is verified
+	rc, err := r.RangeReader(ctx, 0, r.size)
+	if err != nil {
+		return Checksums{}, err
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	checksums, err := computeChecksums(rc)
+	if err != nil {
+		return Checksums{}, err
+	}
+
+	r.checksums = checksums
+	r.computedChecksums.set(checksums)
+
+	return checksums, nil
+}
+
 // layerWrapper adapts to the expected v1.Layer interface.
 type layerWrapper struct {
 	src    BootAsset
@@ -0,0 +1,248 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Build lease manifest annotations.
+const (
+	leaseHolderAnnotation  = "org.siderolabs.image-factory.lease.holder"
+	leaseExpiresAnnotation = "org.siderolabs.image-factory.lease.expires"
+)
+
+const (
+	// leasePollInterval is the interval to check the cache while another replica holds the lease.
+	leasePollInterval = 5 * time.Second
+	// leaseSettleDelay is the delay before reading back the written lease.
+	//
+	// Registries have no compare-and-swap, so the last writer wins, and the delay gives the concurrent writer
+	// the chance to be seen.
+	leaseSettleDelay = time.Second
+)
+
+// lease is a short-lived lock on the build of the asset shared across the replicas.
+//
+// The lease is stored as a tag in the cache repository next to the cached asset,
+// and it's renewed while the asset is being built.
+type lease struct {
+	expires time.Time
+	holder  string
+}
+
+func (l lease) heldBy(holder string, now time.Time) bool {
+	return l.holder == holder && now.Before(l.expires)
+}
+
+func (l lease) active(now time.Time) bool {
+	return l.holder != "" && now.Before(l.expires)
+}
+
+// leaseTag is the tag of the build lease for the profile.
+func leaseTag(profileID string) string {
+	return profileID + "-lease"
+}
+
+// newLeaseHolder generates the unique lease holder ID of the replica.
+func newLeaseHolder() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	var buf [8]byte
+
+	if _, err = rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+
+	return hostname + "-" + hex.EncodeToString(buf[:]), nil
+}
+
+// GetLease returns the build lease for the profile, zero value if there is no lease.
+func (r *registryCache) GetLease(ctx context.Context, profileID string) (lease, error) {
+	annotations, _, err := r.getMarker(ctx, leaseTag(profileID))
+	if err != nil {
+		return lease{}, fmt.Errorf("failed to get lease: %w", err)
+	}
+
+	return parseLease(annotations), nil
+}
+
+// PutLease writes the build lease for the profile.
+func (r *registryCache) PutLease(ctx context.Context, profileID string, l lease) error {
+	if err := r.putMarker(ctx, leaseTag(profileID), map[string]string{
+		leaseHolderAnnotation:  l.holder,
+		leaseExpiresAnnotation: l.expires.UTC().Format(time.RFC3339Nano),
+	}); err != nil {
+		return fmt.Errorf("failed to push lease: %w", err)
+	}
+
+	return nil
+}
+
+// parseLease parses the lease from the manifest annotations, the malformed lease is considered expired.
+func parseLease(annotations map[string]string) lease {
+	expires, err := time.Parse(time.RFC3339Nano, annotations[leaseExpiresAnnotation])
+	if err != nil {
+		return lease{}
+	}
+
+	return lease{
+		holder:  annotations[leaseHolderAnnotation],
+		expires: expires,
+	}
+}
+
+// acquireLease acquires the build lease for the profile.
+//
+// If another replica holds the lease, it waits for the asset to appear in the cache and returns it.
+// Otherwise, it returns the function to release the lease once the asset is in the cache.
+//
+// Lease errors don't fail the build, at worst the asset is built twice.
+func (b *Builder) acquireLease(ctx context.Context, logger *zap.Logger, profileHash string) (func(), BootAsset, error) {
+	noop := func() {}
+
+	if b.leaseTTL == 0 {
+		return noop, nil, nil
+	}
+
+	waiting := false
+
+	for {
+		current, err := b.cache.GetLease(ctx, profileHash)
+		if err != nil {
+			logger.Warn("failed to get build lease, building anyway", zap.String("profile_hash", profileHash), zap.Error(err))
+
+			return noop, nil, nil //nolint:nilerr
+		}
+
+		if current.active(time.Now()) && current.holder != b.leaseHolder {
+			if !waiting {
+				logger.Info("asset is being built by another replica, waiting", zap.String("profile_hash", profileHash), zap.String("holder", current.holder))
+
+				waiting = true
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			case <-time.After(leasePollInterval):
+			}
+
+			var asset BootAsset
+
+			asset, err = b.cache.Get(ctx, profileHash)
+			if err == nil {
+				return nil, asset, nil
+			}
+
+			if !errors.Is(err, errCacheNotFound) {
+				return nil, nil, fmt.Errorf("error getting asset from cache: %w", err)
+			}
+
+			continue
+		}
+
+		if err = b.cache.PutLease(ctx, profileHash, lease{holder: b.leaseHolder, expires: time.Now().Add(b.leaseTTL)}); err != nil {
+			logger.Warn("failed to put build lease, building anyway", zap.String("profile_hash", profileHash), zap.Error(err))
+
+			return noop, nil, nil //nolint:nilerr
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(leaseSettleDelay):
+		}
+
+		current, err = b.cache.GetLease(ctx, profileHash)
+		if err != nil {
+			logger.Warn("failed to get build lease, building anyway", zap.String("profile_hash", profileHash), zap.Error(err))
+
+			return noop, nil, nil //nolint:nilerr
+		}
+
+		if !current.heldBy(b.leaseHolder, time.Now()) {
+			// another replica won the race
+			continue
+		}
+
+		break
+	}
+
+	release := b.renewLease(logger, profileHash)
+
+	// the previous holder might have finished the build just before we took the lease
+	asset, err := b.cache.Get(ctx, profileHash)
+	if err == nil {
+		release()
+
+		return nil, asset, nil
+	}
+
+	if !errors.Is(err, errCacheNotFound) {
+		release()
+
+		return nil, nil, fmt.Errorf("error getting asset from cache: %w", err)
+	}
+
+	return release, nil, nil
+}
+
+// renewLease keeps renewing the held lease, until the returned function is called, which releases the lease.
+func (b *Builder) renewLease(logger *zap.Logger, profileHash string) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(b.leaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := b.cache.PutLease(ctx, profileHash, lease{holder: b.leaseHolder, expires: time.Now().Add(b.leaseTTL)}); err != nil && ctx.Err() == nil {
+				logger.Warn("failed to renew build lease", zap.String("profile_hash", profileHash), zap.Error(err))
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+
+			releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer releaseCancel()
+
+			// expire the lease, so that the waiting replicas don't wait for the poll interval if the build failed
+			if err := b.cache.PutLease(releaseCtx, profileHash, lease{holder: b.leaseHolder, expires: time.Now()}); err != nil {
+				logger.Warn("failed to release build lease", zap.String("profile_hash", profileHash), zap.Error(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+const (
+	leaseTTL    = time.Minute
+	profileHash = "profile"
+)
+
+func newLeaseBuilder(t *testing.T, cache *fakeCache) *asset.Builder {
+	t.Helper()
+
+	b, err := asset.NewTestBuilder(zaptest.NewLogger(t), cache, asset.Options{BuildLeaseTTL: leaseTTL}, nil)
+	require.NoError(t, err)
+
+	return b
+}
+
+func currentLease(t *testing.T, cache *fakeCache) asset.Lease {
+	t.Helper()
+
+	l, err := cache.GetLease(t.Context(), profileHash)
+	require.NoError(t, err)
+
+	return l
+}
+
+func TestAcquireLease(t *testing.T) {
+	t.Parallel()
+
+	cache := newFakeCache()
+	b := newLeaseBuilder(t, cache)
+
+	release, cached, err := b.AcquireLease(t.Context(), profileHash)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Nil(t, cached)
+
+	l := currentLease(t, cache)
+	assert.Equal(t, b.LeaseHolder(), asset.LeaseHolder(l))
+	assert.True(t, asset.LeaseActive(l, time.Now()))
+
+	release()
+
+	// the lease is expired on release, so that the waiting replicas don't wait for the build which failed
+	l = currentLease(t, cache)
+	assert.Equal(t, b.LeaseHolder(), asset.LeaseHolder(l))
+	assert.False(t, asset.LeaseActive(l, time.Now()))
+
+	// release is idempotent
+	writes := len(cache.leaseWriters())
+
+	release()
+
+	assert.Len(t, cache.leaseWriters(), writes)
+}
+
+func TestAcquireLeaseExpired(t *testing.T) {
+	t.Parallel()
+
+	cache := newFakeCache()
+	require.NoError(t, cache.PutLease(t.Context(), profileHash, asset.NewLease("crashed-replica", time.Now().Add(-time.Second))))
+
+	b := newLeaseBuilder(t, cache)
+
+	// the lease of the replica which didn't finish the build is taken over
+	release, cached, err := b.AcquireLease(t.Context(), profileHash)
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Nil(t, cached)
+
+	defer release()
+
+	assert.Equal(t, b.LeaseHolder(), asset.LeaseHolder(currentLease(t, cache)))
+}
+
+func TestAcquireLeaseLostRace(t *testing.T) {
+	t.Parallel()
+
+	cache := newFakeCache()
+	b := newLeaseBuilder(t, cache)
+
+	var once sync.Once
+
+	// another replica writes its lease right after this one (the last writer wins)
+	cache.onPutLease = func(profileID string, l asset.Lease) {
+		if asset.LeaseHolder(l) != b.LeaseHolder() {
+			return
+		}
+
+		once.Do(func() {
+			require.NoError(t, cache.PutLease(t.Context(), profileID, asset.NewLease("other-replica", time.Now().Add(leaseTTL))))
+		})
+	}
+
+	built := &fakeAsset{data: []byte("built by other replica")}
+
+	go func() {
+		// the asset appears once the other replica finishes the build
+		time.Sleep(2 * time.Second)
+
+		cache.Put(context.Background(), profileHash, built, nil) //nolint:errcheck
+	}()
+
+	release, cached, err := b.AcquireLease(t.Context(), profileHash)
+	require.NoError(t, err)
+	assert.Nil(t, release)
+	assert.Same(t, built, cached)
+
+	// the loser doesn't write the lease again while waiting
+	assert.Equal(t, []string{b.LeaseHolder(), "other-replica"}, cache.leaseWriters())
+	assert.Equal(t, "other-replica", asset.LeaseHolder(currentLease(t, cache)))
+}
+
+func TestAcquireLeaseContention(t *testing.T) {
+	t.Parallel()
+
+	cache := newFakeCache()
+	builders := []*asset.Builder{newLeaseBuilder(t, cache), newLeaseBuilder(t, cache)}
+
+	type result struct {
+		release func()
+		cached  asset.BootAsset
+		err     error
+	}
+
+	results := make([]result, len(builders))
+	built := &fakeAsset{data: []byte("asset")}
+
+	var wg sync.WaitGroup
+
+	for i, b := range builders {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			release, cached, err := b.AcquireLease(t.Context(), profileHash)
+			results[i] = result{release: release, cached: cached, err: err}
+
+			if release == nil || err != nil {
+				return
+			}
+
+			// the winner holds the lease, as it was the last one to write it
+			writers := cache.leaseWriters()
+			assert.Equal(t, b.LeaseHolder(), writers[len(writers)-1])
+
+			l, leaseErr := cache.GetLease(context.Background(), profileHash)
+			assert.NoError(t, leaseErr)
+			assert.Equal(t, b.LeaseHolder(), asset.LeaseHolder(l))
+
+			assert.NoError(t, cache.Put(context.Background(), profileHash, built, nil))
+
+			release()
+		}()
+	}
+
+	wg.Wait()
+
+	var winners, losers int
+
+	for _, r := range results {
+		require.NoError(t, r.err)
+
+		if r.release != nil {
+			winners++
+
+			assert.Nil(t, r.cached)
+		} else {
+			losers++
+
+			// the loser waits for the winner to build the asset
+			assert.Same(t, built, r.cached)
+		}
+	}
+
+	assert.Equal(t, 1, winners)
+	assert.Equal(t, 1, losers)
+}
+
+func TestAcquireLeaseAssetAppeared(t *testing.T) {
+	t.Parallel()
+
+	t.Run("while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		cache := newFakeCache()
+		require.NoError(t, cache.PutLease(t.Context(), profileHash, asset.NewLease("other-replica", time.Now().Add(leaseTTL))))
+
+		b := newLeaseBuilder(t, cache)
+		built := &fakeAsset{data: []byte("asset")}
+
+		go func() {
+			time.Sleep(time.Second)
+
+			cache.Put(context.Background(), profileHash, built, nil) //nolint:errcheck
+		}()
+
+		release, cached, err := b.AcquireLease(t.Context(), profileHash)
+		require.NoError(t, err)
+		assert.Nil(t, release)
+		assert.Same(t, built, cached)
+
+		// the lease held by another replica is not touched
+		assert.Equal(t, []string{"other-replica"}, cache.leaseWriters())
+	})
+
+	t.Run("before the takeover", func(t *testing.T) {
+		t.Parallel()
+
+		cache := newFakeCache()
+		require.NoError(t, cache.PutLease(t.Context(), profileHash, asset.NewLease("other-replica", time.Now().Add(-time.Second))))
+
+		// the previous holder pushed the asset just before its lease expired
+		built := &fakeAsset{data: []byte("asset")}
+		require.NoError(t, cache.Put(t.Context(), profileHash, built, nil))
+
+		b := newLeaseBuilder(t, cache)
+
+		release, cached, err := b.AcquireLease(t.Context(), profileHash)
+		require.NoError(t, err)
+		assert.Nil(t, release)
+		assert.Same(t, built, cached)
+
+		// the lease taken over is released right away
+		l := currentLease(t, cache)
+		assert.Equal(t, b.LeaseHolder(), asset.LeaseHolder(l))
+		assert.False(t, asset.LeaseActive(l, time.Now()))
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		cache := newFakeCache()
+		require.NoError(t, cache.PutLease(t.Context(), profileHash, asset.NewLease("other-replica", time.Now().Add(leaseTTL))))
+
+		b := newLeaseBuilder(t, cache)
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+
+		_, _, err := b.AcquireLease(ctx, profileHash)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,94 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"sync"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+)
+
+type priorityKey struct{}
+
+type clientKey struct{}
+
+// WithPriority attaches the build priority to the context.
+//
+// Builds default to the interactive priority.
+func WithPriority(ctx context.Context, priority scheduler.Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// WithClient attaches the client identity to the context, builds are shared fairly across the clients.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// scheduleRequest builds the scheduler request from the context.
+func scheduleRequest(ctx context.Context) scheduler.Request {
+	req := scheduler.Request{
+		Priority: scheduler.PriorityInteractive,
+	}
+
+	if priority, ok := ctx.Value(priorityKey{}).(scheduler.Priority); ok {
+		req.Priority = priority
+	}
+
+	if client, ok := ctx.Value(clientKey{}).(string); ok {
+		req.Client = client
+	}
+
+	return req
+}
+
+// buildEscalations keeps the priority escalations of the builds in flight.
+//
+// The callers waiting for the same build share the escalation, so the build runs with the highest priority of the callers.
+type buildEscalations struct {
+	entries map[string]*buildEscalation
+
+	mu sync.Mutex
+}
+
+type buildEscalation struct {
+	escalation *scheduler.Escalation
+	refs       int
+}
+
+// join returns the escalation of the build raised to the priority, and the function to leave it.
+func (e *buildEscalations) join(profileHash string, priority scheduler.Priority) (*scheduler.Escalation, func()) {
+	e.mu.Lock()
+
+	entry, ok := e.entries[profileHash]
+	if !ok {
+		entry = &buildEscalation{
+			escalation: scheduler.NewEscalation(priority),
+		}
+
+		e.entries[profileHash] = entry
+	}
+
+	entry.refs++
+
+	e.mu.Unlock()
+
+	entry.escalation.Raise(priority)
+
+	var once sync.Once
+
+	return entry.escalation, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+
+			entry.refs--
+
+			if entry.refs == 0 {
+				delete(e.entries, profileHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+)
+
+func TestBuildEscalation(t *testing.T) {
+	t.Parallel()
+
+	b := newLeaseBuilder(t, newFakeCache())
+
+	// the asynchronous build is in flight
+	escalation, leaveBuild := b.JoinEscalation(profileHash, scheduler.PriorityBackground)
+	assert.Equal(t, scheduler.PriorityBackground, escalation.Priority())
+
+	// another asynchronous caller doesn't change the priority
+	joined, leave := b.JoinEscalation(profileHash, scheduler.PriorityBackground)
+	assert.Same(t, escalation, joined)
+	assert.Equal(t, scheduler.PriorityBackground, escalation.Priority())
+
+	leave()
+
+	// the client starts waiting for the build
+	joined, leave = b.JoinEscalation(profileHash, scheduler.PriorityInteractive)
+	assert.Same(t, escalation, joined)
+	assert.Equal(t, scheduler.PriorityInteractive, escalation.Priority())
+
+	// leaving is idempotent
+	leave()
+	leave()
+
+	// the escalation is kept while the build is in flight
+	joined, leave = b.JoinEscalation(profileHash, scheduler.PriorityBackground)
+	assert.Same(t, escalation, joined)
+
+	leave()
+	leaveBuild()
+
+	// the next build starts over
+	joined, leave = b.JoinEscalation(profileHash, scheduler.PriorityBackground)
+	defer leave()
+
+	assert.NotSame(t, escalation, joined)
+	assert.Equal(t, scheduler.PriorityBackground, joined.Priority())
+}
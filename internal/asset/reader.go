@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ReadSeeker implements io.ReadSeekCloser over the boot asset.
+//
+// Seeking is free: the data is fetched lazily starting at the current offset on the first read,
+// so that serving a range of the asset doesn't require reading the whole asset.
+type ReadSeeker struct {
+	ctx    context.Context //nolint:containedctx
+	asset  BootAsset
+	reader io.ReadCloser
+	err    error
+	offset int64
+}
+
+// NewReadSeeker creates a new ReadSeeker for the boot asset.
+func NewReadSeeker(ctx context.Context, asset BootAsset) *ReadSeeker {
+	return &ReadSeeker{
+		ctx:   ctx,
+		asset: asset,
+	}
+}
+
+// Read implements io.Reader.
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	size := r.asset.Size()
+
+	if r.offset >= size {
+		return 0, io.EOF
+	}
+
+	if r.reader == nil {
+		r.reader, r.err = r.asset.RangeReader(r.ctx, r.offset, size-r.offset)
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.asset.Size()
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != r.offset {
+		if err := r.closeReader(); err != nil {
+			return 0, err
+		}
+
+		r.offset = offset
+	}
+
+	return offset, nil
+}
+
+// Close implements io.Closer.
+func (r *ReadSeeker) Close() error {
+	return r.closeReader()
+}
+
+// Err returns the last error encountered while reading the asset.
+//
+// As http.ServeContent doesn't report read errors, this allows to log them.
+func (r *ReadSeeker) Err() error {
+	return r.err
+}
+
+func (r *ReadSeeker) closeReader() error {
+	if r.reader == nil {
+		return nil
+	}
+
+	err := r.reader.Close()
+	r.reader = nil
+
+	return err
+}
+
+// limitReadCloser returns a ReadCloser that reads from r but stops with EOF after n bytes.
+func limitReadCloser(r io.ReadCloser, n int64) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.LimitReader(r, n),
+		Closer: r,
+	}
+}
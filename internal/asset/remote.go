@@ -0,0 +1,105 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package asset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/siderolabs/talos/pkg/imager/profile"
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+	factoryprofile "github.com/siderolabs/image-factory/internal/profile"
+)
+
+// Source describes how the profile was produced.
+//
+// The resolved profile refers to the local artifacts (extension images, installer base image, etc.),
+// so remote workers reproduce the profile from the source instead.
+type Source struct {
+	// Profile is the profile before it was enhanced from the schematic.
+	Profile     profile.Profile
+	SchematicID string
+	// Inputs (optional) are the build inputs picked when the profile was enhanced from the schematic, they are recorded with the asset.
+	//
+	// The inputs are not sent to the remote workers, as they enhance the profile on their own.
+	Inputs *factoryprofile.BuildInputs
+}
+
+// RemoteBuildRequest is the request to build the asset on a remote worker.
+type RemoteBuildRequest struct {
+	Source
+
+	// Log (optional) receives the log of the remote build.
+	Log io.Writer
+
+	ProfileHash string
+	Version     string
+}
+
+// RemoteBuilder builds the assets on the remote workers.
+//
+// The worker pushes the built asset to the shared cache, and returns once the asset is available in the cache.
+type RemoteBuilder interface {
+	BuildRemote(ctx context.Context, req RemoteBuildRequest) error
+}
+
+// buildRemote builds the asset on a remote worker and fetches it from the cache.
+//
+// A concurrency limit is enforced by the scheduler, so it limits the number of concurrent builds across the workers.
+//
+// The log of the remote build is appended to the build log.
+func (b *Builder) buildRemote(
+	ctx context.Context,
+	logger *zap.Logger,
+	req scheduler.Request,
+	profileHash string,
+	versionString string,
+	source Source,
+	log io.Writer,
+) (BootAsset, error) {
+	start := time.Now()
+
+	release, err := b.scheduler.Acquire(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	b.jobs.setRunning(profileHash, true)
+
+	concurrencyLatency := time.Since(start)
+	logger.Info("building image asset remotely", zap.String("profile_hash", profileHash), zap.String("version", versionString), zap.Duration("concurrency_latency", concurrencyLatency))
+	b.metricConcurrencyLatency.Observe(concurrencyLatency.Seconds())
+
+	if err = b.remote.BuildRemote(ctx, RemoteBuildRequest{
+		Source:      source,
+		ProfileHash: profileHash,
+		Version:     versionString,
+		Log:         log,
+	}); err != nil {
+		return nil, fmt.Errorf("error building asset remotely: %w", err)
+	}
+
+	asset, err := b.cache.Get(ctx, profileHash)
+	if err != nil {
+		if errors.Is(err, errCacheNotFound) {
+			return nil, fmt.Errorf("asset %s is missing in the cache after the remote build", profileHash)
+		}
+
+		return nil, fmt.Errorf("error getting remotely built asset from cache: %w", err)
+	}
+
+	buildLatency := time.Since(start) - concurrencyLatency
+	logger.Info("finished building image asset remotely", zap.String("profile_hash", profileHash), zap.String("version", versionString), zap.Duration("build_latency", buildLatency))
+	b.metricBuildLatency.Observe(buildLatency.Seconds())
+
+	return asset, nil
+}
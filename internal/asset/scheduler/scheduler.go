@@ -0,0 +1,406 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package scheduler implements a fair, prioritized scheduler of the asset builds.
+//
+// The scheduler limits the number of concurrent builds, and the waiting builds are served
+// by priority class first, and round-robin across the clients within the priority class.
+package scheduler
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/siderolabs/gen/xerrors"
+)
+
+// QueueFullErrorTag tags the errors when the build queue is full.
+type QueueFullErrorTag struct{}
+
+// Priority is the priority class of the build, lower value means higher priority.
+type Priority int
+
+// Priority classes.
+const (
+	// PriorityInteractive is the priority of the builds a client is waiting for (e.g. downloads).
+	PriorityInteractive Priority = iota
+	// PriorityBackground is the priority of the builds nobody is waiting for (e.g. asynchronous builds, prefetch).
+	PriorityBackground
+
+	numPriorities = int(PriorityBackground) + 1
+)
+
+// String implements fmt.Stringer.
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	default:
+		return strconv.Itoa(int(p))
+	}
+}
+
+// Request describes the build to be scheduled.
+type Request struct {
+	// Client identifies the client for the fair share (e.g. IP address or authenticated identity).
+	Client   string
+	Priority Priority
+	// Escalation (optional) raises the priority of the build while it is waiting.
+	Escalation *Escalation
+}
+
+// Escalation raises the priority of the build shared by several callers.
+//
+// The build takes the highest priority of the callers, e.g. the background build is raised
+// to the interactive priority once a client starts waiting for it.
+type Escalation struct {
+	// raise (if set) moves the waiting build to the higher priority queue.
+	raise    func(Priority)
+	priority Priority
+
+	mu sync.Mutex
+}
+
+// NewEscalation creates the escalation starting with the priority.
+func NewEscalation(priority Priority) *Escalation {
+	return &Escalation{
+		priority: priority,
+	}
+}
+
+// Raise raises the priority of the build, lower priorities are ignored.
+func (e *Escalation) Raise(priority Priority) {
+	e.mu.Lock()
+
+	if priority >= e.priority {
+		e.mu.Unlock()
+
+		return
+	}
+
+	e.priority = priority
+	raise := e.raise
+
+	e.mu.Unlock()
+
+	if raise != nil {
+		raise(priority)
+	}
+}
+
+// Priority returns the current priority of the build.
+func (e *Escalation) Priority() Priority {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.priority
+}
+
+// Options configures the scheduler.
+type Options struct {
+	// Concurrency is the maximum number of concurrent builds.
+	Concurrency int
+	// MaxQueueLength is the maximum number of waiting builds, zero means no limit.
+	//
+	// Builds beyond the limit are rejected immediately.
+	MaxQueueLength int
+}
+
+// Scheduler schedules the builds.
+type Scheduler struct {
+	metricQueueDepth *prometheus.GaugeVec
+	metricWaitTime   *prometheus.HistogramVec
+	metricRejected   *prometheus.CounterVec
+	metricRunning    prometheus.Gauge
+
+	queues [numPriorities]fairQueue
+
+	options Options
+	running int
+	queued  int
+
+	mu sync.Mutex
+}
+
+type waiter struct {
+	ready    chan struct{}
+	client   string
+	priority Priority
+	granted  bool
+	canceled bool
+}
+
+// fairQueue is the queue of waiters served round-robin across the clients.
+type fairQueue struct {
+	// clients is the list of clients with waiting builds in the round-robin order.
+	clients *list.List
+	// waiters maps the client to the list of its waiters (FIFO).
+	waiters map[string]*list.List
+	// elements maps the client to its element in clients.
+	elements map[string]*list.Element
+}
+
+// New creates a new scheduler.
+func New(options Options) *Scheduler {
+	s := &Scheduler{
+		options: options,
+		metricQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "image_factory_build_queue_depth",
+			Help: "Number of builds waiting in the build queue.",
+		}, []string{"priority"}),
+		metricWaitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "image_factory_build_queue_wait_seconds",
+			Help:    "Time builds spent waiting in the build queue.",
+			Buckets: []float64{1, 10, 60, 180, 600},
+		}, []string{"priority"}),
+		metricRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "image_factory_build_queue_rejected_total",
+			Help: "Number of builds rejected because the build queue is full.",
+		}, []string{"priority"}),
+		metricRunning: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "image_factory_build_queue_running",
+			Help: "Number of builds running.",
+		}),
+	}
+
+	for i := range s.queues {
+		s.queues[i] = fairQueue{
+			clients:  list.New(),
+			waiters:  map[string]*list.List{},
+			elements: map[string]*list.Element{},
+		}
+
+		// initialize the metrics, so that they are exported even if there was no build yet
+		s.metricQueueDepth.WithLabelValues(Priority(i).String()).Set(0)
+	}
+
+	return s
+}
+
+// Acquire waits for the build slot, and returns the function to release it.
+//
+// If the queue is full, the error tagged with QueueFullErrorTag is returned immediately.
+func (s *Scheduler) Acquire(ctx context.Context, req Request) (func(), error) {
+	start := time.Now()
+
+	if req.Escalation != nil {
+		req.Priority = min(req.Priority, req.Escalation.Priority())
+	}
+
+	if req.Priority < 0 || int(req.Priority) >= numPriorities {
+		req.Priority = PriorityBackground
+	}
+
+	s.mu.Lock()
+
+	if s.running < s.options.Concurrency && s.queued == 0 {
+		s.running++
+		s.metricRunning.Set(float64(s.running))
+		s.mu.Unlock()
+
+		s.metricWaitTime.WithLabelValues(req.Priority.String()).Observe(0)
+
+		return s.releaseFunc(), nil
+	}
+
+	if s.options.MaxQueueLength > 0 && s.queued >= s.options.MaxQueueLength {
+		s.mu.Unlock()
+
+		s.metricRejected.WithLabelValues(req.Priority.String()).Inc()
+
+		return nil, xerrors.NewTaggedf[QueueFullErrorTag]("build queue is full (%d builds waiting)", s.options.MaxQueueLength)
+	}
+
+	w := &waiter{
+		ready:    make(chan struct{}),
+		client:   req.Client,
+		priority: req.Priority,
+	}
+
+	if req.Escalation != nil {
+		// the priority might have been raised since it was read, so it is read again under the escalation lock
+		req.Escalation.mu.Lock()
+		w.priority = min(w.priority, req.Escalation.priority)
+		req.Escalation.raise = func(priority Priority) { s.raise(w, priority) }
+		req.Escalation.mu.Unlock()
+
+		defer func() {
+			req.Escalation.mu.Lock()
+			req.Escalation.raise = nil
+			req.Escalation.mu.Unlock()
+		}()
+	}
+
+	s.queues[w.priority].push(w)
+	s.queued++
+	s.metricQueueDepth.WithLabelValues(w.priority.String()).Inc()
+
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// the priority is not raised once the slot is granted
+		s.metricWaitTime.WithLabelValues(w.priority.String()).Observe(time.Since(start).Seconds())
+
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+
+	if w.granted {
+		// the slot was granted concurrently with the cancellation, pass it on
+		s.mu.Unlock()
+
+		s.releaseFunc()()
+
+		return nil, ctx.Err()
+	}
+
+	s.queues[w.priority].remove(w)
+	s.queued--
+	s.metricQueueDepth.WithLabelValues(w.priority.String()).Dec()
+
+	w.canceled = true
+
+	s.mu.Unlock()
+
+	return nil, ctx.Err()
+}
+
+// raise moves the waiter to the higher priority queue, behind the waiters of the same client.
+func (s *Scheduler) raise(w *waiter, priority Priority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w.granted || w.canceled || priority >= w.priority || priority < 0 {
+		return
+	}
+
+	s.queues[w.priority].remove(w)
+	s.metricQueueDepth.WithLabelValues(w.priority.String()).Dec()
+
+	w.priority = priority
+
+	s.queues[w.priority].push(w)
+	s.metricQueueDepth.WithLabelValues(w.priority.String()).Inc()
+}
+
+// QueueFull returns true if the new builds would be rejected.
+func (s *Scheduler) QueueFull() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.options.MaxQueueLength > 0 && s.queued >= s.options.MaxQueueLength
+}
+
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(s.release)
+	}
+}
+
+// release passes the slot to the next waiter, or frees it.
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.queues {
+		w := s.queues[i].pop()
+		if w == nil {
+			continue
+		}
+
+		s.queued--
+		s.metricQueueDepth.WithLabelValues(w.priority.String()).Dec()
+
+		w.granted = true
+		close(w.ready)
+
+		return
+	}
+
+	s.running--
+	s.metricRunning.Set(float64(s.running))
+}
+
+func (q *fairQueue) push(w *waiter) {
+	waiters, ok := q.waiters[w.client]
+	if !ok {
+		waiters = list.New()
+
+		q.waiters[w.client] = waiters
+		q.elements[w.client] = q.clients.PushBack(w.client)
+	}
+
+	waiters.PushBack(w)
+}
+
+// pop returns the first waiter of the next client in the round-robin order.
+func (q *fairQueue) pop() *waiter {
+	front := q.clients.Front()
+	if front == nil {
+		return nil
+	}
+
+	client := front.Value.(string) //nolint:forcetypeassert,errcheck
+	waiters := q.waiters[client]
+
+	w := waiters.Remove(waiters.Front()).(*waiter) //nolint:forcetypeassert,errcheck
+
+	if waiters.Len() == 0 {
+		q.removeClient(client)
+	} else {
+		// the client goes to the end of the line
+		q.clients.MoveToBack(front)
+	}
+
+	return w
+}
+
+func (q *fairQueue) remove(w *waiter) {
+	waiters := q.waiters[w.client]
+
+	for e := waiters.Front(); e != nil; e = e.Next() {
+		if e.Value == w {
+			waiters.Remove(e)
+
+			break
+		}
+	}
+
+	if waiters.Len() == 0 {
+		q.removeClient(w.client)
+	}
+}
+
+func (q *fairQueue) removeClient(client string) {
+	q.clients.Remove(q.elements[client])
+
+	delete(q.elements, client)
+	delete(q.waiters, client)
+}
+
+// Describe implements prom.Collector interface.
+func (s *Scheduler) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+// Collect implements prom.Collector interface.
+func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
+	s.metricQueueDepth.Collect(ch)
+	s.metricWaitTime.Collect(ch)
+	s.metricRejected.Collect(ch)
+	s.metricRunning.Collect(ch)
+}
+
+var _ prometheus.Collector = &Scheduler{}
@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+)
+
+// enqueue starts acquiring in the background, and waits until the request is queued.
+func enqueue(t *testing.T, s *scheduler.Scheduler, req scheduler.Request, order chan<- scheduler.Request, wg *sync.WaitGroup) {
+	t.Helper()
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		release, err := s.Acquire(t.Context(), req)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		order <- req
+
+		release()
+	}()
+
+	// the scheduler has no introspection, so wait for the queue depth to settle
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.New(scheduler.Options{Concurrency: 1})
+
+	release, err := s.Acquire(t.Context(), scheduler.Request{Client: "a"})
+	require.NoError(t, err)
+
+	order := make(chan scheduler.Request, 10)
+
+	var wg sync.WaitGroup
+
+	for _, req := range []scheduler.Request{
+		{Client: "a", Priority: scheduler.PriorityBackground},
+		{Client: "a", Priority: scheduler.PriorityInteractive},
+		{Client: "a", Priority: scheduler.PriorityInteractive},
+		{Client: "a", Priority: scheduler.PriorityInteractive},
+		{Client: "b", Priority: scheduler.PriorityInteractive},
+		{Client: "c", Priority: scheduler.PriorityInteractive},
+	} {
+		enqueue(t, s, req, order, &wg)
+	}
+
+	release()
+
+	wg.Wait()
+	close(order)
+
+	var clients []string
+
+	for req := range order {
+		clients = append(clients, req.Priority.String()+"/"+req.Client)
+	}
+
+	// interactive first, round-robin across the clients, then background
+	assert.Equal(t, []string{
+		"interactive/a",
+		"interactive/b",
+		"interactive/c",
+		"interactive/a",
+		"interactive/a",
+		"background/a",
+	}, clients)
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.New(scheduler.Options{Concurrency: 1, MaxQueueLength: 1})
+
+	release, err := s.Acquire(t.Context(), scheduler.Request{})
+	require.NoError(t, err)
+
+	assert.False(t, s.QueueFull())
+
+	order := make(chan scheduler.Request, 1)
+
+	var wg sync.WaitGroup
+
+	enqueue(t, s, scheduler.Request{Client: "queued"}, order, &wg)
+
+	assert.True(t, s.QueueFull())
+
+	_, err = s.Acquire(t.Context(), scheduler.Request{Client: "rejected"})
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[scheduler.QueueFullErrorTag](err))
+
+	release()
+	wg.Wait()
+
+	assert.Equal(t, "queued", (<-order).Client)
+	assert.False(t, s.QueueFull())
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.New(scheduler.Options{Concurrency: 1, MaxQueueLength: 1})
+
+	release, err := s.Acquire(t.Context(), scheduler.Request{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = s.Acquire(ctx, scheduler.Request{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the canceled request left the queue
+	assert.False(t, s.QueueFull())
+
+	release()
+
+	// releasing twice is a no-op, the slot is free
+	release()
+
+	release, err = s.Acquire(t.Context(), scheduler.Request{})
+	require.NoError(t, err)
+
+	release()
+}
+
+func TestSchedulerEscalation(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.New(scheduler.Options{Concurrency: 1})
+
+	release, err := s.Acquire(t.Context(), scheduler.Request{Client: "a"})
+	require.NoError(t, err)
+
+	order := make(chan scheduler.Request, 10)
+
+	var wg sync.WaitGroup
+
+	escalation := scheduler.NewEscalation(scheduler.PriorityBackground)
+	raisedEarly := scheduler.NewEscalation(scheduler.PriorityBackground)
+
+	// raised before it is queued
+	raisedEarly.Raise(scheduler.PriorityInteractive)
+
+	for _, req := range []scheduler.Request{
+		{Client: "background", Priority: scheduler.PriorityBackground},
+		{Client: "escalated", Priority: scheduler.PriorityBackground, Escalation: escalation},
+		{Client: "interactive", Priority: scheduler.PriorityInteractive},
+		{Client: "raised-early", Priority: scheduler.PriorityBackground, Escalation: raisedEarly},
+	} {
+		enqueue(t, s, req, order, &wg)
+	}
+
+	// raised while waiting, lower priorities are ignored
+	escalation.Raise(scheduler.PriorityInteractive)
+	escalation.Raise(scheduler.PriorityBackground)
+
+	assert.Equal(t, scheduler.PriorityInteractive, escalation.Priority())
+
+	release()
+
+	wg.Wait()
+	close(order)
+
+	var clients []string
+
+	for req := range order {
+		clients = append(clients, req.Client)
+	}
+
+	assert.Equal(t, []string{
+		"interactive",
+		"raised-early",
+		"escalated",
+		"background",
+	}, clients)
+}
@@ -5,6 +5,7 @@
 package asset
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +14,9 @@ import (
 
 // tmpDir holds a generates boot asset in a temporary directory.
 type tmpDir struct {
+	recordedInputs
+
+	checksums     Checksums
 	directoryPath string
 	assetPath     string
 	size          int64
@@ -49,6 +53,41 @@ func (t *tmpDir) Reader() (io.ReadCloser, error) {
 	return os.Open(t.assetPath)
 }
 
+// RangeReader returns a reader for the part of the boot asset.
+func (t *tmpDir) RangeReader(_ context.Context, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(t.assetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close() //nolint:errcheck
+
+		return nil, err
+	}
+
+	return limitReadCloser(f, length), nil
+}
+
+// Checksums returns the checksums of the boot asset.
+func (t *tmpDir) Checksums(context.Context) (Checksums, error) {
+	return t.checksums, nil
+}
+
+// computeChecksums computes the checksums of the generated boot asset.
+func (t *tmpDir) computeChecksums() error {
+	f, err := os.Open(t.assetPath)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	t.checksums, err = computeChecksums(f)
+
+	return err
+}
+
 // cleanup releases the boot asset.
 func (t *tmpDir) cleanup() error {
 	return os.RemoveAll(t.directoryPath)
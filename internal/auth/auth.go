@@ -0,0 +1,94 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package auth implements authentication of the Image Factory API callers.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// UnauthorizedErrorTag tags the errors when the caller is not authenticated.
+type UnauthorizedErrorTag struct{}
+
+// ForbiddenErrorTag tags the errors when the authenticated caller is not allowed to access the resource.
+type ForbiddenErrorTag struct{}
+
+// Identity is the authenticated caller.
+type Identity struct {
+	// Subject identifies the caller: the token name, or the JWT subject.
+	Subject string
+	// Method is the authentication method which authenticated the caller.
+	Method string
+}
+
+// String returns the subject qualified with the method (e.g. static:admin), so that the subjects of different methods don't collide.
+func (identity Identity) String() string {
+	return identity.Method + ":" + identity.Subject
+}
+
+// ValidateQualifiedSubject checks that the subject is qualified with a known authentication method, see Identity.String.
+func ValidateQualifiedSubject(subject string) error {
+	method, name, ok := strings.Cut(subject, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("subject %q is not qualified with the authentication method, expected <method>:<subject>", subject)
+	}
+
+	if method != MethodStatic && method != MethodOIDC {
+		return fmt.Errorf("subject %q has unknown authentication method %q, expected %q or %q", subject, method, MethodStatic, MethodOIDC)
+	}
+
+	return nil
+}
+
+// Authenticator authenticates the bearer token.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Identity, error)
+}
+
+// Chain tries the authenticators in order, and returns the first successful result.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (chain Chain) Authenticate(ctx context.Context, token string) (Identity, error) {
+	var errs []error
+
+	for _, authenticator := range chain {
+		identity, err := authenticator.Authenticate(ctx, token)
+		if err == nil {
+			return identity, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return Identity{}, errors.New("no authenticators configured")
+	}
+
+	return Identity{}, errors.Join(errs...)
+}
+
+// TokenFromRequest extracts the bearer token from the request.
+//
+// The token is either passed as a bearer token, or as the password of the basic authentication
+// (as container registry clients do, e.g. `docker login`).
+func TokenFromRequest(r *http.Request) (string, bool) {
+	if _, password, ok := r.BasicAuth(); ok {
+		return password, password != ""
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
@@ -0,0 +1,262 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/auth"
+)
+
+func TestTokenFromRequest(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name          string
+		header        string
+		expectedToken string
+	}{
+		{
+			name: "no header",
+		},
+		{
+			name:          "bearer",
+			header:        "Bearer secret",
+			expectedToken: "secret",
+		},
+		{
+			name:          "bearer lowercase",
+			header:        "bearer secret",
+			expectedToken: "secret",
+		},
+		{
+			name:          "basic",
+			header:        "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret")),
+			expectedToken: "secret",
+		},
+		{
+			name:   "basic without password",
+			header: "Basic " + base64.StdEncoding.EncodeToString([]byte("user:")),
+		},
+		{
+			name:   "unsupported scheme",
+			header: "Digest secret",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://localhost/", nil)
+			require.NoError(t, err)
+
+			if test.header != "" {
+				r.Header.Set("Authorization", test.header)
+			}
+
+			token, ok := auth.TokenFromRequest(r)
+
+			assert.Equal(t, test.expectedToken, token)
+			assert.Equal(t, test.expectedToken != "", ok)
+		})
+	}
+}
+
+func TestStaticTokens(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens")
+
+	require.NoError(t, os.WriteFile(path, []byte("# CI tokens\n\nci:s3cr3t\nops:an:other\n"), 0o600))
+
+	tokens, err := auth.LoadStaticTokens(path)
+	require.NoError(t, err)
+
+	identity, err := tokens.Authenticate(t.Context(), "s3cr3t")
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Subject: "ci", Method: auth.MethodStatic}, identity)
+
+	identity, err = tokens.Authenticate(t.Context(), "an:other")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", identity.Subject)
+
+	_, err = tokens.Authenticate(t.Context(), "s3cr3")
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("invalid\n"), 0o600))
+
+	_, err = auth.LoadStaticTokens(path)
+	require.Error(t, err)
+
+	_, err = auth.NewStaticTokens(map[string]string{"a": "same", "b": "same"})
+	require.Error(t, err)
+}
+
+func TestOIDC(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	authenticator, err := auth.NewOIDC(t.Context(), auth.OIDCOptions{
+		KeySet:   &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{key.Public()}},
+		Issuer:   "https://issuer.example.com",
+		Audience: "image-factory",
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	identity, err := authenticator.Authenticate(t.Context(), signJWT(t, key, map[string]any{
+		"iss": "https://issuer.example.com",
+		"aud": "image-factory",
+		"sub": "builder@example.com",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, auth.Identity{Subject: "builder@example.com", Method: auth.MethodOIDC}, identity)
+
+	for _, test := range []struct {
+		claims map[string]any
+		name   string
+	}{
+		{
+			name: "wrong issuer",
+			claims: map[string]any{
+				"iss": "https://other.example.com",
+				"aud": "image-factory",
+				"sub": "builder@example.com",
+				"exp": now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name: "wrong audience",
+			claims: map[string]any{
+				"iss": "https://issuer.example.com",
+				"aud": "other",
+				"sub": "builder@example.com",
+				"exp": now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name: "expired",
+			claims: map[string]any{
+				"iss": "https://issuer.example.com",
+				"aud": "image-factory",
+				"sub": "builder@example.com",
+				"exp": now.Add(-time.Hour).Unix(),
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := authenticator.Authenticate(t.Context(), signJWT(t, key, test.claims))
+			require.Error(t, err)
+		})
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = authenticator.Authenticate(t.Context(), signJWT(t, otherKey, map[string]any{
+		"iss": "https://issuer.example.com",
+		"aud": "image-factory",
+		"sub": "builder@example.com",
+		"exp": now.Add(time.Hour).Unix(),
+	}))
+	require.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	first, err := auth.NewStaticTokens(map[string]string{"first": "one"})
+	require.NoError(t, err)
+
+	second, err := auth.NewStaticTokens(map[string]string{"second": "two"})
+	require.NoError(t, err)
+
+	chain := auth.Chain{first, second}
+
+	identity, err := chain.Authenticate(t.Context(), "two")
+	require.NoError(t, err)
+	assert.Equal(t, "second", identity.Subject)
+
+	_, err = chain.Authenticate(t.Context(), "three")
+	require.Error(t, err)
+
+	_, err = auth.Chain{}.Authenticate(t.Context(), "one")
+	require.Error(t, err)
+}
+
+func TestQualifiedSubject(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "static:admin", auth.Identity{Subject: "admin", Method: auth.MethodStatic}.String())
+	assert.Equal(t, "oidc:admin", auth.Identity{Subject: "admin", Method: auth.MethodOIDC}.String())
+
+	for _, test := range []struct {
+		subject       string
+		expectedError string
+	}{
+		{subject: "static:admin"},
+		{subject: "oidc:alice@example.com"},
+		{subject: "oidc:urn:example:alice"},
+		{subject: "admin", expectedError: "not qualified with the authentication method"},
+		{subject: "static:", expectedError: "not qualified with the authentication method"},
+		{subject: "ldap:admin", expectedError: `unknown authentication method "ldap"`},
+	} {
+		t.Run(test.subject, func(t *testing.T) {
+			t.Parallel()
+
+			err := auth.ValidateQualifiedSubject(test.subject)
+
+			if test.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, test.expectedError)
+			}
+		})
+	}
+}
+
+// signJWT signs the claims as the ES256 JWT.
+func signJWT(t *testing.T, key *ecdsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,81 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// MethodOIDC is the authentication method of the OIDC JWT tokens.
+const MethodOIDC = "oidc"
+
+// OIDCOptions configures the OIDC authenticator.
+type OIDCOptions struct {
+	// KeySet verifies the JWT signatures.
+	//
+	// If not set, the keys are fetched (and refreshed) from the JWKSURL.
+	KeySet oidc.KeySet
+
+	// Issuer is the expected `iss` claim of the JWT.
+	Issuer string
+	// JWKSURL is the URL of the JSON Web Key Set of the issuer.
+	JWKSURL string
+	// Audience is the expected `aud` claim of the JWT, if empty the audience is not checked.
+	Audience string
+}
+
+// OIDC authenticates the callers with the JWT bearer tokens issued by the OIDC provider.
+type OIDC struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDC creates the OIDC authenticator.
+//
+// The context is used to fetch the keys from the JWKS URL, so it should be long-lived.
+func NewOIDC(ctx context.Context, opts OIDCOptions) (*OIDC, error) {
+	if opts.Issuer == "" {
+		return nil, errors.New("OIDC issuer is required")
+	}
+
+	keySet := opts.KeySet
+
+	if keySet == nil {
+		if opts.JWKSURL == "" {
+			return nil, errors.New("OIDC JWKS URL is required")
+		}
+
+		keySet = oidc.NewRemoteKeySet(ctx, opts.JWKSURL)
+	}
+
+	return &OIDC{
+		verifier: oidc.NewVerifier(opts.Issuer, keySet, &oidc.Config{
+			ClientID:          opts.Audience,
+			SkipClientIDCheck: opts.Audience == "",
+			SupportedSigningAlgs: []string{
+				oidc.RS256, oidc.RS384, oidc.RS512,
+				oidc.ES256, oidc.ES384, oidc.ES512,
+				oidc.PS256, oidc.PS384, oidc.PS512,
+				oidc.EdDSA,
+			},
+		}),
+	}, nil
+}
+
+// Authenticate implements Authenticator.
+func (o *OIDC) Authenticate(ctx context.Context, token string) (Identity, error) {
+	idToken, err := o.verifier.Verify(ctx, token)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid OIDC token: %w", err)
+	}
+
+	return Identity{
+		Subject: idToken.Subject,
+		Method:  MethodOIDC,
+	}, nil
+}
@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MethodStatic is the authentication method of the static tokens.
+const MethodStatic = "static"
+
+// StaticTokens authenticates the callers with the static bearer tokens.
+type StaticTokens struct {
+	// tokens maps the token hash to the token name, hashing avoids timing attacks on the map lookup.
+	tokens map[[sha256.Size]byte]string
+}
+
+// NewStaticTokens creates the authenticator from the map of token names to tokens.
+func NewStaticTokens(tokens map[string]string) (*StaticTokens, error) {
+	s := &StaticTokens{
+		tokens: make(map[[sha256.Size]byte]string, len(tokens)),
+	}
+
+	for tokenName, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("token %q is empty", tokenName)
+		}
+
+		hash := sha256.Sum256([]byte(token))
+
+		if existing, ok := s.tokens[hash]; ok {
+			return nil, fmt.Errorf("token %q is the same as token %q", tokenName, existing)
+		}
+
+		s.tokens[hash] = tokenName
+	}
+
+	return s, nil
+}
+
+// LoadStaticTokens loads the static tokens from the file.
+//
+// Each line of the file is `<name>:<token>`, empty lines and lines starting with `#` are ignored.
+func LoadStaticTokens(path string) (*StaticTokens, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokens file: %w", err)
+	}
+
+	tokens := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tokenName, token, ok := strings.Cut(line, ":")
+		if !ok || tokenName == "" {
+			return nil, fmt.Errorf("invalid tokens file line %d: expected <name>:<token>", lineNo)
+		}
+
+		if _, exists := tokens[tokenName]; exists {
+			return nil, fmt.Errorf("invalid tokens file line %d: duplicate token name %q", lineNo, tokenName)
+		}
+
+		tokens[tokenName] = token
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tokens file: %w", err)
+	}
+
+	return NewStaticTokens(tokens)
+}
+
+// Authenticate implements Authenticator.
+func (s *StaticTokens) Authenticate(_ context.Context, token string) (Identity, error) {
+	tokenName, ok := s.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return Identity{}, errors.New("unknown token")
+	}
+
+	return Identity{
+		Subject: tokenName,
+		Method:  MethodStatic,
+	}, nil
+}
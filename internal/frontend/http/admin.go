@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/siderolabs/gen/xerrors"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/profile"
+)
+
+// cacheEntryResponse describes the asset in the cache.
+type cacheEntryResponse struct {
+	Created time.Time `json:"created,omitzero"`
+
+	ProfileHash string `json:"profile_hash"`
+	SchematicID string `json:"schematic_id,omitempty"`
+	Version     string `json:"talos_version,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+	Profile     string `json:"profile,omitempty"`
+	Digest      string `json:"digest"`
+	Size        int64  `json:"size"`
+}
+
+// cachePurgeResponse lists the purged cache entries.
+type cachePurgeResponse struct {
+	Purged []cacheEntryResponse `json:"purged"`
+}
+
+func newCacheEntryResponse(entry asset.CacheEntry) cacheEntryResponse {
+	return cacheEntryResponse{
+		Created:     entry.Created,
+		ProfileHash: entry.ProfileHash,
+		SchematicID: entry.SchematicID,
+		Version:     entry.Version,
+		Kind:        entry.Kind,
+		Arch:        entry.Arch,
+		Profile:     entry.Profile,
+		Digest:      entry.Digest,
+		Size:        entry.Size,
+	}
+}
+
+func newCacheEntriesResponse(entries []asset.CacheEntry) []cacheEntryResponse {
+	resp := make([]cacheEntryResponse, 0, len(entries))
+
+	for _, entry := range entries {
+		resp = append(resp, newCacheEntryResponse(entry))
+	}
+
+	return resp
+}
+
+// cacheFilter builds the cache filter from the request query (`schematic`, `version`) and the profile hash path parameter.
+func cacheFilter(r *http.Request, p httprouter.Params) asset.CacheFilter {
+	return asset.CacheFilter{
+		ProfileHash: p.ByName("hash"),
+		SchematicID: r.URL.Query().Get("schematic"),
+		Version:     r.URL.Query().Get("version"),
+	}
+}
+
+// handleCacheList handles listing the assets in the cache, optionally filtered by the schematic and Talos version.
+func (f *Frontend) handleCacheList(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	if err := f.authorizeAdmin(ctx, r); err != nil {
+		return err
+	}
+
+	entries, err := f.assetBuilder.ListCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := cacheFilter(r, p)
+
+	var matched []asset.CacheEntry
+
+	for _, entry := range entries {
+		if filter.Matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(newCacheEntriesResponse(matched))
+}
+
+// handleCacheGet handles inspecting the asset in the cache by the profile hash.
+func (f *Frontend) handleCacheGet(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	if err := f.authorizeAdmin(ctx, r); err != nil {
+		return err
+	}
+
+	entry, err := f.assetBuilder.InspectCache(ctx, p.ByName("hash"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(newCacheEntryResponse(entry))
+}
+
+// handleCachePurge handles purging the assets from the cache by the profile hash, the schematic or the Talos version.
+func (f *Frontend) handleCachePurge(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	if err := f.authorizeAdmin(ctx, r); err != nil {
+		return err
+	}
+
+	filter := cacheFilter(r, p)
+	if filter.IsEmpty() {
+		return xerrors.NewTaggedf[profile.InvalidErrorTag]("profile hash, schematic or version is required to purge the cache")
+	}
+
+	purged, err := f.assetBuilder.PurgeCache(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if filter.ProfileHash != "" && len(purged) == 0 {
+		return xerrors.NewTaggedf[asset.ErrNotFoundTag]("asset %q not found in the cache", filter.ProfileHash)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(cachePurgeResponse{
+		Purged: newCacheEntriesResponse(purged),
+	})
+}
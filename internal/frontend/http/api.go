@@ -0,0 +1,65 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/yaml.v3"
+)
+
+// apiPrefix is the path prefix of the versioned API.
+const apiPrefix = "/api/v1"
+
+//go:embed openapi.yaml
+var openAPIYAML []byte
+
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc any
+
+	if err := yaml.Unmarshal(openAPIYAML, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI document: %w", err)
+	}
+
+	return json.Marshal(doc)
+})
+
+// handleOpenAPI serves the OpenAPI document of the versioned API (as JSON or YAML depending on the path).
+func (f *Frontend) handleOpenAPI(_ context.Context, w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	if strings.HasSuffix(r.URL.Path, ".yaml") {
+		w.Header().Set("Content-Type", "application/yaml")
+
+		_, err := w.Write(openAPIYAML)
+
+		return err
+	}
+
+	data, err := openAPIJSON()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// routePrefix returns the API path prefix if the request was made via the versioned API.
+func routePrefix(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		return apiPrefix
+	}
+
+	return ""
+}
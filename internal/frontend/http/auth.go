@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/siderolabs/gen/xerrors"
+	"go.uber.org/zap"
+
+	"github.com/siderolabs/image-factory/internal/auth"
+	"github.com/siderolabs/image-factory/pkg/schematic"
+)
+
+// authRealm is the realm of the authentication challenge.
+const authRealm = "image-factory"
+
+// authorize checks whether the caller is allowed to access the resource.
+//
+// Authentication is required if it is enforced for the whole factory, or if the resource is restricted (private schematic).
+func (f *Frontend) authorize(ctx context.Context, r *http.Request, restricted bool) error {
+	if !restricted && !f.options.RequireAuth {
+		return nil
+	}
+
+	_, err := f.authenticate(ctx, r)
+
+	return err
+}
+
+// authorizeAdmin checks whether the caller is allowed to access the admin API.
+//
+// The admin API is only available to the authenticated callers listed in the admin subjects.
+// The subjects are qualified with the authentication method, so that e.g. a static token name doesn't match an OIDC subject.
+func (f *Frontend) authorizeAdmin(ctx context.Context, r *http.Request) error {
+	identity, err := f.authenticate(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(f.options.AdminSubjects, identity.String()) {
+		f.logger.Warn("admin access denied", zap.String("path", r.URL.Path), zap.Stringer("identity", identity))
+
+		return xerrors.NewTaggedf[auth.ForbiddenErrorTag]("admin access denied")
+	}
+
+	return nil
+}
+
+// authenticate authenticates the caller by the bearer token.
+func (f *Frontend) authenticate(ctx context.Context, r *http.Request) (auth.Identity, error) {
+	if f.options.Authenticator == nil {
+		return auth.Identity{}, xerrors.NewTaggedf[auth.UnauthorizedErrorTag]("authentication is not configured")
+	}
+
+	token, ok := auth.TokenFromRequest(r)
+	if !ok {
+		return auth.Identity{}, xerrors.NewTaggedf[auth.UnauthorizedErrorTag]("authentication required")
+	}
+
+	identity, err := f.options.Authenticator.Authenticate(ctx, token)
+	if err != nil {
+		f.logger.Warn("authentication failed", zap.String("path", r.URL.Path), zap.Error(err))
+
+		return auth.Identity{}, xerrors.NewTaggedf[auth.UnauthorizedErrorTag]("authentication failed")
+	}
+
+	f.logger.Debug("authenticated", zap.String("path", r.URL.Path), zap.String("subject", identity.Subject), zap.String("method", identity.Method))
+
+	return identity, nil
+}
+
+// getSchematic fetches the schematic and checks whether the caller is allowed to access it.
+func (f *Frontend) getSchematic(ctx context.Context, r *http.Request, schematicID string) (*schematic.Schematic, error) {
+	cfg, err := f.schematicFactory.Get(ctx, schematicID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.authorize(ctx, r, cfg.Private); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// authChallenge returns the WWW-Authenticate challenge for the request.
+//
+// Container registry clients get the basic authentication challenge, so that the token can be passed as the password.
+func authChallenge(r *http.Request) string {
+	if r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/") {
+		return `Basic realm="` + authRealm + `"`
+	}
+
+	return `Bearer realm="` + authRealm + `"`
+}
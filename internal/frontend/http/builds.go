@@ -0,0 +1,150 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/siderolabs/gen/xerrors"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/profile"
+)
+
+// buildRequest is the request to submit an asynchronous build.
+type buildRequest struct {
+	Schematic string `json:"schematic"`
+	Version   string `json:"version"`
+	Path      string `json:"path"`
+}
+
+// buildResponse is the status of the asynchronous build.
+type buildResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ID     string          `json:"id"`
+	Status asset.JobStatus `json:"status"`
+	Error  string          `json:"error,omitempty"`
+	// URL is the download URL of the asset, set once the build is done.
+	URL string `json:"url,omitempty"`
+}
+
+func newBuildResponse(job asset.Job) buildResponse {
+	resp := buildResponse{
+		ID:        job.ID,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+
+	if job.Status == asset.JobStatusDone {
+		resp.URL = job.Location
+	}
+
+	return resp
+}
+
+// handleBuildCreate handles submitting an asynchronous build of the boot asset.
+func (f *Frontend) handleBuildCreate(ctx context.Context, w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	var req buildRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return xerrors.NewTaggedf[profile.InvalidErrorTag]("error decoding build request: %w", err)
+	}
+
+	if err := r.Body.Close(); err != nil {
+		return err
+	}
+
+	if req.Schematic == "" || req.Version == "" || req.Path == "" {
+		return xerrors.NewTaggedf[profile.InvalidErrorTag]("schematic, version and path are required")
+	}
+
+	prof, source, version, err := f.imageProfile(ctx, r, req.Schematic, req.Version, req.Path)
+	if err != nil {
+		return err
+	}
+
+	location := f.options.ExternalURL.JoinPath("image", req.Schematic, "v"+version.String(), req.Path)
+
+	job, err := f.assetBuilder.Submit(ctx, prof, version.String(), source, location.String())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", f.options.ExternalURL.JoinPath(routePrefix(r), "builds", job.ID).String())
+	w.WriteHeader(http.StatusAccepted)
+
+	return json.NewEncoder(w).Encode(newBuildResponse(job))
+}
+
+// handleBuildGet handles polling the status of the asynchronous build.
+//
+// The access to the build is authorized as the access to the schematic of the build.
+func (f *Frontend) handleBuildGet(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	job, err := f.assetBuilder.Job(p.ByName("id"))
+	if err != nil {
+		return err
+	}
+
+	if err = f.authorizeBuild(ctx, r, job.SchematicID); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(newBuildResponse(job))
+}
+
+// handleBuildLog handles fetching the log of the latest build of the asset by the build ID or by the profile hash.
+//
+// The access to the log is authorized as the access to the schematic of the build (or the schematic the asset was built for).
+func (f *Frontend) handleBuildLog(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	profileHash, schematicID := p.ByName("id"), ""
+
+	if job, err := f.assetBuilder.Job(profileHash); err == nil {
+		profileHash, schematicID = job.ProfileHash, job.SchematicID
+	}
+
+	log, logSchematicID, err := f.assetBuilder.BuildLog(profileHash)
+	if err != nil {
+		return err
+	}
+
+	if schematicID == "" {
+		schematicID = logSchematicID
+	}
+
+	if err = f.authorizeBuild(ctx, r, schematicID); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	_, err = io.WriteString(w, log)
+
+	return err
+}
+
+// authorizeBuild checks whether the caller is allowed to access the build for the schematic.
+func (f *Frontend) authorizeBuild(ctx context.Context, r *http.Request, schematicID string) error {
+	if schematicID == "" {
+		// unknown schematic, require authentication
+		return f.authorize(ctx, r, true)
+	}
+
+	_, err := f.getSchematic(ctx, r, schematicID)
+
+	return err
+}
@@ -8,9 +8,12 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/siderolabs/gen/xerrors"
 
 	"github.com/siderolabs/image-factory/pkg/schematic"
 )
@@ -31,6 +34,14 @@ func (f *Frontend) handleSchematicCreate(ctx context.Context, w http.ResponseWri
 		return err
 	}
 
+	if cfg.Private && f.options.Authenticator == nil {
+		return xerrors.NewTaggedf[schematic.InvalidErrorTag]("private schematics require authentication to be configured")
+	}
+
+	if err = f.authorize(ctx, r, cfg.Private); err != nil {
+		return err
+	}
+
 	id, err := f.schematicFactory.Put(ctx, cfg)
 	if err != nil {
 		return err
@@ -47,3 +58,62 @@ func (f *Frontend) handleSchematicCreate(ctx context.Context, w http.ResponseWri
 
 	return json.NewEncoder(w).Encode(resp)
 }
+
+// handleSchematicGet handles retrieval of the schematic.
+//
+// The schematic is returned as YAML by default, or as JSON if requested via the Accept header.
+func (f *Frontend) handleSchematicGet(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	return f.serveSchematic(ctx, w, r, p.ByName("schematic"), acceptsJSON(r, false))
+}
+
+// handleAPISchematicGet handles retrieval of the schematic via the versioned API.
+//
+// The schematic is returned as JSON by default, or as YAML if requested via the Accept header.
+func (f *Frontend) handleAPISchematicGet(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	return f.serveSchematic(ctx, w, r, p.ByName("schematic"), acceptsJSON(r, true))
+}
+
+func (f *Frontend) serveSchematic(ctx context.Context, w http.ResponseWriter, r *http.Request, schematicID string, asJSON bool) error {
+	cfg, err := f.getSchematic(ctx, r, schematicID)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+
+		return json.NewEncoder(w).Encode(cfg)
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// acceptsJSON returns true if the JSON is the preferred response format for the request.
+//
+// If neither JSON nor YAML is explicitly accepted, defaultJSON is returned.
+func acceptsJSON(r *http.Request, defaultJSON bool) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/json":
+			return true
+		case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+			return false
+		}
+	}
+
+	return defaultJSON
+}
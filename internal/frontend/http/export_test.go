@@ -0,0 +1,31 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// NewTestFrontend returns the frontend without the backing services, enough to test the request handling.
+func NewTestFrontend(logger *zap.Logger, options Options) *Frontend {
+	return &Frontend{
+		logger:  logger,
+		options: options,
+	}
+}
+
+// WrapText wraps the handler reporting the errors as plain text (legacy routes).
+func (f *Frontend) WrapText(h func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error) httprouter.Handle {
+	return f.wrapper(h, errorFormatText)
+}
+
+// WrapJSON wraps the handler reporting the errors as JSON (versioned API).
+func (f *Frontend) WrapJSON(h func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error) httprouter.Handle {
+	return f.wrapper(h, errorFormatJSON)
+}
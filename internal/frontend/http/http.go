@@ -8,6 +8,7 @@ package http
 import (
 	"context"
 	"crypto"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -29,12 +30,16 @@ import (
 
 	"github.com/siderolabs/image-factory/internal/artifacts"
 	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+	"github.com/siderolabs/image-factory/internal/auth"
 	"github.com/siderolabs/image-factory/internal/image/signer"
 	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/ratelimit"
 	"github.com/siderolabs/image-factory/internal/remotewrap"
 	"github.com/siderolabs/image-factory/internal/schematic"
 	"github.com/siderolabs/image-factory/internal/schematic/storage"
 	"github.com/siderolabs/image-factory/internal/secureboot"
+	"github.com/siderolabs/image-factory/pkg/client"
 	schematicpkg "github.com/siderolabs/image-factory/pkg/schematic"
 )
 
@@ -65,6 +70,24 @@ type Options struct {
 
 	RemoteOptions           []remote.Option
 	RegistryRefreshInterval time.Duration
+
+	// RedirectCachedAssets redirects the downloads of the cached assets to the cache storage (registry blob URL,
+	// pre-signed object store URL) instead of proxying them.
+	RedirectCachedAssets bool
+
+	// Authenticator authenticates the callers, authentication is disabled if not set.
+	Authenticator auth.Authenticator
+	// RequireAuth requires authentication for creating schematics, building and downloading assets.
+	//
+	// If not set, authentication is only required for private schematics.
+	RequireAuth bool
+	// AdminSubjects are the authenticated subjects allowed to access the admin API, qualified with the method (see auth.Identity.String).
+	AdminSubjects []string
+
+	// RateLimiter limits the requests and fresh builds per client, rate limiting is disabled if not set.
+	RateLimiter *ratelimit.Limiter
+	// ClientIPHeader is the header with the client IP address set by the reverse proxy (e.g. X-Forwarded-For).
+	ClientIPHeader string
 }
 
 // NewFrontend creates a new HTTP frontend.
@@ -109,29 +132,66 @@ func NewFrontend(
 	})
 
 	registerRoute := func(registrator func(string, httprouter.Handle), path string, handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error) {
-		registrator(path, httproutermiddleware.Handler(path, frontend.wrapper(handler), mdlw))
+		registrator(path, httproutermiddleware.Handler(path, frontend.wrapper(handler, errorFormatText), mdlw))
 	}
 
+	registerAPIRoute := func(registrator func(string, httprouter.Handle), path string, handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error) {
+		path = apiPrefix + path
+
+		registrator(path, httproutermiddleware.Handler(path, frontend.wrapper(handler, errorFormatJSON), mdlw))
+	}
+
+	// versioned API, the routes below are kept as aliases
+	registerAPIRoute(frontend.router.GET, "/openapi.json", frontend.handleOpenAPI)
+	registerAPIRoute(frontend.router.GET, "/openapi.yaml", frontend.handleOpenAPI)
+	registerAPIRoute(frontend.router.POST, "/schematics", frontend.handleSchematicCreate)
+	registerAPIRoute(frontend.router.GET, "/schematics/:schematic", frontend.handleAPISchematicGet)
+	registerAPIRoute(frontend.router.GET, "/versions", frontend.handleVersions)
+	registerAPIRoute(frontend.router.GET, "/versions/:version/extensions", frontend.handleOfficialExtensions)
+	registerAPIRoute(frontend.router.GET, "/versions/:version/overlays", frontend.handleOfficialOverlays)
+	registerAPIRoute(frontend.router.GET, "/images/:schematic/:version/:path", frontend.handleImage)
+	registerAPIRoute(frontend.router.HEAD, "/images/:schematic/:version/:path", frontend.handleImage)
+	registerAPIRoute(frontend.router.POST, "/builds", frontend.handleBuildCreate)
+	registerAPIRoute(frontend.router.GET, "/builds/:id", frontend.handleBuildGet)
+	registerAPIRoute(frontend.router.GET, "/builds/:id/log", frontend.handleBuildLog)
+	registerAPIRoute(frontend.router.GET, "/admin/cache", frontend.handleCacheList)
+	registerAPIRoute(frontend.router.DELETE, "/admin/cache", frontend.handleCachePurge)
+	registerAPIRoute(frontend.router.GET, "/admin/cache/:hash", frontend.handleCacheGet)
+	registerAPIRoute(frontend.router.DELETE, "/admin/cache/:hash", frontend.handleCachePurge)
+
 	// images
 	registerRoute(frontend.router.GET, "/image/:schematic/:version/:path", frontend.handleImage)
 	registerRoute(frontend.router.HEAD, "/image/:schematic/:version/:path", frontend.handleImage)
 
+	// asynchronous builds
+	registerRoute(frontend.router.POST, "/builds", frontend.handleBuildCreate)
+	registerRoute(frontend.router.GET, "/builds/:id", frontend.handleBuildGet)
+	registerRoute(frontend.router.GET, "/builds/:id/log", frontend.handleBuildLog)
+
+	// cache administration
+	registerRoute(frontend.router.GET, "/admin/cache", frontend.handleCacheList)
+	registerRoute(frontend.router.DELETE, "/admin/cache", frontend.handleCachePurge)
+	registerRoute(frontend.router.GET, "/admin/cache/:hash", frontend.handleCacheGet)
+	registerRoute(frontend.router.DELETE, "/admin/cache/:hash", frontend.handleCachePurge)
+
 	// PXE
 	registerRoute(frontend.router.GET, "/pxe/:schematic/:version/:path", frontend.handlePXE)
 
 	// registry
-	registerRoute(frontend.router.GET, "/v2", frontend.handleHealth)
-	registerRoute(frontend.router.HEAD, "/v2", frontend.handleHealth)
+	registerRoute(frontend.router.GET, "/v2", frontend.handleRegistryBase)
+	registerRoute(frontend.router.HEAD, "/v2", frontend.handleRegistryBase)
 	registerRoute(frontend.router.GET, "/healthz", frontend.handleHealth)
 	registerRoute(frontend.router.HEAD, "/healthz", frontend.handleHealth)
 	registerRoute(frontend.router.GET, "/v2/:image/:schematic/blobs/:digest", frontend.handleBlob)
 	registerRoute(frontend.router.HEAD, "/v2/:image/:schematic/blobs/:digest", frontend.handleBlob)
 	registerRoute(frontend.router.GET, "/v2/:image/:schematic/manifests/:tag", frontend.handleManifest)
 	registerRoute(frontend.router.HEAD, "/v2/:image/:schematic/manifests/:tag", frontend.handleManifest)
+	registerRoute(frontend.router.GET, "/v2/:image/:schematic/referrers/:digest", frontend.handleReferrers)
 	registerRoute(frontend.router.GET, "/oci/cosign/signing-key.pub", frontend.handleCosignSigningKeyPub)
 
 	// schematic
 	registerRoute(frontend.router.POST, "/schematics", frontend.handleSchematicCreate)
+	registerRoute(frontend.router.GET, "/schematics/:schematic", frontend.handleSchematicGet)
 
 	// meta
 	registerRoute(frontend.router.GET, "/versions", frontend.handleVersions)
@@ -160,13 +220,26 @@ func (f *Frontend) Handler() http.Handler {
 	return f.router
 }
 
-func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error) httprouter.Handle {
+// errorFormat defines how the handler errors are reported to the client.
+type errorFormat int
+
+const (
+	// errorFormatText reports errors as plain text (legacy routes).
+	errorFormatText errorFormat = iota
+	// errorFormatJSON reports errors as JSON objects with stable error codes (versioned API).
+	errorFormatJSON
+)
+
+func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error, format errorFormat) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		ctx := r.Context()
 
 		start := time.Now()
 
-		err := h(ctx, w, r, p)
+		ctx, err := f.rateLimit(ctx, r)
+		if err == nil {
+			err = h(ctx, w, r, p)
+		}
 
 		duration := time.Since(start)
 		status := http.StatusOK
@@ -175,25 +248,28 @@ func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r
 		switch {
 		case err == nil:
 			// happy case
-		case xerrors.TagIs[storage.ErrNotFoundTag](err):
-			level = zap.WarnLevel
-			status = http.StatusNotFound
-
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case xerrors.TagIs[profile.InvalidErrorTag](err),
-			xerrors.TagIs[schematicpkg.InvalidErrorTag](err):
-			level = zap.WarnLevel
-			status = http.StatusBadRequest
-
-			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, context.Canceled):
 			status = 499
 			// client closed connection
 		default:
-			status = http.StatusInternalServerError
-			level = zap.ErrorLevel
+			var code client.ErrorCode
 
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			status, code = errorStatus(err)
+
+			message := err.Error()
+			level = zap.WarnLevel
+
+			switch status {
+			case http.StatusInternalServerError:
+				level = zap.ErrorLevel
+				message = f.internalErrorMessage(r, err)
+			case http.StatusUnauthorized:
+				w.Header().Set("WWW-Authenticate", authChallenge(r))
+			case http.StatusTooManyRequests:
+				setRetryAfter(w, err)
+			}
+
+			writeError(w, format, status, code, message)
 		}
 
 		f.logger.Log(level, "request",
@@ -206,6 +282,69 @@ func (f *Frontend) wrapper(h func(ctx context.Context, w http.ResponseWriter, r
 	}
 }
 
+// errorStatus maps the handler error to the HTTP status code and the API error code.
+func errorStatus(err error) (int, client.ErrorCode) {
+	var (
+		exceeded *ratelimit.ExceededError
+		buildErr *asset.BuildError
+	)
+
+	switch {
+	case xerrors.TagIs[storage.ErrNotFoundTag](err):
+		return http.StatusNotFound, client.ErrorCodeNotFound
+	case xerrors.TagIs[auth.UnauthorizedErrorTag](err):
+		return http.StatusUnauthorized, client.ErrorCodeUnauthorized
+	case xerrors.TagIs[auth.ForbiddenErrorTag](err):
+		return http.StatusForbidden, client.ErrorCodeForbidden
+	case errors.As(err, &exceeded):
+		return http.StatusTooManyRequests, client.ErrorCodeRateLimited
+	case xerrors.TagIs[scheduler.QueueFullErrorTag](err):
+		return http.StatusServiceUnavailable, client.ErrorCodeQueueFull
+	case errors.As(err, &buildErr):
+		return http.StatusInternalServerError, client.ErrorCodeBuildFailed
+	case xerrors.TagIs[profile.InvalidErrorTag](err):
+		return http.StatusBadRequest, client.ErrorCodeInvalidProfile
+	case xerrors.TagIs[schematicpkg.InvalidErrorTag](err):
+		return http.StatusBadRequest, client.ErrorCodeInvalidSchematic
+	default:
+		return http.StatusInternalServerError, client.ErrorCodeInternal
+	}
+}
+
+// internalErrorMessage hides the details of the internal errors, except for the failed builds,
+// which are reported with the excerpt of the build log.
+func (f *Frontend) internalErrorMessage(r *http.Request, err error) string {
+	var buildErr *asset.BuildError
+
+	if !errors.As(err, &buildErr) {
+		return "internal server error"
+	}
+
+	return fmt.Sprintf("%s\n\nlast lines of the build log (full log at %s):\n%s",
+		buildErr.Error(),
+		f.options.ExternalURL.JoinPath(routePrefix(r), "builds", buildErr.ProfileHash, "log"),
+		buildErr.LogExcerpt,
+	)
+}
+
+// writeError writes the error response in the requested format.
+func writeError(w http.ResponseWriter, format errorFormat, status int, code client.ErrorCode, message string) {
+	if format == errorFormatText {
+		http.Error(w, message, status)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(client.APIError{ //nolint:errcheck,errchkjson
+		Code:    code,
+		Message: message,
+	})
+}
+
 // Use several ways to detect language.
 func (f *Frontend) getLocalizer(r *http.Request) *i18n.Localizer {
 	lang := r.URL.Query().Get("lang")
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	frontendhttp "github.com/siderolabs/image-factory/internal/frontend/http"
+	"github.com/siderolabs/image-factory/pkg/client"
+)
+
+func TestBuildErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	frontend := frontendhttp.NewTestFrontend(zaptest.NewLogger(t), frontendhttp.Options{
+		ExternalURL: &url.URL{Scheme: "https", Host: "factory.example.com", Path: "/"},
+	})
+
+	buildFailed := func(context.Context, http.ResponseWriter, *http.Request, httprouter.Params) error {
+		return fmt.Errorf("failed to build: %w", &asset.BuildError{
+			Err:         errors.New("imager failed"),
+			ProfileHash: "abcd",
+			LogExcerpt:  "fetching inputs\nimager failed",
+		})
+	}
+
+	internalError := func(context.Context, http.ResponseWriter, *http.Request, httprouter.Params) error {
+		return errors.New("secret details")
+	}
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		frontend.WrapText(buildFailed)(w, httptest.NewRequest(http.MethodGet, "/image/x/v1.10.2/metal-amd64.iso", nil), nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "error building asset abcd: imager failed")
+		assert.Contains(t, w.Body.String(), "full log at https://factory.example.com/builds/abcd/log")
+		assert.Contains(t, w.Body.String(), "fetching inputs\nimager failed")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		frontend.WrapJSON(buildFailed)(w, httptest.NewRequest(http.MethodGet, "/api/v1/images/x/v1.10.2/metal-amd64.iso", nil), nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		var apiErr client.APIError
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		assert.Equal(t, client.ErrorCodeBuildFailed, apiErr.Code)
+		assert.Contains(t, apiErr.Message, "full log at https://factory.example.com/api/v1/builds/abcd/log")
+		assert.Contains(t, apiErr.Message, "fetching inputs\nimager failed")
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		frontend.WrapText(internalError)(w, httptest.NewRequest(http.MethodGet, "/image/x/v1.10.2/metal-amd64.iso", nil), nil)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), "secret details")
+	})
+}
@@ -5,82 +5,243 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"mime"
 	"net/http"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/julienschmidt/httprouter"
+	imagerprofile "github.com/siderolabs/talos/pkg/imager/profile"
+	"go.uber.org/zap"
 
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/image/signer"
 	"github.com/siderolabs/image-factory/internal/profile"
 )
 
-// handleImage handles downloading of boot assets.
+// Extensions of the sidecar files served next to every asset.
+const (
+	sidecarSHA256    = ".sha256"
+	sidecarSHA512    = ".sha512"
+	sidecarSignature = ".sig"
+	sidecarBundle    = ".bundle"
+	sidecarSBOM      = ".sbom.json"
+)
+
+var sidecarExtensions = []string{sidecarSHA256, sidecarSHA512, sidecarSignature, sidecarBundle, sidecarSBOM}
+
+// handleImage handles downloading of boot assets and their sidecar files (checksums, signatures and SBOMs).
 func (f *Frontend) handleImage(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
-	schematicID := p.ByName("schematic")
+	path := p.ByName("path")
 
-	schematic, err := f.schematicFactory.Get(ctx, schematicID)
-	if err != nil {
-		return err
-	}
+	var sidecarExt string
 
-	versionTag := p.ByName("version")
-	if !strings.HasPrefix(versionTag, "v") {
-		versionTag = "v" + versionTag
+	for _, ext := range sidecarExtensions {
+		if trimmed, ok := strings.CutSuffix(path, ext); ok {
+			path, sidecarExt = trimmed, ext
+
+			break
+		}
 	}
 
-	version, err := semver.Parse(versionTag[1:])
+	prof, source, version, err := f.imageProfile(ctx, r, p.ByName("schematic"), p.ByName("version"), path)
 	if err != nil {
-		return fmt.Errorf("error parsing version: %w", err)
+		return err
 	}
 
-	path := p.ByName("path")
-
-	prof, err := profile.ParseFromPath(path, version.String())
+	profileHash, err := profile.Hash(prof)
 	if err != nil {
-		return fmt.Errorf("error parsing profile from path: %w", err)
+		return err
 	}
 
-	prof, err = profile.EnhanceFromSchematic(ctx, prof, schematic, f.artifactsManager, f.secureBootService, versionTag)
-	if err != nil {
-		return fmt.Errorf("error enhancing profile from schematic: %w", err)
+	// the asset is fully defined by the profile, so the profile hash is a strong validator
+	etag := `"` + profileHash + sidecarExt + `"`
+	etagHeader := etag
+
+	if sidecarExt == sidecarSignature || sidecarExt == sidecarBundle || sidecarExt == sidecarSBOM {
+		// signatures and SBOM timestamps are not deterministic, but they are equivalent
+		etagHeader = "W/" + etag
 	}
 
-	if err = prof.Validate(); err != nil {
-		return fmt.Errorf("error validating profile: %w", err)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		// skip building (or fetching from the cache) the asset if the client has it already
+		w.Header().Set("ETag", etagHeader)
+		w.WriteHeader(http.StatusNotModified)
+
+		return nil
 	}
 
-	asset, err := f.assetBuilder.Build(ctx, prof, version.String())
+	bootAsset, err := f.assetBuilder.Build(ctx, prof, version.String(), source)
 	if err != nil {
 		return err
 	}
 
-	w.Header().Set("Content-Length", strconv.FormatInt(asset.Size(), 10))
+	w.Header().Set("ETag", etagHeader)
+
+	if sidecarExt == sidecarSBOM {
+		return f.serveSBOM(ctx, w, r, bootAsset, p.ByName("schematic"), "v"+version.String(), path)
+	}
+
+	if sidecarExt != "" {
+		return f.serveSidecar(ctx, w, r, bootAsset, path, sidecarExt)
+	}
 
 	if ext := filepath.Ext(path); ext != "" {
 		w.Header().Set("Content-Type", mime.TypeByExtension(ext))
 	}
 
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, path))
-	w.WriteHeader(http.StatusOK)
 
-	if r.Method == http.MethodHead {
-		return nil
+	if f.options.RedirectCachedAssets && r.Method == http.MethodGet {
+		if f.redirectCachedAsset(ctx, w, bootAsset) {
+			return nil
+		}
+	}
+
+	content := asset.NewReadSeeker(ctx, bootAsset)
+	defer content.Close() //nolint:errcheck
+
+	// ServeContent handles Range/If-Range requests and HEAD
+	http.ServeContent(w, r, path, time.Time{}, content)
+
+	return content.Err()
+}
+
+// redirectCachedAsset redirects the download of the cached asset to the cache storage, like handleBlob does for the installer layers.
+//
+// The assets which were just built (or served from the local disk cache) are not redirected, HEAD requests are served directly
+// (pre-signed URLs are only valid for GET requests).
+func (f *Frontend) redirectCachedAsset(ctx context.Context, w http.ResponseWriter, bootAsset asset.BootAsset) bool {
+	redirectable, ok := bootAsset.(asset.RedirectableAsset)
+	if !ok {
+		return false
+	}
+
+	location, err := redirectable.RedirectURL(ctx, w.Header().Get("Content-Type"), w.Header().Get("Content-Disposition"))
+	if err != nil {
+		// serve the asset directly
+		f.logger.Warn("failed to get cached asset redirect URL", zap.Error(err))
+
+		return false
 	}
 
-	reader, err := asset.Reader()
+	if location == "" {
+		return false
+	}
+
+	f.logger.Debug("redirecting cached asset", zap.String("location", location))
+
+	w.Header().Add("Location", location)
+	w.WriteHeader(http.StatusTemporaryRedirect)
+
+	return true
+}
+
+// serveSidecar serves the sidecar file of the asset.
+//
+// Checksums are in the format of `sha256sum`/`sha512sum`, the signature is in the format of `cosign sign-blob`
+// (base64-encoded), and the bundle is a Sigstore bundle.
+func (f *Frontend) serveSidecar(ctx context.Context, w http.ResponseWriter, r *http.Request, bootAsset asset.BootAsset, path, sidecarExt string) error {
+	checksums, err := bootAsset.Checksums(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer reader.Close() //nolint:errcheck
+	var (
+		contents    []byte
+		contentType = "text/plain; charset=utf-8"
+	)
+
+	switch sidecarExt {
+	case sidecarSHA256:
+		contents = fmt.Appendf(nil, "%s  %s\n", checksums.SHA256, path)
+	case sidecarSHA512:
+		contents = fmt.Appendf(nil, "%s  %s\n", checksums.SHA512, path)
+	case sidecarSignature, sidecarBundle:
+		var digest, signature []byte
+
+		digest, err = hex.DecodeString(checksums.SHA256)
+		if err != nil {
+			return fmt.Errorf("error decoding asset digest: %w", err)
+		}
+
+		signature, err = f.imageSigner.SignBlobDigest(digest)
+		if err != nil {
+			return err
+		}
+
+		if sidecarExt == sidecarSignature {
+			contents = []byte(base64.StdEncoding.EncodeToString(signature))
+		} else {
+			contents, err = f.imageSigner.BlobBundle(digest, signature)
+			if err != nil {
+				return err
+			}
+
+			contentType = signer.BundleMediaType
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	http.ServeContent(w, r, path+sidecarExt, time.Time{}, bytes.NewReader(contents))
+
+	return nil
+}
+
+// etagMatches checks whether the If-None-Match header matches the ETag (using weak comparison, as per RFC 9110).
+//
+// The wildcard (`*`) is not matched: the ETag is known before the asset is built, so the asset might not exist (e.g. if the build fails).
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
 
-	_, err = io.Copy(w, reader)
+// imageProfile builds the validated image profile for the schematic, Talos version and the asset path.
+//
+// The caller should be authorized to access the schematic.
+func (f *Frontend) imageProfile(ctx context.Context, r *http.Request, schematicID, versionTag, path string) (imagerprofile.Profile, asset.Source, semver.Version, error) {
+	schematic, err := f.getSchematic(ctx, r, schematicID)
+	if err != nil {
+		return imagerprofile.Profile{}, asset.Source{}, semver.Version{}, err
+	}
+
+	if !strings.HasPrefix(versionTag, "v") {
+		versionTag = "v" + versionTag
+	}
+
+	version, err := semver.Parse(versionTag[1:])
+	if err != nil {
+		return imagerprofile.Profile{}, asset.Source{}, semver.Version{}, fmt.Errorf("error parsing version: %w", err)
+	}
+
+	baseProf, err := profile.ParseFromPath(path, version.String())
+	if err != nil {
+		return imagerprofile.Profile{}, asset.Source{}, semver.Version{}, fmt.Errorf("error parsing profile from path: %w", err)
+	}
+
+	prof, inputs, err := profile.EnhanceFromSchematicWithInputs(ctx, baseProf.DeepCopy(), schematicID, schematic, f.artifactsManager, f.secureBootService, versionTag)
+	if err != nil {
+		return imagerprofile.Profile{}, asset.Source{}, semver.Version{}, fmt.Errorf("error enhancing profile from schematic: %w", err)
+	}
+
+	if err = prof.Validate(); err != nil {
+		return imagerprofile.Profile{}, asset.Source{}, semver.Version{}, fmt.Errorf("error validating profile: %w", err)
+	}
 
-	return err
+	return prof, asset.Source{Profile: baseProf, SchematicID: schematicID, Inputs: &inputs}, version, nil
 }
@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/siderolabs/image-factory/internal/provenance"
+)
+
+// attestInstallerImage generates the SLSA provenance for the installer image and attaches the signed attestation
+// to the image index and to each platform image.
+func (f *Frontend) attestInstallerImage(ctx context.Context, repo name.Repository, imageName string, indexDigest v1.Hash, archImages []v1.Image, build provenance.Build) error {
+	digests := []v1.Hash{indexDigest}
+
+	for _, archImage := range archImages {
+		digest, err := archImage.Digest()
+		if err != nil {
+			return fmt.Errorf("error getting installer image digest: %w", err)
+		}
+
+		digests = append(digests, digest)
+	}
+
+	subjects := make([]provenance.Subject, 0, len(digests))
+
+	for _, digest := range digests {
+		subjects = append(subjects, provenance.Subject{
+			Name:   imageName,
+			Digest: digest,
+		})
+	}
+
+	build.BuilderID = f.options.ExternalURL.String()
+
+	statement, err := provenance.Generate(subjects, build)
+	if err != nil {
+		return fmt.Errorf("error generating provenance: %w", err)
+	}
+
+	for _, digest := range digests {
+		if err = f.imageSigner.AttestImage(ctx, repo.Digest(digest.String()), provenance.PredicateType, statement, f.pusher); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
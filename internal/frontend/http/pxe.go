@@ -17,6 +17,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/siderolabs/gen/ensure"
 
+	"github.com/siderolabs/image-factory/internal/asset"
 	"github.com/siderolabs/image-factory/internal/profile"
 )
 
@@ -27,10 +28,10 @@ var standardIPXE string
 var securebootIPXE string
 
 // handlePXE delivers a PXE script to boot Talos.
-func (f *Frontend) handlePXE(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
+func (f *Frontend) handlePXE(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	schematicID := p.ByName("schematic")
 
-	schematic, err := f.schematicFactory.Get(ctx, schematicID)
+	schematic, err := f.getSchematic(ctx, r, schematicID)
 	if err != nil {
 		return err
 	}
@@ -48,12 +49,12 @@ func (f *Frontend) handlePXE(ctx context.Context, w http.ResponseWriter, _ *http
 	// the PXE format is just platform+arch, so if we append cmdline, it should parse
 	path := "cmdline-" + p.ByName("path")
 
-	prof, err := profile.ParseFromPath(path, version.String())
+	baseProf, err := profile.ParseFromPath(path, version.String())
 	if err != nil {
 		return fmt.Errorf("error parsing profile from path: %w", err)
 	}
 
-	prof, err = profile.EnhanceFromSchematic(ctx, prof, schematic, f.artifactsManager, f.secureBootService, versionTag)
+	prof, inputs, err := profile.EnhanceFromSchematicWithInputs(ctx, baseProf.DeepCopy(), schematicID, schematic, f.artifactsManager, f.secureBootService, versionTag)
 	if err != nil {
 		return fmt.Errorf("error enhancing profile from schematic: %w", err)
 	}
@@ -63,12 +64,12 @@ func (f *Frontend) handlePXE(ctx context.Context, w http.ResponseWriter, _ *http
 	}
 
 	// build the cmdline
-	asset, err := f.assetBuilder.Build(ctx, prof, version.String())
+	cmdlineAsset, err := f.assetBuilder.Build(ctx, prof, version.String(), asset.Source{Profile: baseProf, SchematicID: schematicID, Inputs: &inputs})
 	if err != nil {
 		return err
 	}
 
-	reader, err := asset.Reader()
+	reader, err := cmdlineAsset.Reader()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,94 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/auth"
+	"github.com/siderolabs/image-factory/internal/ratelimit"
+)
+
+// rateLimit attaches the client to the context (for the build budget and the fair build scheduling),
+// and charges the request budget of the client.
+func (f *Frontend) rateLimit(ctx context.Context, r *http.Request) (context.Context, error) {
+	if r.URL.Path == "/healthz" {
+		return ctx, nil
+	}
+
+	key := f.clientKey(ctx, r)
+	ctx = asset.WithClient(ctx, key)
+
+	if f.options.RateLimiter == nil {
+		return ctx, nil
+	}
+
+	ctx = ratelimit.WithClient(ctx, key)
+
+	return ctx, f.options.RateLimiter.Allow(key, ratelimit.BudgetRequests)
+}
+
+// admitBuild charges the build budget of the client for the fresh build.
+func (f *Frontend) admitBuild(ctx context.Context) error {
+	if f.options.RateLimiter == nil {
+		return nil
+	}
+
+	return f.options.RateLimiter.AdmitBuild(ctx)
+}
+
+// clientKey identifies the client: authenticated callers by their identity, anonymous callers by the IP address.
+//
+// Invalid tokens are ignored, so that random tokens can't be used to bypass the limits.
+func (f *Frontend) clientKey(ctx context.Context, r *http.Request) string {
+	if f.options.Authenticator != nil {
+		if token, ok := auth.TokenFromRequest(r); ok {
+			if identity, err := f.options.Authenticator.Authenticate(ctx, token); err == nil {
+				return "identity:" + identity.String()
+			}
+		}
+	}
+
+	return "ip:" + f.clientIP(r)
+}
+
+// clientIP returns the IP address of the client.
+//
+// If the client IP header is configured (the factory is behind a reverse proxy), the last address in the header is used,
+// as it is the one set by the proxy.
+func (f *Frontend) clientIP(r *http.Request) string {
+	if f.options.ClientIPHeader != "" {
+		if values := r.Header.Values(f.options.ClientIPHeader); len(values) > 0 {
+			addresses := strings.Split(values[len(values)-1], ",")
+
+			if address := strings.TrimSpace(addresses[len(addresses)-1]); address != "" {
+				return address
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// setRetryAfter sets the Retry-After header if the error is the rate limit error.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var exceeded *ratelimit.ExceededError
+
+	if errors.As(err, &exceeded) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(exceeded.RetryAfter.Seconds()))))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -24,16 +25,24 @@ import (
 	"github.com/siderolabs/image-factory/internal/artifacts"
 	"github.com/siderolabs/image-factory/internal/asset"
 	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/provenance"
 	"github.com/siderolabs/image-factory/internal/regtransport"
 	"github.com/siderolabs/image-factory/pkg/schematic"
 )
 
-// handleHealth handles registry health and auth.
+// handleHealth handles health checks.
 func (f *Frontend) handleHealth(_ context.Context, _ http.ResponseWriter, _ *http.Request, _ httprouter.Params) error {
 	// always healthy, yay!
 	return nil
 }
 
+// handleRegistryBase handles registry API version check and auth.
+//
+// If authentication is required, the registry clients get the challenge here.
+func (f *Frontend) handleRegistryBase(ctx context.Context, _ http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	return f.authorize(ctx, r, false)
+}
+
 type requestedImage struct {
 	imageName  string
 	platform   string
@@ -79,11 +88,11 @@ func (img requestedImage) Name() string {
 // handleBlob handles image blob download.
 //
 // We always redirect to the external registry, as we assume the image has already been pushed.
-func (f *Frontend) handleBlob(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
+func (f *Frontend) handleBlob(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	// verify that schematic exists
 	schematicID := p.ByName("schematic")
 
-	_, err := f.schematicFactory.Get(ctx, schematicID)
+	_, err := f.getSchematic(ctx, r, schematicID)
 	if err != nil {
 		return err
 	}
@@ -126,13 +135,44 @@ func (f *Frontend) redirectToExternalRegistry(w http.ResponseWriter, imageName,
 	return nil
 }
 
+// handleReferrers handles listing of the referrers (signatures, SBOMs) of the image manifest.
+func (f *Frontend) handleReferrers(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	// verify that schematic exists
+	schematicID := p.ByName("schematic")
+
+	_, err := f.getSchematic(ctx, r, schematicID)
+	if err != nil {
+		return err
+	}
+
+	img, err := getRequestedImage(p)
+	if err != nil {
+		return err
+	}
+
+	var redirectURL url.URL
+
+	redirectURL.Scheme = f.options.InstallerExternalRepository.Scheme()
+	redirectURL.Host = f.options.InstallerExternalRepository.Registry.Name()
+
+	location := redirectURL.JoinPath("v2", f.options.InstallerExternalRepository.RepositoryStr(), img.Name(), schematicID, "referrers", p.ByName("digest"))
+	location.RawQuery = r.URL.RawQuery // keep the artifactType filter
+
+	f.logger.Info("redirecting referrers", zap.Stringer("location", location))
+
+	w.Header().Add("Location", location.String())
+	w.WriteHeader(http.StatusTemporaryRedirect)
+
+	return nil
+}
+
 // handleManifest handles image manifest download.
 //
 // If the manifest is for the tag, we check if the image already exists, and either redirect, or build, push and redirect.
-func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _ *http.Request, p httprouter.Params) error {
+func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	schematicID := p.ByName("schematic")
 
-	schematic, err := f.schematicFactory.Get(ctx, schematicID)
+	schematic, err := f.getSchematic(ctx, r, schematicID)
 	if err != nil {
 		return err
 	}
@@ -206,6 +246,10 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 		return fmt.Errorf("error parsing version: %w", err)
 	}
 
+	if err = f.admitBuild(ctx); err != nil {
+		return err
+	}
+
 	// build installer images for each architecture, combine them into a single index and push it
 	key := fmt.Sprintf("%s-%s-%s", img.Name(), schematicID, versionTag)
 
@@ -238,12 +282,19 @@ func (f *Frontend) handleManifest(ctx context.Context, w http.ResponseWriter, _
 func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, schematic *schematic.Schematic, version semver.Version, schematicID, versionTag string) (v1.Hash, error) {
 	f.logger.Info("building installer image", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag))
 
-	var imageIndex v1.ImageIndex = empty.Index
+	startedOn := time.Now()
+	imageName := img.Name() + "/" + schematicID + ":" + versionTag
+
+	var (
+		imageIndex v1.ImageIndex = empty.Index
+		archImages []v1.Image
+		archInputs []profile.BuildInputs
+	)
 
 	for _, arch := range []artifacts.Arch{artifacts.ArchAmd64, artifacts.ArchArm64} {
-		prof := profile.InstallerProfile(img.secureboot, arch, img.platform)
+		baseProf := profile.InstallerProfile(img.secureboot, arch, img.platform)
 
-		prof, err := profile.EnhanceFromSchematic(ctx, prof, schematic, f.artifactsManager, f.secureBootService, versionTag)
+		prof, enhancedInputs, err := profile.EnhanceFromSchematicWithInputs(ctx, baseProf.DeepCopy(), schematicID, schematic, f.artifactsManager, f.secureBootService, versionTag)
 		if err != nil {
 			return v1.Hash{}, fmt.Errorf("error enhancing profile from schematic: %w", err)
 		}
@@ -252,16 +303,22 @@ func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, sc
 			return v1.Hash{}, fmt.Errorf("error validating profile: %w", err)
 		}
 
-		var asset asset.BootAsset
+		var bootAsset asset.BootAsset
 
-		asset, err = f.assetBuilder.Build(ctx, prof, version.String())
+		bootAsset, err = f.assetBuilder.Build(ctx, prof, version.String(), asset.Source{Profile: baseProf, SchematicID: schematicID, Inputs: &enhancedInputs})
 		if err != nil {
 			return v1.Hash{}, err
 		}
 
+		inputs, ok := bootAsset.BuildInputs()
+		if !ok {
+			// the asset was cached before the build inputs were recorded, the imager reference is unknown
+			inputs = enhancedInputs
+		}
+
 		var archImage v1.Image
 
-		archImage, err = tarball.Image(asset.Reader, nil)
+		archImage, err = tarball.Image(bootAsset.Reader, nil)
 		if err != nil {
 			return v1.Hash{}, fmt.Errorf("error creating image from asset: %w", err)
 		}
@@ -276,6 +333,9 @@ func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, sc
 					},
 				},
 			})
+
+		archImages = append(archImages, archImage)
+		archInputs = append(archInputs, inputs)
 	}
 
 	f.logger.Info("pushing installer image", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag))
@@ -294,11 +354,36 @@ func (f *Frontend) buildInstallImage(ctx context.Context, img requestedImage, sc
 		return v1.Hash{}, fmt.Errorf("error pushing index: %w", err)
 	}
 
+	finishedOn := time.Now()
+
 	digest, err := imageIndex.Digest()
 	if err != nil {
 		return v1.Hash{}, fmt.Errorf("error getting index digest: %w", err)
 	}
 
+	// the signature marks the installer image as complete (see handleManifest), so the SBOMs and the provenance are attached first
+	f.logger.Info("attaching installer image SBOMs", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag))
+
+	for i, archImage := range archImages {
+		if err := f.pushInstallerSBOM(ctx, installerRepo, imageName, archImage, archInputs[i]); err != nil {
+			return v1.Hash{}, fmt.Errorf("error attaching SBOM: %w", err)
+		}
+	}
+
+	f.logger.Info("attaching installer image provenance", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag))
+
+	if err := f.attestInstallerImage(ctx, installerRepo, imageName, digest, archImages, provenance.Build{
+		StartedOn:    startedOn,
+		FinishedOn:   finishedOn,
+		Schematic:    schematic,
+		SchematicID:  schematicID,
+		TalosVersion: versionTag,
+		Image:        img.Name(),
+		Inputs:       archInputs,
+	}); err != nil {
+		return v1.Hash{}, fmt.Errorf("error attaching provenance: %w", err)
+	}
+
 	f.logger.Info("signing installer image", zap.String("image", img.Name()), zap.String("schematic", schematicID), zap.String("version", versionTag), zap.Stringer("digest", digest))
 
 	if err := f.imageSigner.SignImage(
@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/siderolabs/gen/xerrors"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/sbom"
+)
+
+// serveSBOM serves the SBOM of the boot asset.
+//
+// The SBOM describes the build inputs recorded when the asset was built, so the assets cached before the inputs were recorded have no SBOM.
+func (f *Frontend) serveSBOM(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	bootAsset asset.BootAsset,
+	schematicID, versionTag, path string,
+) error {
+	inputs, ok := bootAsset.BuildInputs()
+	if !ok {
+		return xerrors.NewTaggedf[asset.ErrNotFoundTag]("SBOM of %q is not available: the build inputs were not recorded with the cached asset", path)
+	}
+
+	checksums, err := bootAsset.Checksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	contents, err := sbom.Generate(
+		sbom.Subject{
+			Name:    path,
+			Purpose: sbom.PurposeFile,
+			SHA256:  checksums.SHA256,
+			SHA512:  checksums.SHA512,
+		},
+		inputs,
+		sbom.Options{
+			Created:   time.Now(),
+			Namespace: f.options.ExternalURL.JoinPath("image", schematicID, versionTag, path+sidecarSBOM).String(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error generating SBOM: %w", err)
+	}
+
+	w.Header().Set("Content-Type", sbom.MediaType)
+
+	http.ServeContent(w, r, path+sidecarSBOM, time.Time{}, bytes.NewReader(contents))
+
+	return nil
+}
+
+// pushInstallerSBOM generates the SBOM for the installer image and attaches it to the image as an OCI referrer.
+func (f *Frontend) pushInstallerSBOM(ctx context.Context, repo name.Repository, imageName string, archImage v1.Image, inputs profile.BuildInputs) error {
+	subject, err := partial.Descriptor(archImage)
+	if err != nil {
+		return fmt.Errorf("error getting installer image descriptor: %w", err)
+	}
+
+	contents, err := sbom.Generate(
+		sbom.Subject{
+			Name:    imageName,
+			Purpose: sbom.PurposeContainer,
+			SHA256:  subject.Digest.Hex,
+		},
+		inputs,
+		sbom.Options{
+			Created:   time.Now(),
+			Namespace: f.options.ExternalURL.JoinPath("spdx", repo.RepositoryStr(), inputs.TalosVersion, inputs.Arch).String(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error generating SBOM: %w", err)
+	}
+
+	return f.pushReferrer(ctx, repo, *subject, sbom.MediaType, contents)
+}
+
+// pushReferrer pushes the artifact with the contents as a single layer referring to the subject.
+//
+// The artifact type is stored as the config media type, registries without the referrers API
+// get the fallback referrers tag.
+func (f *Frontend) pushReferrer(ctx context.Context, repo name.Repository, subject v1.Descriptor, artifactType types.MediaType, contents []byte) error {
+	artifact, err := mutate.Append(
+		mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), artifactType),
+		mutate.Addendum{
+			Layer: static.NewLayer(contents, artifactType),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error building %s artifact: %w", artifactType, err)
+	}
+
+	referrer, ok := mutate.Subject(artifact, subject).(v1.Image)
+	if !ok {
+		return fmt.Errorf("unexpected %s artifact type", artifactType)
+	}
+
+	digest, err := referrer.Digest()
+	if err != nil {
+		return fmt.Errorf("error getting %s artifact digest: %w", artifactType, err)
+	}
+
+	if err = f.pusher.Push(ctx, repo.Digest(digest.String()), referrer); err != nil {
+		return fmt.Errorf("error pushing %s artifact: %w", artifactType, err)
+	}
+
+	return nil
+}
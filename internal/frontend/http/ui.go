@@ -596,6 +596,11 @@ func (f *Frontend) wizard(ctx context.Context, r *http.Request, localizer *i18n.
 
 // handleUIWizard handles '/ui/wizard'.
 func (f *Frontend) handleUIWizard(ctx context.Context, w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	// the wizard creates schematics
+	if err := f.authorize(ctx, r, false); err != nil {
+		return err
+	}
+
 	templateName, data, query, err := f.wizard(ctx, r, f.getLocalizer(r))
 	if err != nil {
 		return err
@@ -0,0 +1,57 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package signer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/oci/empty"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+
+	"github.com/siderolabs/image-factory/internal/remotewrap"
+)
+
+// AttestImage signs the in-toto statement and attaches it to the image in the OCI repository.
+//
+// The attestation is stored the same way as `cosign attest` does, so it can be verified with `cosign verify-attestation`.
+func (s *Signer) AttestImage(ctx context.Context, imageRef name.Digest, predicateType string, statement []byte, pusher remotewrap.Pusher) error {
+	envelope, err := dsse.WrapSigner(s.sv, types.IntotoPayloadType).SignMessage(bytes.NewReader(statement))
+	if err != nil {
+		return fmt.Errorf("error signing attestation: %w", err)
+	}
+
+	attestationTag, err := cosignremote.AttestationTag(imageRef)
+	if err != nil {
+		return fmt.Errorf("error generating attestation tag: %w", err)
+	}
+
+	attestationLayer, err := static.NewAttestation(envelope,
+		static.WithLayerMediaType(types.DssePayloadType),
+		static.WithAnnotations(map[string]string{
+			"predicateType": predicateType,
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("error generating attestation layer: %w", err)
+	}
+
+	attestations, err := mutate.AppendSignatures(empty.Signatures(), true, attestationLayer)
+	if err != nil {
+		return fmt.Errorf("error appending attestations: %w", err)
+	}
+
+	if err = pusher.Push(ctx, attestationTag, attestations); err != nil {
+		return fmt.Errorf("error pushing attestation: %w", err)
+	}
+
+	return nil
+}
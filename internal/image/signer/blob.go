@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package signer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// BundleMediaType is the media type of the Sigstore bundle produced by BlobBundle.
+const BundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+// SignBlobDigest signs the blob by its SHA-256 digest.
+//
+// The signature is compatible with `cosign sign-blob` (and `cosign verify-blob --key`).
+func (s *Signer) SignBlobDigest(digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("unexpected digest size %d", len(digest))
+	}
+
+	signature, err := s.sv.SignMessage(nil, options.WithDigest(digest))
+	if err != nil {
+		return nil, fmt.Errorf("error signing blob: %w", err)
+	}
+
+	return signature, nil
+}
+
+// VerifyBlobDigest verifies the signature produced by SignBlobDigest.
+func (s *Signer) VerifyBlobDigest(digest, signature []byte) error {
+	if len(digest) != sha256.Size {
+		return fmt.Errorf("unexpected digest size %d", len(digest))
+	}
+
+	if err := s.sv.VerifySignature(bytes.NewReader(signature), nil, options.WithDigest(digest)); err != nil {
+		return fmt.Errorf("error verifying blob signature: %w", err)
+	}
+
+	return nil
+}
+
+// sigstoreBundle is the JSON representation of the Sigstore bundle with a message signature verified by a public key.
+type sigstoreBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		PublicKey struct {
+			Hint string `json:"hint"`
+		} `json:"publicKey"`
+	} `json:"verificationMaterial"`
+	MessageSignature struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// BlobBundle returns the Sigstore bundle (JSON) for the blob signature produced by SignBlobDigest.
+//
+// The bundle doesn't contain transparency log entries, as the signing key is not a Sigstore identity.
+func (s *Signer) BlobBundle(digest, signature []byte) ([]byte, error) {
+	pubKey, err := s.sv.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve public key: %w", err)
+	}
+
+	pubKeyDER, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	hint := sha256.Sum256(pubKeyDER)
+
+	var bundle sigstoreBundle
+
+	bundle.MediaType = BundleMediaType
+	bundle.VerificationMaterial.PublicKey.Hint = base64.StdEncoding.EncodeToString(hint[:])
+	bundle.MessageSignature.MessageDigest.Algorithm = "SHA2_256"
+	bundle.MessageSignature.MessageDigest.Digest = digest
+	bundle.MessageSignature.Signature = signature
+
+	return json.Marshal(bundle)
+}
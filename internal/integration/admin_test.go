@@ -0,0 +1,107 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/pkg/client"
+	"github.com/siderolabs/image-factory/pkg/schematic"
+)
+
+func testCacheAdmin(ctx context.Context, t *testing.T, baseURL string) {
+	anonymous, err := client.New(baseURL)
+	require.NoError(t, err)
+
+	viewer, err := client.New(baseURL, client.WithAuthToken(viewerToken))
+	require.NoError(t, err)
+
+	admin, err := client.New(baseURL, client.WithAuthToken(authToken))
+	require.NoError(t, err)
+
+	t.Run("access", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := anonymous.CacheList(ctx, client.CacheFilter{})
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+		_, err = viewer.CacheList(ctx, client.CacheFilter{})
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusForbidden), err)
+
+		assertAPIError(t, authGet(ctx, t, baseURL+"/api/v1/admin/cache", viewerToken), http.StatusForbidden, client.ErrorCodeForbidden)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		t.Parallel()
+
+		// use a dedicated schematic, so that purging doesn't affect other tests
+		schematicID, err := admin.SchematicCreate(ctx, schematic.Schematic{
+			Customization: schematic.Customization{
+				ExtraKernelArgs: []string{"cache-admin-test"},
+			},
+		})
+		require.NoError(t, err)
+
+		resp := downloadAsset(ctx, t, baseURL, schematicID, "v1.10.2", "cmdline-metal-amd64")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+
+		entries, err := admin.CacheList(ctx, client.CacheFilter{SchematicID: schematicID, Version: "v1.10.2"})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		entry := entries[0]
+
+		assert.Equal(t, schematicID, entry.SchematicID)
+		assert.Equal(t, "1.10.2", entry.Version)
+		assert.Equal(t, "cmdline", entry.Kind)
+		assert.Equal(t, "amd64", entry.Arch)
+		assert.Contains(t, entry.Profile, "platform: metal")
+		assert.NotEmpty(t, entry.Digest)
+		assert.NotZero(t, entry.Size)
+		assert.False(t, entry.Created.IsZero())
+
+		inspected, err := admin.CacheGet(ctx, entry.ProfileHash)
+		require.NoError(t, err)
+		assert.Equal(t, entry, *inspected)
+
+		purged, err := admin.CachePurge(ctx, entry.ProfileHash)
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, entry.ProfileHash, purged[0].ProfileHash)
+
+		_, err = admin.CacheGet(ctx, entry.ProfileHash)
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusNotFound), err)
+
+		_, err = admin.CachePurge(ctx, entry.ProfileHash)
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusNotFound), err)
+
+		// the asset is built again
+		resp = downloadAsset(ctx, t, baseURL, schematicID, "v1.10.2", "cmdline-metal-amd64")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("empty filter", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := admin.CachePurgeFiltered(ctx, client.CacheFilter{})
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusBadRequest), err)
+	})
+}
@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/pkg/client"
+)
+
+func apiGet(ctx context.Context, t *testing.T, url string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		resp.Body.Close()
+	})
+
+	return resp
+}
+
+func assertAPIError(t *testing.T, resp *http.Response, expectedStatus int, expectedCode client.ErrorCode) {
+	t.Helper()
+
+	require.Equal(t, expectedStatus, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var apiErr client.APIError
+
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+
+	assert.Equal(t, expectedCode, apiErr.Code)
+	assert.NotEmpty(t, apiErr.Message)
+}
+
+func testAPIFrontend(ctx context.Context, t *testing.T, baseURL string) {
+	apiURL := baseURL + "/api/v1"
+
+	t.Run("openapi", func(t *testing.T) {
+		t.Parallel()
+
+		resp := apiGet(ctx, t, apiURL+"/openapi.json")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var doc struct {
+			OpenAPI string         `json:"openapi"`
+			Paths   map[string]any `json:"paths"`
+		}
+
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+		assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), doc.OpenAPI)
+		assert.Contains(t, doc.Paths, "/schematics/{schematic}")
+		assert.Contains(t, doc.Paths, "/builds/{id}")
+		assert.Contains(t, doc.Paths, "/builds/{id}/log")
+		assert.Contains(t, doc.Paths, "/admin/cache/{hash}")
+
+		resp = apiGet(ctx, t, apiURL+"/openapi.yaml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("schematic", func(t *testing.T) {
+		t.Parallel()
+
+		resp := apiGet(ctx, t, apiURL+"/schematics/"+emptySchematicID)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		t.Parallel()
+
+		resp := apiGet(ctx, t, apiURL+"/versions")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var versions []string
+
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&versions))
+		assert.Contains(t, versions, "v1.10.2")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assertAPIError(t, apiGet(ctx, t, apiURL+"/schematics/aaaa"), http.StatusNotFound, client.ErrorCodeNotFound)
+		assertAPIError(t, apiGet(ctx, t, apiURL+"/builds/"+strings.Repeat("0", 64)+"/log"), http.StatusNotFound, client.ErrorCodeNotFound)
+	})
+
+	t.Run("invalid profile", func(t *testing.T) {
+		t.Parallel()
+
+		assertAPIError(t, apiGet(ctx, t, apiURL+"/images/"+emptySchematicID+"/v1.10.2/metal-amd64.foo"), http.StatusBadRequest, client.ErrorCodeInvalidProfile)
+	})
+
+	t.Run("legacy errors", func(t *testing.T) {
+		t.Parallel()
+
+		resp := apiGet(ctx, t, baseURL+"/schematics/aaaa")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	})
+}
@@ -0,0 +1,184 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/pkg/client"
+	"github.com/siderolabs/image-factory/pkg/schematic"
+)
+
+const privateSchematicID = "14ba141e799b350b220060b6437e5c18f41078c3b7613848fb71f7ecbd73e725"
+
+func authGet(ctx context.Context, t *testing.T, url, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		resp.Body.Close()
+	})
+
+	return resp
+}
+
+func testAuth(ctx context.Context, t *testing.T, baseURL string) {
+	anonymous, err := client.New(baseURL)
+	require.NoError(t, err)
+
+	authenticated, err := client.New(baseURL, client.WithAuthToken(authToken))
+	require.NoError(t, err)
+
+	invalid, err := client.New(baseURL, client.WithAuthToken("invalid"))
+	require.NoError(t, err)
+
+	privateSchematic := schematic.Schematic{Private: true}
+
+	// create the private schematic first
+	_, err = anonymous.SchematicCreate(ctx, privateSchematic)
+	require.Error(t, err)
+	assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+	_, err = invalid.SchematicCreate(ctx, privateSchematic)
+	require.Error(t, err)
+	assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+	id, err := authenticated.SchematicCreate(ctx, privateSchematic)
+	require.NoError(t, err)
+	require.Equal(t, privateSchematicID, id)
+
+	t.Run("schematic", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := anonymous.SchematicGet(ctx, privateSchematicID)
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+		cfg, err := authenticated.SchematicGet(ctx, privateSchematicID)
+		require.NoError(t, err)
+		assert.True(t, cfg.Private)
+
+		// public schematics are still available to anyone
+		_, err = anonymous.SchematicGet(ctx, emptySchematicID)
+		require.NoError(t, err)
+	})
+
+	t.Run("api", func(t *testing.T) {
+		t.Parallel()
+
+		resp := authGet(ctx, t, baseURL+"/api/v1/schematics/"+privateSchematicID, "")
+		assert.Equal(t, `Bearer realm="image-factory"`, resp.Header.Get("WWW-Authenticate"))
+		assertAPIError(t, resp, http.StatusUnauthorized, client.ErrorCodeUnauthorized)
+	})
+
+	t.Run("pxe", func(t *testing.T) {
+		t.Parallel()
+
+		resp := authGet(ctx, t, baseURL+"/pxe/"+privateSchematicID+"/v1.10.2/metal-amd64", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = authGet(ctx, t, baseURL+"/pxe/"+privateSchematicID+"/v1.10.2/metal-amd64", authToken)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		script, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(script), "#!ipxe")
+	})
+
+	t.Run("image", func(t *testing.T) {
+		t.Parallel()
+
+		resp := authGet(ctx, t, baseURL+"/image/"+privateSchematicID+"/v1.10.2/kernel-amd64", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = authGet(ctx, t, baseURL+"/image/"+privateSchematicID+"/v1.10.2/kernel-amd64", "invalid")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("build", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := anonymous.BuildCreate(ctx, privateSchematicID, "v1.10.2", "metal-arm64.raw.xz")
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+		build, err := authenticated.BuildCreate(ctx, privateSchematicID, "v1.10.2", "metal-arm64.raw.xz")
+		require.NoError(t, err)
+
+		// the same asset for the public schematic is a separate build
+		public, err := anonymous.BuildCreate(ctx, emptySchematicID, "v1.10.2", "metal-arm64.raw.xz")
+		require.NoError(t, err)
+		assert.NotEqual(t, build.ID, public.ID)
+
+		_, err = anonymous.BuildGet(ctx, build.ID)
+		require.Error(t, err)
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusUnauthorized), err)
+
+		build, err = authenticated.BuildGet(ctx, build.ID)
+		require.NoError(t, err)
+
+		if build.Status == client.BuildStatusDone {
+			assert.Contains(t, build.URL, "/image/"+privateSchematicID+"/")
+		}
+	})
+
+	t.Run("build log", func(t *testing.T) {
+		t.Parallel()
+
+		// use a dedicated private schematic, so that no other test builds the same asset
+		schematicID, err := authenticated.SchematicCreate(ctx, schematic.Schematic{
+			Private: true,
+			Customization: schematic.Customization{
+				ExtraKernelArgs: []string{"build-log-auth-test"},
+			},
+		})
+		require.NoError(t, err)
+
+		resp := authGet(ctx, t, baseURL+"/image/"+schematicID+"/v1.10.2/cmdline-metal-amd64", authToken)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+
+		// the build ID is the profile hash, which is the ETag of the asset
+		logURL := baseURL + "/builds/" + strings.Trim(resp.Header.Get("ETag"), `"`) + "/log"
+
+		resp = authGet(ctx, t, logURL, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = authGet(ctx, t, logURL, authToken)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		t.Parallel()
+
+		resp := authGet(ctx, t, baseURL+"/v2/metal-installer/"+privateSchematicID+"/manifests/v1.10.2", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, `Basic realm="image-factory"`, resp.Header.Get("WWW-Authenticate"))
+
+		// the registry base is not protected, as authentication is only required for private schematics
+		resp = authGet(ctx, t, baseURL+"/v2", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/pkg/client"
+)
+
+func testBuildsFrontend(ctx context.Context, t *testing.T, baseURL string) {
+	c, err := client.New(baseURL)
+	require.NoError(t, err)
+
+	t.Run("build", func(t *testing.T) {
+		t.Parallel()
+
+		build, err := c.BuildCreate(ctx, emptySchematicID, "v1.10.2", "metal-arm64.raw.xz")
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, build.ID)
+		assert.Contains(t, []client.BuildStatus{client.BuildStatusQueued, client.BuildStatusRunning, client.BuildStatusDone}, build.Status)
+
+		// submitting the same build again returns the same job
+		again, err := c.BuildCreate(ctx, emptySchematicID, "1.10.2", "metal-arm64.raw.xz")
+		require.NoError(t, err)
+
+		assert.Equal(t, build.ID, again.ID)
+
+		require.EventuallyWithT(t, func(collect *assert.CollectT) {
+			build, err = c.BuildGet(ctx, build.ID)
+			require.NoError(collect, err)
+
+			assert.Equal(collect, client.BuildStatusDone, build.Status)
+		}, 20*time.Minute, 5*time.Second)
+
+		assert.Empty(t, build.Error)
+		require.True(t, strings.HasSuffix(build.URL, "/image/"+emptySchematicID+"/v1.10.2/metal-arm64.raw.xz"), build.URL)
+
+		// the asset is available from the cache now
+		resp := downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "metal-arm64.raw.xz")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := c.BuildGet(ctx, "aaaa")
+		require.Error(t, err)
+
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusNotFound))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := c.BuildCreate(ctx, emptySchematicID, "v1.10.2", "metal-amd64.foo")
+		require.Error(t, err)
+
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusBadRequest))
+	})
+
+	t.Run("missing schematic", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := c.BuildCreate(ctx, strings.Repeat("0", 64), "v1.10.2", "metal-amd64.iso")
+		require.Error(t, err)
+
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusNotFound))
+	})
+}
@@ -0,0 +1,154 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/cmd/image-factory/cmd"
+	"github.com/siderolabs/image-factory/internal/objectstore/objectstoretest"
+)
+
+// noRedirectClient returns the redirects as the responses.
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func doCacheRedirectRequest(ctx context.Context, t *testing.T, client *http.Client, method, requestURL string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, body
+}
+
+// TestIntegrationCacheRedirect runs the factories with the cache storages with and without the download redirects support.
+//
+// The factory is started for each cache storage, so the object store and filesystem caches are empty at the start.
+func TestIntegrationCacheRedirect(t *testing.T) {
+	const assetPath = "initramfs-amd64.xz"
+
+	expectedContentType := mime.TypeByExtension(".xz")
+	expectedContentDisposition := `attachment; filename="` + assetPath + `"`
+
+	t.Run("s3", func(t *testing.T) {
+		store := objectstoretest.NewStore(t)
+
+		t.Setenv("AWS_ACCESS_KEY_ID", objectstoretest.AccessKeyID)
+		t.Setenv("AWS_SECRET_ACCESS_KEY", objectstoretest.SecretAccessKey)
+
+		ctx, listenAddr := setupFactory(t, func(options *cmd.Options) {
+			options.CacheStorage = cmd.CacheStorageS3
+			options.CacheS3.Endpoint = store.URL
+			options.CacheS3.Bucket = objectstoretest.Bucket
+			options.CacheRedirect = true
+		})
+
+		assetURL := "http://" + listenAddr + "/image/" + emptySchematicID + "/v1.10.2/" + assetPath
+
+		// the asset which was just built is served directly
+		resp, built := doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotEmpty(t, built)
+
+		// HEAD requests are served directly
+		resp, _ = doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodHead, assetURL)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedContentDisposition, resp.Header.Get("Content-Disposition"))
+		assert.Equal(t, 0, store.PresignedDownloads())
+
+		// the cached asset is redirected to the pre-signed URL
+		resp, _ = doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(location.String(), store.URL+"/"+objectstoretest.Bucket+"/sha256-"), location.String())
+		assert.NotEmpty(t, location.Query().Get("X-Amz-Signature"))
+		assert.Equal(t, expectedContentDisposition, location.Query().Get("response-content-disposition"))
+
+		// the object store serves the asset with the same headers as the factory
+		resp, redirected := doCacheRedirectRequest(ctx, t, http.DefaultClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedContentDisposition, resp.Header.Get("Content-Disposition"))
+
+		if expectedContentType != "" {
+			assert.Equal(t, expectedContentType, resp.Header.Get("Content-Type"))
+		}
+
+		assert.Equal(t, sha256.Sum256(built), sha256.Sum256(redirected))
+		assert.Equal(t, 1, store.PresignedDownloads())
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		ctx, listenAddr := setupFactory(t, func(options *cmd.Options) {
+			options.CacheRedirect = true
+		})
+
+		assetURL := "http://" + listenAddr + "/image/" + emptySchematicID + "/v1.10.2/" + assetPath
+
+		// the asset might be already in the cache repository shared with the other tests
+		resp, _ := doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Contains(t, []int{http.StatusOK, http.StatusTemporaryRedirect}, resp.StatusCode)
+
+		// the cached asset is redirected to the cache repository blob
+		resp, _ = doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+		repository, err := name.NewRepository(cacheRepository)
+		require.NoError(t, err)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+
+		assert.Equal(t, repository.Registry.Name(), location.Host)
+		assert.True(t, strings.HasPrefix(location.Path, "/v2/"+repository.RepositoryStr()+"/blobs/sha256:"), location.Path)
+	})
+
+	t.Run("filesystem", func(t *testing.T) {
+		ctx, listenAddr := setupFactory(t, func(options *cmd.Options) {
+			options.CacheStorage = cmd.CacheStorageFilesystem
+			options.CacheStoragePath = t.TempDir()
+			options.CacheRedirect = true
+		})
+
+		assetURL := "http://" + listenAddr + "/image/" + emptySchematicID + "/v1.10.2/" + assetPath
+
+		resp, built := doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// the filesystem cache storage can't presign the URLs, so the cached asset is served directly
+		resp, cached := doCacheRedirectRequest(ctx, t, noRedirectClient, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Equal(t, expectedContentDisposition, resp.Header.Get("Content-Disposition"))
+		assert.Equal(t, sha256.Sum256(built), sha256.Sum256(cached))
+	})
+}
@@ -7,11 +7,18 @@
 package integration_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +35,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ulikunitz/xz"
 	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/pkg/client"
 )
 
 func downloadAsset(ctx context.Context, t *testing.T, baseURL string, schematicID, talosVersion, path string) *http.Response {
@@ -913,6 +922,257 @@ func testDownloadFrontend(ctx context.Context, t *testing.T, baseURL string) {
 		}
 	})
 
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+
+		resp := downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "kernel-amd64")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		full, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		downloadRange := func(t *testing.T, headers map[string]string) *http.Response {
+			t.Helper()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/image/"+emptySchematicID+"/v1.10.2/kernel-amd64", nil)
+			require.NoError(t, err)
+
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				resp.Body.Close()
+			})
+
+			return resp
+		}
+
+		for _, test := range []struct {
+			name    string
+			headers map[string]string
+
+			expectedCode int
+			expectedBody []byte
+		}{
+			{
+				name:         "range",
+				headers:      map[string]string{"Range": "bytes=1000-1999"},
+				expectedCode: http.StatusPartialContent,
+				expectedBody: full[1000:2000],
+			},
+			{
+				name:         "suffix range",
+				headers:      map[string]string{"Range": "bytes=-100"},
+				expectedCode: http.StatusPartialContent,
+				expectedBody: full[len(full)-100:],
+			},
+			{
+				name:         "if-range match",
+				headers:      map[string]string{"Range": "bytes=1000-", "If-Range": etag},
+				expectedCode: http.StatusPartialContent,
+				expectedBody: full[1000:],
+			},
+			{
+				name:         "if-range mismatch",
+				headers:      map[string]string{"Range": "bytes=1000-", "If-Range": `"foo"`},
+				expectedCode: http.StatusOK,
+				expectedBody: full,
+			},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+
+				resp := downloadRange(t, test.headers)
+				require.Equal(t, test.expectedCode, resp.StatusCode)
+
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, test.expectedBody, body)
+			})
+		}
+
+		t.Run("unsatisfiable", func(t *testing.T) {
+			t.Parallel()
+
+			resp := downloadRange(t, map[string]string{"Range": fmt.Sprintf("bytes=%d-", len(full))})
+			assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+		})
+	})
+
+	t.Run("checksums", func(t *testing.T) {
+		t.Parallel()
+
+		resp := downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "metal-amd64.iso")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		sha256Hash, sha512Hash := sha256.New(), sha512.New()
+
+		_, err := io.Copy(io.MultiWriter(sha256Hash, sha512Hash), resp.Body)
+		require.NoError(t, err)
+
+		for ext, expected := range map[string]string{
+			".sha256": hex.EncodeToString(sha256Hash.Sum(nil)),
+			".sha512": hex.EncodeToString(sha512Hash.Sum(nil)),
+		} {
+			t.Run(ext, func(t *testing.T) {
+				t.Parallel()
+
+				resp := downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "metal-amd64.iso"+ext)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, expected+"  metal-amd64.iso\n", string(body))
+			})
+		}
+
+		t.Run("if-none-match", func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/image/"+emptySchematicID+"/v1.10.2/metal-amd64.iso", nil)
+			require.NoError(t, err)
+
+			req.Header.Set("If-None-Match", etag)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				resp.Body.Close()
+			})
+
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+			assert.Equal(t, etag, resp.Header.Get("ETag"))
+		})
+
+		t.Run("if-none-match wildcard", func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL+"/image/"+emptySchematicID+"/v1.10.2/metal-amd64.iso", nil)
+			require.NoError(t, err)
+
+			req.Header.Set("If-None-Match", "*")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				resp.Body.Close()
+			})
+
+			// the asset is served, as the wildcard is not matched
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := client.New(baseURL)
+		require.NoError(t, err)
+
+		resp := downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "kernel-amd64")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		asset, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		require.NoError(t, c.VerifyAsset(ctx, emptySchematicID, "v1.10.2", "kernel-amd64", bytes.NewReader(asset)))
+
+		tampered := slices.Clone(asset)
+		tampered[len(tampered)/2] ^= 0xff
+
+		require.Error(t, c.VerifyAsset(ctx, emptySchematicID, "v1.10.2", "kernel-amd64", bytes.NewReader(tampered)))
+
+		resp = downloadAsset(ctx, t, baseURL, emptySchematicID, "v1.10.2", "kernel-amd64.bundle")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var bundle struct {
+			MediaType        string `json:"mediaType"`
+			MessageSignature struct {
+				MessageDigest struct {
+					Algorithm string `json:"algorithm"`
+					Digest    []byte `json:"digest"`
+				} `json:"messageDigest"`
+				Signature []byte `json:"signature"`
+			} `json:"messageSignature"`
+		}
+
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&bundle))
+
+		digest := sha256.Sum256(asset)
+
+		assert.Equal(t, "application/vnd.dev.sigstore.bundle.v0.3+json", bundle.MediaType)
+		assert.Equal(t, "SHA2_256", bundle.MessageSignature.MessageDigest.Algorithm)
+		assert.Equal(t, digest[:], bundle.MessageSignature.MessageDigest.Digest)
+		assert.NotEmpty(t, bundle.MessageSignature.Signature)
+	})
+
+	t.Run("sbom", func(t *testing.T) {
+		t.Parallel()
+
+		resp := downloadAsset(ctx, t, baseURL, systemExtensionsSchematicID, "v1.10.2", "initramfs-amd64.xz")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		sha256Hash := sha256.New()
+
+		_, err := io.Copy(sha256Hash, resp.Body)
+		require.NoError(t, err)
+
+		resp = downloadAsset(ctx, t, baseURL, systemExtensionsSchematicID, "v1.10.2", "initramfs-amd64.xz.sbom.json")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "application/spdx+json", resp.Header.Get("Content-Type"))
+
+		var sbom struct {
+			SPDXVersion string `json:"spdxVersion"`
+			Packages    []struct {
+				Name        string `json:"name"`
+				VersionInfo string `json:"versionInfo"`
+				Checksums   []struct {
+					Algorithm     string `json:"algorithm"`
+					ChecksumValue string `json:"checksumValue"`
+				} `json:"checksums"`
+			} `json:"packages"`
+		}
+
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&sbom))
+
+		assert.Equal(t, "SPDX-2.3", sbom.SPDXVersion)
+		require.Len(t, sbom.Packages, 6)
+
+		assert.Equal(t, "initramfs-amd64.xz", sbom.Packages[0].Name)
+		assert.Equal(t, hex.EncodeToString(sha256Hash.Sum(nil)), sbom.Packages[0].Checksums[0].ChecksumValue)
+
+		assert.Equal(t, "talos", sbom.Packages[1].Name)
+		assert.Equal(t, "v1.10.2", sbom.Packages[1].VersionInfo)
+		assert.NotEmpty(t, sbom.Packages[1].Checksums, "imager digest should be recorded")
+
+		assert.Equal(t, "schematic", sbom.Packages[2].Name)
+		assert.Equal(t, systemExtensionsSchematicID, sbom.Packages[2].VersionInfo)
+
+		var extensions []string
+
+		for _, pkg := range sbom.Packages[3:] {
+			extensions = append(extensions, pkg.Name)
+		}
+
+		assert.Equal(t, []string{"siderolabs/amd-ucode", "siderolabs/gvisor", "siderolabs/gasket-driver"}, extensions)
+	})
+
 	t.Run("invalid", func(t *testing.T) {
 		t.Parallel()
 
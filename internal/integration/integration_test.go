@@ -11,6 +11,7 @@ import (
 	"crypto/elliptic"
 	_ "embed"
 	"flag"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -24,10 +25,11 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/siderolabs/image-factory/cmd/image-factory/cmd"
+	"github.com/siderolabs/image-factory/internal/asset"
 	"github.com/siderolabs/image-factory/internal/remotewrap"
 )
 
-func setupFactory(t *testing.T) (context.Context, string) {
+func setupFactory(t *testing.T, configure ...func(*cmd.Options)) (context.Context, string) {
 	t.Helper()
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -46,6 +48,11 @@ func setupFactory(t *testing.T) (context.Context, string) {
 
 	setupSecureBoot(t, &options)
 	setupCacheSigningKey(t, &options)
+	setupAuthTokens(t, &options)
+
+	for _, fn := range configure {
+		fn(&options)
+	}
 
 	t.Cleanup(remotewrap.ShutdownTransport)
 
@@ -88,6 +95,24 @@ func setupCacheSigningKey(t *testing.T, options *cmd.Options) {
 	options.CacheSigningKeyPath = optionsDir + "/cache-signing-key.pem"
 }
 
+// Static tokens accepted by the factory in the tests, only the authToken is allowed to access the admin API.
+const (
+	authToken   = "integration-test-token"
+	viewerToken = "integration-test-viewer-token"
+)
+
+func setupAuthTokens(t *testing.T, options *cmd.Options) {
+	t.Helper()
+
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+
+	require.NoError(t, os.WriteFile(tokensPath, []byte("integration:"+authToken+"\nviewer:"+viewerToken+"\n"), 0o600))
+
+	// authentication is only required for private schematics
+	options.Auth.TokensPath = tokensPath
+	options.Auth.AdminSubjects = "static:integration"
+}
+
 var (
 	//go:embed "testdata/secureboot/uki-signing-key.pem"
 	secureBootSigningKey []byte
@@ -129,6 +154,21 @@ func findListenAddr(t *testing.T) string {
 	return addr
 }
 
+// TestMain runs the Talos imager when the test binary is started as the imager subprocess of the asset builder.
+func TestMain(m *testing.M) {
+	if len(os.Args) == 3 && os.Args[1] == asset.ImagerCommand {
+		if err := cmd.RunImager(context.Background(), os.Args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
 func TestIntegration(t *testing.T) {
 	ctx, listenAddr := setupFactory(t)
 	baseURL := "http://" + listenAddr
@@ -144,6 +184,12 @@ func TestIntegration(t *testing.T) {
 		testDownloadFrontend(ctx, t, baseURL)
 	})
 
+	t.Run("TestBuildsFrontend", func(t *testing.T) {
+		t.Parallel()
+
+		testBuildsFrontend(ctx, t, baseURL)
+	})
+
 	t.Run("TestPXEFrontend", func(t *testing.T) {
 		t.Parallel()
 
@@ -167,6 +213,24 @@ func TestIntegration(t *testing.T) {
 
 		testSecureBootFrontend(ctx, t, baseURL)
 	})
+
+	t.Run("TestAPIFrontend", func(t *testing.T) {
+		t.Parallel()
+
+		testAPIFrontend(ctx, t, baseURL)
+	})
+
+	t.Run("TestAuth", func(t *testing.T) {
+		t.Parallel()
+
+		testAuth(ctx, t, baseURL)
+	})
+
+	t.Run("TestCacheAdmin", func(t *testing.T) {
+		t.Parallel()
+
+		testCacheAdmin(ctx, t, baseURL)
+	})
 }
 
 var (
@@ -11,6 +11,7 @@ import (
 	"context"
 	"crypto"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -123,6 +124,12 @@ func testInstallerImage(ctx context.Context, t *testing.T, registry name.Registr
 	// verify the image signature
 	assertImageSignature(ctx, t, ref, baseURL)
 
+	// verify the SBOM is attached to the platform image
+	assertImageSBOM(ctx, t, ref, img)
+
+	// verify the provenance attestation
+	assertImageProvenance(ctx, t, ref, baseURL, schematic, talosVersion)
+
 	// try to get the image once again, it should be fast now, as the image got cached & signed
 	start := time.Now()
 
@@ -174,9 +181,108 @@ func assertImageContainsFiles(t *testing.T, img v1.Image, files map[string]struc
 	assert.Empty(t, files, "extra files: %v, missing files %v", extraFiles, files)
 }
 
+func assertImageSBOM(ctx context.Context, t *testing.T, ref name.Reference, img v1.Image) {
+	t.Helper()
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	referrers, err := remote.Referrers(ref.Context().Digest(digest.String()), remote.WithContext(ctx), remote.WithFilter("artifactType", "application/spdx+json"))
+	require.NoError(t, err)
+
+	referrersManifest, err := referrers.IndexManifest()
+	require.NoError(t, err)
+
+	require.NotEmpty(t, referrersManifest.Manifests)
+
+	sbomImage, err := remote.Image(ref.Context().Digest(referrersManifest.Manifests[0].Digest.String()), remote.WithContext(ctx))
+	require.NoError(t, err)
+
+	layers, err := sbomImage.Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+
+	rc, err := layers[0].Uncompressed()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		rc.Close()
+	})
+
+	var sbom struct {
+		Packages []struct {
+			Name        string `json:"name"`
+			VersionInfo string `json:"versionInfo"`
+			Checksums   []struct {
+				ChecksumValue string `json:"checksumValue"`
+			} `json:"checksums"`
+		} `json:"packages"`
+	}
+
+	require.NoError(t, json.NewDecoder(rc).Decode(&sbom))
+	require.NotEmpty(t, sbom.Packages)
+
+	assert.Equal(t, digest.Hex, sbom.Packages[0].Checksums[0].ChecksumValue)
+}
+
 func assertImageSignature(ctx context.Context, t *testing.T, ref name.Reference, baseURL string) {
 	t.Helper()
 
+	_, _, err := cosign.VerifyImageSignatures(ctx, ref, imageCheckOpts(ctx, t, baseURL))
+	assert.NoError(t, err)
+}
+
+func assertImageProvenance(ctx context.Context, t *testing.T, ref name.Reference, baseURL, schematicID, talosVersion string) {
+	t.Helper()
+
+	attestations, _, err := cosign.VerifyImageAttestations(ctx, ref, imageCheckOpts(ctx, t, baseURL))
+	require.NoError(t, err)
+	require.NotEmpty(t, attestations)
+
+	payload, err := attestations[0].Payload()
+	require.NoError(t, err)
+
+	var envelope struct {
+		PayloadType string `json:"payloadType"`
+		Payload     []byte `json:"payload"`
+	}
+
+	require.NoError(t, json.Unmarshal(payload, &envelope))
+	assert.Equal(t, "application/vnd.in-toto+json", envelope.PayloadType)
+
+	var statement struct {
+		PredicateType string `json:"predicateType"`
+		Predicate     struct {
+			BuildDefinition struct {
+				ExternalParameters struct {
+					SchematicID  string `json:"schematicId"`
+					TalosVersion string `json:"talosVersion"`
+				} `json:"externalParameters"`
+				ResolvedDependencies []struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+		} `json:"predicate"`
+	}
+
+	require.NoError(t, json.Unmarshal(envelope.Payload, &statement))
+
+	assert.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	assert.Equal(t, schematicID, statement.Predicate.BuildDefinition.ExternalParameters.SchematicID)
+	assert.Equal(t, talosVersion, statement.Predicate.BuildDefinition.ExternalParameters.TalosVersion)
+
+	var roles []string
+
+	for _, dep := range statement.Predicate.BuildDefinition.ResolvedDependencies {
+		roles = append(roles, dep.Annotations["role"])
+	}
+
+	assert.Contains(t, roles, "base-installer")
+}
+
+func imageCheckOpts(ctx context.Context, t *testing.T, baseURL string) *cosign.CheckOpts {
+	t.Helper()
+
 	// download public key
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/oci/cosign/signing-key.pub", nil)
 	require.NoError(t, err)
@@ -199,15 +305,12 @@ func assertImageSignature(ctx context.Context, t *testing.T, ref name.Reference,
 	verifier, err := signature.LoadVerifier(pubKey, crypto.SHA256)
 	require.NoError(t, err)
 
-	checkOpts := &cosign.CheckOpts{
+	return &cosign.CheckOpts{
 		SigVerifier: verifier,
 		IgnoreSCT:   true,
 		IgnoreTlog:  true,
 		Offline:     true,
 	}
-
-	_, _, err = cosign.VerifyImageSignatures(ctx, ref, checkOpts)
-	assert.NoError(t, err)
 }
 
 func testRegistryFrontend(ctx context.Context, t *testing.T, registryAddr string, baseURL string) {
@@ -11,6 +11,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net/http"
 	"testing"
@@ -136,6 +137,44 @@ func testSchematic(ctx context.Context, t *testing.T, baseURL string) {
 		assert.Equal(t, emptySchematicID, createSchematicGetID(ctx, t, c, *testSchematics[emptySchematicID]))
 	})
 
+	t.Run("get", func(t *testing.T) {
+		for _, id := range []string{emptySchematicID, systemExtensionsSchematicID, metaSchematicID, rpiGenericOverlaySchematicID} {
+			cfg, err := c.SchematicGet(ctx, id)
+			require.NoError(t, err)
+
+			assert.Equal(t, testSchematics[id], cfg)
+		}
+	})
+
+	t.Run("get json", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/schematics/"+extraArgsSchematicID, nil)
+		require.NoError(t, err)
+
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			resp.Body.Close()
+		})
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var cfg schematic.Schematic
+
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&cfg))
+		assert.Equal(t, testSchematics[extraArgsSchematicID], &cfg)
+	})
+
+	t.Run("get not found", func(t *testing.T) {
+		_, err := c.SchematicGet(ctx, "0000000000000000000000000000000000000000000000000000000000000000")
+		require.Error(t, err)
+
+		assert.True(t, client.IsHTTPErrorCode(err, http.StatusNotFound))
+	})
+
 	t.Run("invalid", func(t *testing.T) {
 		assert.Equal(t, "yaml: unmarshal errors:\n  line 1: field something not found in type schematic.Schematic\n", createSchematicInvalid(ctx, t, baseURL, []byte(`something:`)))
 	})
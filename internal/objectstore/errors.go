@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package objectstore
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is the error response of the object store.
+type Error struct {
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	StatusCode int    `xml:"-"`
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("object store error: status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("object store error: status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// parseError parses the XML error response, the response without the body (e.g. HEAD) only has the status code.
+func parseError(statusCode int, data []byte) error {
+	storeErr := &Error{}
+
+	xml.Unmarshal(data, storeErr) //nolint:errcheck
+
+	storeErr.StatusCode = statusCode
+
+	return storeErr
+}
+
+// IsNotFound checks if the error is the object store error for the missing object.
+func IsNotFound(err error) bool {
+	var storeErr *Error
+
+	if !errors.As(err, &storeErr) {
+		return false
+	}
+
+	return storeErr.StatusCode == http.StatusNotFound
+}
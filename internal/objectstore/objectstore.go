@@ -0,0 +1,542 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package objectstore implements a minimal client for the S3-compatible object stores.
+//
+// Only the operations required to store the cached assets are implemented: objects are uploaded (with multipart uploads
+// for the large objects), copied, downloaded by ranges, listed and deleted.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const (
+	// DefaultPartSize is the default size of the parts of the multipart upload.
+	DefaultPartSize = 128 << 20
+
+	// unsignedPayload is the payload hash of the streamed request bodies.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// emptyPayloadHash is the SHA-256 hash of the empty request body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Options configures the object store client.
+type Options struct {
+	// Client is the HTTP client (optional).
+	Client *http.Client
+
+	// Endpoint is the base URL of the object store, e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000.
+	Endpoint string
+	// Region is the region of the bucket, S3-compatible stores usually accept any region.
+	Region string
+	// Bucket is the name of the bucket.
+	Bucket string
+	// Prefix is prepended to all object keys (optional).
+	Prefix string
+
+	// Static credentials, if not set the standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	// environment variables are used.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// PartSize is the size of the parts of the multipart upload, objects up to this size are uploaded with a single request.
+	//
+	// Defaults to DefaultPartSize.
+	PartSize int64
+
+	// VirtualHostedStyle addresses the bucket as the subdomain of the endpoint, otherwise the bucket is the first path segment.
+	//
+	// Path-style addressing is supported by most S3-compatible stores (MinIO, Ceph, etc.).
+	VirtualHostedStyle bool
+}
+
+// Client is the S3-compatible object store client.
+type Client struct {
+	client      *http.Client
+	signer      *v4.Signer
+	endpoint    *url.URL
+	credentials aws.Credentials
+	options     Options
+}
+
+// New creates a new object store client.
+func New(options Options) (*Client, error) {
+	if options.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported endpoint scheme %q", endpoint.Scheme)
+	}
+
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	if options.PartSize <= 0 {
+		options.PartSize = DefaultPartSize
+	}
+
+	if options.Client == nil {
+		options.Client = cleanhttp.DefaultPooledClient()
+	}
+
+	credentials := aws.Credentials{
+		AccessKeyID:     options.AccessKeyID,
+		SecretAccessKey: options.SecretAccessKey,
+		SessionToken:    options.SessionToken,
+	}
+
+	if credentials.AccessKeyID == "" {
+		credentials.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		credentials.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		credentials.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
+	return &Client{
+		client: options.Client,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 doesn't normalize the paths
+			o.DisableURIPathEscaping = true
+		}),
+		endpoint:    endpoint,
+		credentials: credentials,
+		options:     options,
+	}, nil
+}
+
+// ObjectInfo describes the object.
+type ObjectInfo struct {
+	LastModified time.Time
+
+	// Key is only set for the listed objects.
+	Key  string
+	ETag string
+	Size int64
+}
+
+// Head returns the object metadata.
+func (c *Client) Head(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	resp.Body.Close() //nolint:errcheck
+
+	// the malformed timestamp is ignored
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified")) //nolint:errcheck
+
+	return ObjectInfo{
+		LastModified: lastModified,
+		ETag:         resp.Header.Get("ETag"),
+		Size:         resp.ContentLength,
+	}, nil
+}
+
+// Get returns the reader for the part of the object starting at the offset, negative length reads to the end.
+func (c *Client) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+
+	switch {
+	case length == 0:
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	case length > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Put uploads the object of the given size.
+//
+// Objects larger than the part size are uploaded with the multipart upload.
+func (c *Client) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size > c.options.PartSize {
+		return c.putMultipart(ctx, key, r, size)
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, key, nil, nil, &body{reader: r, size: size})
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// Copy copies the object of the given size within the bucket.
+//
+// Objects larger than the part size are copied with the multipart upload.
+func (c *Client) Copy(ctx context.Context, srcKey, dstKey string, size int64) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", (&url.URL{Path: "/" + c.options.Bucket + "/" + c.options.Prefix + srcKey}).EscapedPath())
+
+	if size > c.options.PartSize {
+		return c.multipartUpload(ctx, dstKey, size, func(query url.Values, offset, partSize int64) (string, error) {
+			partHeader := header.Clone()
+			partHeader.Set("X-Amz-Copy-Source-Range", fmt.Sprintf("bytes=%d-%d", offset, offset+partSize-1))
+
+			return copyResult(c.do(ctx, http.MethodPut, dstKey, query, partHeader, nil))
+		})
+	}
+
+	_, err := copyResult(c.do(ctx, http.MethodPut, dstKey, nil, header, nil))
+
+	return err
+}
+
+// copyResult returns the ETag of the copied object (or part) from the copy response.
+func copyResult(resp *http.Response, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	// the copy request might fail after the response status is sent
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read copy response: %w", err)
+	}
+
+	if bytes.Contains(data, []byte("<Error>")) {
+		return "", parseError(http.StatusInternalServerError, data)
+	}
+
+	var result struct {
+		ETag string `xml:"ETag"`
+	}
+
+	if err = xml.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to decode copy response: %w", err)
+	}
+
+	return result.ETag, nil
+}
+
+// Delete removes the object, the object which doesn't exist is ignored.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// List returns the objects with the prefix, the keys are relative to the client prefix.
+func (c *Client) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var (
+		objects           []ObjectInfo
+		continuationToken string
+	)
+
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {c.options.Prefix + prefix},
+		}
+
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				LastModified time.Time `xml:"LastModified"`
+				Key          string    `xml:"Key"`
+				ETag         string    `xml:"ETag"`
+				Size         int64     `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated bool `xml:"IsTruncated"`
+		}
+
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close() //nolint:errcheck
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				LastModified: object.LastModified,
+				Key:          strings.TrimPrefix(object.Key, c.options.Prefix),
+				ETag:         object.ETag,
+				Size:         object.Size,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// PresignGet returns the pre-signed URL to download the object, valid for the given duration.
+func (c *Client) PresignGet(ctx context.Context, key string, expires time.Duration, query url.Values) (string, error) {
+	query = maps.Clone(query)
+	if query == nil {
+		query = url.Values{}
+	}
+
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(key, query), nil)
+	if err != nil {
+		return "", err
+	}
+
+	signedURL, _, err := c.signer.PresignHTTP(ctx, c.credentials, req, unsignedPayload, "s3", c.options.Region, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to presign request: %w", err)
+	}
+
+	return signedURL, nil
+}
+
+func (c *Client) putMultipart(ctx context.Context, key string, r io.Reader, size int64) error {
+	return c.multipartUpload(ctx, key, size, func(query url.Values, _, partSize int64) (string, error) {
+		resp, err := c.do(ctx, http.MethodPut, key, query, nil, &body{reader: io.LimitReader(r, partSize), size: partSize})
+		if err != nil {
+			return "", err
+		}
+
+		resp.Body.Close() //nolint:errcheck
+
+		return resp.Header.Get("ETag"), nil
+	})
+}
+
+// uploadPartFunc uploads the part of the object at the offset with the multipart upload query, and returns the ETag of the part.
+type uploadPartFunc func(query url.Values, offset, partSize int64) (string, error)
+
+// multipartUpload creates the object of the given size from the parts.
+func (c *Client) multipartUpload(ctx context.Context, key string, size int64, uploadPart uploadPartFunc) error {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close() //nolint:errcheck
+
+	if err != nil {
+		return fmt.Errorf("failed to decode multipart upload response: %w", err)
+	}
+
+	if err = c.uploadParts(ctx, key, initiated.UploadID, size, uploadPart); err != nil {
+		// the upload is aborted on the best-effort basis, stores remove the incomplete uploads eventually
+		abortResp, abortErr := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil)
+		if abortErr == nil {
+			abortResp.Body.Close() //nolint:errcheck
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// completedPart is the part of the multipart upload in the complete request.
+type completedPart struct {
+	ETag       string `xml:"ETag"`
+	PartNumber int    `xml:"PartNumber"`
+}
+
+func (c *Client) uploadParts(ctx context.Context, key, uploadID string, size int64, uploadPart uploadPartFunc) error {
+	var parts []completedPart
+
+	for offset, partNumber := int64(0), 1; offset < size; offset, partNumber = offset+c.options.PartSize, partNumber+1 {
+		partSize := min(c.options.PartSize, size-offset)
+
+		etag, err := uploadPart(url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		}, offset, partSize)
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+		}
+
+		parts = append(parts, completedPart{
+			ETag:       etag,
+			PartNumber: partNumber,
+		})
+	}
+
+	completeRequest, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{
+		Parts: parts,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, &body{
+		reader:      bytes.NewReader(completeRequest),
+		size:        int64(len(completeRequest)),
+		payloadHash: payloadHash(completeRequest),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	// the complete request might fail after the response status is sent
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read multipart upload response: %w", err)
+	}
+
+	if bytes.Contains(data, []byte("<Error>")) {
+		return parseError(http.StatusInternalServerError, data)
+	}
+
+	return nil
+}
+
+// body is the request body.
+type body struct {
+	reader io.Reader
+	// payloadHash is the hex-encoded SHA-256 of the body, the streamed bodies are not signed.
+	payloadHash string
+	size        int64
+}
+
+func payloadHash(data []byte) string {
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// objectURL returns the URL of the object, empty key returns the URL of the bucket.
+func (c *Client) objectURL(key string, query url.Values) string {
+	u := *c.endpoint
+
+	objectPath := ""
+
+	if key != "" {
+		objectPath = "/" + c.options.Prefix + key
+	}
+
+	if c.options.VirtualHostedStyle {
+		u.Host = c.options.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.options.Bucket + objectPath
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	return u.String()
+}
+
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, reqBody *body) (*http.Response, error) {
+	var bodyReader io.Reader
+
+	if reqBody != nil {
+		bodyReader = reqBody.reader
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	hash := emptyPayloadHash
+
+	if reqBody != nil {
+		req.ContentLength = reqBody.size
+
+		if reqBody.size == 0 {
+			// make sure Content-Length: 0 is sent
+			req.Body = http.NoBody
+		}
+
+		hash = reqBody.payloadHash
+		if hash == "" {
+			hash = unsignedPayload
+		}
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", hash)
+
+	if err = c.signer.SignHTTP(ctx, c.credentials, req, hash, "s3", c.options.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024)) //nolint:errcheck
+
+	return nil, parseError(resp.StatusCode, data)
+}
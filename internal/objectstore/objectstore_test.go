@@ -0,0 +1,237 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package objectstore_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/objectstore"
+	"github.com/siderolabs/image-factory/internal/objectstore/objectstoretest"
+)
+
+func newClient(t *testing.T, store *objectstoretest.Store, options objectstore.Options) *objectstore.Client {
+	t.Helper()
+
+	options.Endpoint = store.URL
+	options.Bucket = objectstoretest.Bucket
+	options.AccessKeyID = objectstoretest.AccessKeyID
+	options.SecretAccessKey = objectstoretest.SecretAccessKey
+
+	client, err := objectstore.New(options)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	store := objectstoretest.NewStore(t)
+	client := newClient(t, store, objectstore.Options{Prefix: "cache/"})
+
+	data := []byte("hello, world")
+
+	require.NoError(t, client.Put(ctx, "greeting", bytes.NewReader(data), int64(len(data))))
+	require.NoError(t, client.Put(ctx, "empty", bytes.NewReader(nil), 0))
+
+	stored, _ := store.Object("cache/greeting")
+	assert.Equal(t, data, stored)
+
+	info, err := client.Head(ctx, "greeting")
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), info.Size)
+	assert.Equal(t, objectstoretest.ETag(data), info.ETag)
+	assert.Equal(t, store.Modified, info.LastModified.UTC())
+
+	for _, test := range []struct {
+		expected       string
+		offset, length int64
+	}{
+		{offset: 0, length: -1, expected: "hello, world"},
+		{offset: 7, length: -1, expected: "world"},
+		{offset: 7, length: 3, expected: "wor"},
+		{offset: 3, length: 0, expected: ""},
+	} {
+		var (
+			rc       io.ReadCloser
+			contents []byte
+		)
+
+		rc, err = client.Get(ctx, "greeting", test.offset, test.length)
+		require.NoError(t, err)
+
+		contents, err = io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		assert.Equal(t, test.expected, string(contents), "offset %d, length %d", test.offset, test.length)
+	}
+
+	_, err = client.Head(ctx, "missing")
+	require.Error(t, err)
+	assert.True(t, objectstore.IsNotFound(err), err)
+
+	_, err = client.Get(ctx, "missing", 0, -1)
+	require.Error(t, err)
+	assert.True(t, objectstore.IsNotFound(err), err)
+	assert.ErrorContains(t, err, "NoSuchKey")
+
+	require.NoError(t, client.Delete(ctx, "greeting"))
+	require.NoError(t, client.Delete(ctx, "greeting"))
+
+	_, err = client.Head(ctx, "greeting")
+	assert.True(t, objectstore.IsNotFound(err), err)
+}
+
+func TestClientList(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	store := objectstoretest.NewStore(t)
+	client := newClient(t, store, objectstore.Options{Prefix: "cache/"})
+
+	objects, err := client.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	for _, key := range []string{"a", "b-lease", "b", "c", "d"} {
+		require.NoError(t, client.Put(ctx, key, strings.NewReader(key), int64(len(key))))
+	}
+
+	// outside of the prefix
+	require.NoError(t, newClient(t, store, objectstore.Options{Prefix: "other/"}).Put(ctx, "e", strings.NewReader("e"), 1))
+
+	// listed in pages of 2
+	objects, err = client.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "b-lease", "c", "d"}, objectKeys(objects))
+
+	assert.EqualValues(t, len("b-lease"), objects[2].Size)
+	assert.Equal(t, objectstoretest.ETag([]byte("b-lease")), objects[2].ETag)
+	assert.Equal(t, store.Modified, objects[2].LastModified)
+
+	objects, err = client.List(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "b-lease"}, objectKeys(objects))
+}
+
+func objectKeys(objects []objectstore.ObjectInfo) []string {
+	result := make([]string, 0, len(objects))
+
+	for _, object := range objects {
+		result = append(result, object.Key)
+	}
+
+	return result
+}
+
+func TestClientMultipart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	store := objectstoretest.NewStore(t)
+	client := newClient(t, store, objectstore.Options{PartSize: 10})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4)
+
+	require.NoError(t, client.Put(ctx, "large", bytes.NewReader(data), int64(len(data))))
+
+	stored, _ := store.Object("large")
+	assert.Equal(t, data, stored)
+	assert.Zero(t, store.PendingUploads())
+
+	// short reader fails the upload
+	require.Error(t, client.Put(ctx, "short", bytes.NewReader(data[:25]), int64(len(data))))
+
+	_, ok := store.Object("short")
+	assert.False(t, ok)
+
+	// the failed upload is aborted
+	assert.Zero(t, store.PendingUploads())
+}
+
+func TestClientCopy(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	store := objectstoretest.NewStore(t)
+	client := newClient(t, store, objectstore.Options{Prefix: "cache/", PartSize: 10})
+
+	small := []byte("hello")
+	large := bytes.Repeat([]byte("0123456789abcdef"), 4)
+
+	require.NoError(t, client.Put(ctx, "small", bytes.NewReader(small), int64(len(small))))
+	require.NoError(t, client.Put(ctx, "large", bytes.NewReader(large), int64(len(large))))
+
+	require.NoError(t, client.Copy(ctx, "small", "small-copy", int64(len(small))))
+
+	stored, _ := store.Object("cache/small-copy")
+	assert.Equal(t, small, stored)
+
+	// copied with the multipart upload
+	require.NoError(t, client.Copy(ctx, "large", "large-copy", int64(len(large))))
+
+	stored, _ = store.Object("cache/large-copy")
+	assert.Equal(t, large, stored)
+	assert.Zero(t, store.PendingUploads())
+
+	err := client.Copy(ctx, "missing", "missing-copy", 5)
+	require.Error(t, err)
+	assert.True(t, objectstore.IsNotFound(err), err)
+
+	// the failed multipart copy is aborted
+	require.Error(t, client.Copy(ctx, "missing", "missing-copy", int64(len(large))))
+	assert.Zero(t, store.PendingUploads())
+}
+
+func TestClientPresignGet(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	store := objectstoretest.NewStore(t)
+	client := newClient(t, store, objectstore.Options{})
+
+	signedURL, err := client.PresignGet(ctx, "asset", time.Minute, url.Values{"response-content-disposition": {`attachment; filename="metal-amd64.iso"`}})
+	require.NoError(t, err)
+
+	u, err := url.Parse(signedURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/bucket/asset", u.Path)
+	assert.Equal(t, "60", u.Query().Get("X-Amz-Expires"))
+	assert.Equal(t, `attachment; filename="metal-amd64.iso"`, u.Query().Get("response-content-disposition"))
+	assert.Contains(t, u.Query().Get("X-Amz-Credential"), "access/")
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+}
+
+func TestNewValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := objectstore.New(objectstore.Options{Endpoint: "http://localhost:9000"})
+	require.EqualError(t, err, "bucket is required")
+
+	_, err = objectstore.New(objectstore.Options{Endpoint: "ftp://localhost", Bucket: "bucket"})
+	require.EqualError(t, err, `unsupported endpoint scheme "ftp"`)
+}
@@ -0,0 +1,327 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package objectstoretest implements the in-memory S3-compatible object store for the tests.
+package objectstoretest
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Credentials and the bucket accepted by the store.
+const (
+	AccessKeyID     = "access"
+	SecretAccessKey = "secret"
+	Bucket          = "bucket"
+)
+
+// Store is a MinIO-style stand-in: an in-memory S3-compatible object store with path-style addressing.
+//
+// The requests should be signed with AccessKeyID, the signature itself is not verified.
+// The objects are listed in pages of ListPageSize, and all of them are reported as modified at Modified.
+type Store struct {
+	*httptest.Server
+
+	Modified time.Time
+
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	mu      sync.Mutex
+
+	ListPageSize int
+
+	uploadID      int
+	presignedGets int
+}
+
+// NewStore starts the store, it's stopped when the test finishes.
+func NewStore(t testing.TB) *Store {
+	t.Helper()
+
+	s := &Store{
+		objects:      map[string][]byte{},
+		uploads:      map[string]map[int][]byte{},
+		Modified:     time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		ListPageSize: 2,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		presigned := query.Get("X-Amz-Signature") != ""
+
+		switch {
+		case presigned && !strings.HasPrefix(query.Get("X-Amz-Credential"), AccessKeyID+"/"):
+			writeError(w, http.StatusForbidden, "AccessDenied")
+		case presigned && r.Method != http.MethodGet:
+			// pre-signed URLs are only valid for the signed method
+			writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		case !presigned && (!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+AccessKeyID+"/") ||
+			r.Header.Get("X-Amz-Content-Sha256") == ""):
+			writeError(w, http.StatusForbidden, "AccessDenied")
+		default:
+			s.serve(t, w, r, presigned)
+		}
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Store) serve(t testing.TB, w http.ResponseWriter, r *http.Request, presigned bool) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, query)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.uploadID++
+		uploadID := strconv.Itoa(s.uploadID)
+		s.uploads[uploadID] = map[int][]byte{}
+
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadID)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copy(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			t.Errorf("invalid part number: %s", err)
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			// truncated body
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+
+			return
+		}
+
+		parts, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+
+			return
+		}
+
+		parts[partNumber] = data
+
+		w.Header().Set("ETag", ETag(data))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var complete struct {
+			Parts []struct {
+				ETag       string `xml:"ETag"`
+				PartNumber int    `xml:"PartNumber"`
+			} `xml:"Part"`
+		}
+
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			t.Errorf("invalid complete multipart upload request: %s", err)
+		}
+
+		var data []byte
+
+		for _, part := range complete.Parts {
+			partData := s.uploads[query.Get("uploadId")][part.PartNumber]
+			if ETag(partData) != part.ETag {
+				t.Errorf("part %d ETag mismatch: expected %s, got %s", part.PartNumber, ETag(partData), part.ETag)
+			}
+
+			data = append(data, partData...)
+		}
+
+		delete(s.uploads, query.Get("uploadId"))
+		s.objects[key] = data
+
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+
+			return
+		}
+
+		s.objects[key] = data
+
+		w.Header().Set("ETag", ETag(data))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+
+			return
+		}
+
+		if presigned {
+			s.presignedGets++
+		}
+
+		w.Header().Set("Content-Type", "binary/octet-stream")
+
+		for header, param := range map[string]string{
+			"Content-Type":        "response-content-type",
+			"Content-Disposition": "response-content-disposition",
+		} {
+			if value := query.Get(param); value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+
+		w.Header().Set("ETag", ETag(data))
+		http.ServeContent(w, r, key, s.Modified, bytes.NewReader(data))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// copy handles the copy of the object, or the copy of the part of the multipart upload.
+func (s *Store) copy(w http.ResponseWriter, r *http.Request, key string) {
+	sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"), "/")
+
+	data, ok := s.objects[sourceKey]
+	if sourceBucket != Bucket || !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	if !query.Has("uploadId") {
+		s.objects[key] = slices.Clone(data)
+
+		fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag></CopyObjectResult>", ETag(data))
+
+		return
+	}
+
+	parts, ok := s.uploads[query.Get("uploadId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload")
+
+		return
+	}
+
+	var first, last int
+
+	if _, err := fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &first, &last); err != nil || first > last || last >= len(data) {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+
+		return
+	}
+
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgument")
+
+		return
+	}
+
+	parts[partNumber] = slices.Clone(data[first : last+1])
+
+	fmt.Fprintf(w, "<CopyPartResult><ETag>%s</ETag></CopyPartResult>", ETag(parts[partNumber]))
+}
+
+func (s *Store) list(w http.ResponseWriter, query url.Values) {
+	keys := slices.Sorted(func(yield func(string) bool) {
+		for key := range s.objects {
+			if strings.HasPrefix(key, query.Get("prefix")) && !yield(key) {
+				return
+			}
+		}
+	})
+
+	if token := query.Get("continuation-token"); token != "" {
+		keys = keys[slices.IndexFunc(keys, func(key string) bool { return key > token }):]
+	}
+
+	truncated := len(keys) > s.ListPageSize
+	if truncated {
+		keys = keys[:s.ListPageSize]
+	}
+
+	fmt.Fprint(w, "<ListBucketResult>")
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>%s</LastModified><ETag>%s</ETag><Size>%d</Size></Contents>",
+			key, s.Modified.UTC().Format(time.RFC3339), ETag(s.objects[key]), len(s.objects[key]))
+	}
+
+	if truncated {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", keys[len(keys)-1])
+	}
+
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+// Object returns the object contents by the full key.
+func (s *Store) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+
+	return data, ok
+}
+
+// PendingUploads returns the number of the multipart uploads which were neither completed nor aborted.
+func (s *Store) PendingUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.uploads)
+}
+
+// PresignedDownloads returns the number of the objects downloaded with the pre-signed URLs.
+func (s *Store) PresignedDownloads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.presignedGets
+}
+
+// ETag returns the ETag of the object (or the part) contents.
+func ETag(data []byte) string {
+	hash := md5.Sum(data) //nolint:gosec
+
+	return `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
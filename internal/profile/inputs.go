@@ -0,0 +1,32 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package profile
+
+// BuildInputs describes the inputs the asset is built from.
+//
+// BuildInputs are picked by EnhanceFromSchematicWithInputs, and recorded in the SBOMs and the provenance attestations of the assets.
+type BuildInputs struct {
+	Overlay      *InputImage
+	SchematicID  string
+	TalosVersion string
+	Arch         string
+	// ImagerRef is the digest reference of the imager image, it's resolved when the asset is built.
+	//
+	// It might be empty if the digest is unknown.
+	ImagerRef string
+	// BaseInstallerRef is the digest reference of the base installer image (only for installer images).
+	BaseInstallerRef string
+	Extensions       []InputImage
+}
+
+// InputImage is a container image used as an input of the build.
+type InputImage struct {
+	// Name is the name of the image, e.g. 'siderolabs/gvisor'.
+	Name string
+	// Ref is the full image reference.
+	Ref string
+	// Digest is the image digest.
+	Digest string
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/siderolabs/gen/value"
 	"github.com/siderolabs/gen/xerrors"
@@ -276,9 +277,11 @@ type ArtifactProducer interface {
 	GetOfficialExtensions(context.Context, string) ([]artifacts.ExtensionRef, error)
 	GetOfficialOverlays(context.Context, string) ([]artifacts.OverlayRef, error)
 	GetExtensionImage(context.Context, artifacts.Arch, artifacts.ExtensionRef) (string, error)
+	GetCustomExtensionImage(context.Context, artifacts.Arch, name.Digest) (string, error)
 	GetOverlayImage(context.Context, artifacts.Arch, artifacts.OverlayRef) (string, error)
 	GetOverlayArtifact(ctx context.Context, arch artifacts.Arch, ref artifacts.OverlayRef, kind artifacts.OverlayKind) (string, error)
 	GetInstallerImage(context.Context, artifacts.Arch, string) (string, error)
+	GetInstallerImageRef(context.Context, artifacts.Arch, string) (string, error)
 }
 
 func findExtension(availableExtensions []artifacts.ExtensionRef, extensionName string) artifacts.ExtensionRef {
@@ -291,6 +294,31 @@ func findExtension(availableExtensions []artifacts.ExtensionRef, extensionName s
 	return artifacts.ExtensionRef{}
 }
 
+// findOfficialExtension finds the official extension by name, taking into account the aliases.
+func findOfficialExtension(availableExtensions []artifacts.ExtensionRef, extensionName string) (artifacts.ExtensionRef, bool) {
+	extensionRef := findExtension(availableExtensions, extensionName)
+
+	if value.IsZero(extensionRef) {
+		// try with aliases if not found
+		if aliasedName, ok := extensionNameAlias(extensionName); ok {
+			extensionRef = findExtension(availableExtensions, aliasedName)
+		}
+	}
+
+	return extensionRef, !value.IsZero(extensionRef)
+}
+
+// findOverlay finds the official overlay by name.
+func findOverlay(availableOverlays []artifacts.OverlayRef, overlayName string) (artifacts.OverlayRef, bool) {
+	for _, availableOverlay := range availableOverlays {
+		if availableOverlay.Name == overlayName {
+			return availableOverlay, true
+		}
+	}
+
+	return artifacts.OverlayRef{}, false
+}
+
 func extensionNameAlias(extensionName string) (string, bool) {
 	switch extensionName {
 	case "siderolabs/v4l-uvc": // wrong name in the extension manifest
@@ -321,8 +349,6 @@ func extensionNameAlias(extensionName string) (string, bool) {
 }
 
 // EnhanceFromSchematic enhances the profile with the schematic.
-//
-//nolint:gocognit,gocyclo,cyclop,maintidx
 func EnhanceFromSchematic(
 	ctx context.Context,
 	prof profile.Profile,
@@ -331,16 +357,41 @@ func EnhanceFromSchematic(
 	secureBootService *secureboot.Service,
 	versionTag string,
 ) (profile.Profile, error) {
+	prof, _, err := EnhanceFromSchematicWithInputs(ctx, prof, "", schematic, artifactProducer, secureBootService, versionTag)
+
+	return prof, err
+}
+
+// EnhanceFromSchematicWithInputs enhances the profile with the schematic, and returns the build inputs picked for the profile.
+//
+// The imager reference is not resolved, as the profile doesn't refer to the imager, see BuildInputs.
+//
+//nolint:gocognit,gocyclo,cyclop,maintidx
+func EnhanceFromSchematicWithInputs(
+	ctx context.Context,
+	prof profile.Profile,
+	schematicID string,
+	schematic *schematicpkg.Schematic,
+	artifactProducer ArtifactProducer,
+	secureBootService *secureboot.Service,
+	versionTag string,
+) (profile.Profile, BuildInputs, error) {
 	metricsOnce.Do(initMetrics)
 
+	inputs := BuildInputs{
+		SchematicID:  schematicID,
+		TalosVersion: versionTag,
+		Arch:         prof.Arch,
+	}
+
 	if prof.SecureBootEnabled() {
 		secureBootAssets, err := secureBootService.GetSecureBootAssets()
 		if err != nil {
 			if errors.Is(err, secureboot.ErrDisabled) {
-				return prof, xerrors.NewTagged[InvalidErrorTag](err)
+				return prof, BuildInputs{}, xerrors.NewTagged[InvalidErrorTag](err)
 			}
 
-			return prof, err
+			return prof, BuildInputs{}, err
 		}
 
 		secureBootAssetsCopy := secureBootAssets.DeepCopy()
@@ -353,7 +404,7 @@ func EnhanceFromSchematic(
 	}
 
 	if schematic.Overlay.Name != "" && !quirks.New(versionTag).SupportsOverlay() {
-		return prof, xerrors.NewTaggedf[InvalidErrorTag]("overlay is not supported for Talos version %s", versionTag)
+		return prof, BuildInputs{}, xerrors.NewTaggedf[InvalidErrorTag]("overlay is not supported for Talos version %s", versionTag)
 	}
 
 	if prof.Output.Kind == profile.OutKindInstaller {
@@ -367,46 +418,75 @@ func EnhanceFromSchematic(
 			prof.Input.BaseInstaller.ImageRef = installerImage + ":" + versionTag // fake reference
 			prof.Input.BaseInstaller.OCIPath = installerImagePath
 		} else {
-			return prof, fmt.Errorf("failed to get base installer: %w", err)
+			return prof, BuildInputs{}, fmt.Errorf("failed to get base installer: %w", err)
 		}
+
+		baseInstallerRef, err := artifactProducer.GetInstallerImageRef(ctx, artifacts.Arch(prof.Arch), versionTag)
+		if err != nil {
+			return prof, BuildInputs{}, fmt.Errorf("error getting base installer reference: %w", err)
+		}
+
+		inputs.BaseInstallerRef = baseInstallerRef
 	}
 
 	if prof.Output.Kind != profile.OutKindCmdline && prof.Output.Kind != profile.OutKindKernel {
 		if len(schematic.Customization.SystemExtensions.OfficialExtensions) > 0 {
 			availableExtensions, err := artifactProducer.GetOfficialExtensions(ctx, versionTag)
 			if err != nil {
-				return prof, fmt.Errorf("error getting official extensions: %w", err)
+				return prof, BuildInputs{}, fmt.Errorf("error getting official extensions: %w", err)
 			}
 
 			for _, extensionName := range schematic.Customization.SystemExtensions.OfficialExtensions {
-				extensionRef := findExtension(availableExtensions, extensionName)
-
-				if value.IsZero(extensionRef) {
-					// try with aliases if not found
-					if aliasedName, ok := extensionNameAlias(extensionName); ok {
-						extensionRef = findExtension(availableExtensions, aliasedName)
-					}
-				}
-
-				if value.IsZero(extensionRef) {
-					return prof, xerrors.NewTaggedf[InvalidErrorTag]("official extension %q is not available for Talos version %s", extensionName, versionTag)
+				extensionRef, ok := findOfficialExtension(availableExtensions, extensionName)
+				if !ok {
+					return prof, BuildInputs{}, xerrors.NewTaggedf[InvalidErrorTag]("official extension %q is not available for Talos version %s", extensionName, versionTag)
 				}
 
 				imagePath, err := artifactProducer.GetExtensionImage(ctx, artifacts.Arch(prof.Arch), extensionRef)
 				if err != nil {
-					return prof, fmt.Errorf("error getting extension image %s: %w", extensionRef.TaggedReference, err)
+					return prof, BuildInputs{}, fmt.Errorf("error getting extension image %s: %w", extensionRef.TaggedReference, err)
 				}
 
 				metricSystemExtensionHit.WithLabelValues(extensionName).Inc()
 
 				prof.Input.SystemExtensions = append(prof.Input.SystemExtensions, profile.ContainerAsset{OCIPath: imagePath})
+
+				inputs.Extensions = append(inputs.Extensions, InputImage{
+					Name:   extensionRef.TaggedReference.RepositoryStr(),
+					Ref:    extensionRef.TaggedReference.String(),
+					Digest: extensionRef.Digest,
+				})
+			}
+		}
+
+		for _, extensionImage := range schematic.Customization.SystemExtensions.CustomExtensions {
+			extensionRef, err := name.NewDigest(extensionImage)
+			if err != nil {
+				return prof, BuildInputs{}, xerrors.NewTaggedf[InvalidErrorTag]("custom extension %q should be an image reference pinned by digest: %s", extensionImage, err)
+			}
+
+			imagePath, err := artifactProducer.GetCustomExtensionImage(ctx, artifacts.Arch(prof.Arch), extensionRef)
+			if err != nil {
+				if errors.Is(err, artifacts.ErrCustomExtensionsDisabled) {
+					return prof, BuildInputs{}, xerrors.NewTagged[InvalidErrorTag](err)
+				}
+
+				return prof, BuildInputs{}, fmt.Errorf("error getting custom extension image %s: %w", extensionRef, err)
 			}
+
+			prof.Input.SystemExtensions = append(prof.Input.SystemExtensions, profile.ContainerAsset{OCIPath: imagePath})
+
+			inputs.Extensions = append(inputs.Extensions, InputImage{
+				Name:   extensionRef.Context().RepositoryStr(),
+				Ref:    extensionRef.String(),
+				Digest: extensionRef.DigestStr(),
+			})
 		}
 
 		// append schematic extension
 		schematicExtensionPath, err := artifactProducer.GetSchematicExtension(ctx, versionTag, schematic)
 		if err != nil {
-			return prof, err
+			return prof, BuildInputs{}, err
 		}
 
 		prof.Input.SystemExtensions = append(prof.Input.SystemExtensions, profile.ContainerAsset{TarballPath: schematicExtensionPath})
@@ -414,35 +494,26 @@ func EnhanceFromSchematic(
 		if schematic.Overlay.Name != "" {
 			availableOverlays, err := artifactProducer.GetOfficialOverlays(ctx, versionTag)
 			if err != nil {
-				return prof, fmt.Errorf("error getting official overlays: %w", err)
-			}
-
-			var overlayRef artifacts.OverlayRef
-
-			for _, availableOverlay := range availableOverlays {
-				if availableOverlay.Name == schematic.Overlay.Name {
-					overlayRef = availableOverlay
-
-					break
-				}
+				return prof, BuildInputs{}, fmt.Errorf("error getting official overlays: %w", err)
 			}
 
-			if value.IsZero(overlayRef) {
-				return prof, xerrors.NewTaggedf[InvalidErrorTag]("official overlay %q is not available for Talos version %s", overlayRef, versionTag)
+			overlayRef, ok := findOverlay(availableOverlays, schematic.Overlay.Name)
+			if !ok {
+				return prof, BuildInputs{}, xerrors.NewTaggedf[InvalidErrorTag]("official overlay %q is not available for Talos version %s", schematic.Overlay.Name, versionTag)
 			}
 
 			imageNativePath, err := artifactProducer.GetOverlayImage(ctx, artifacts.Arch(runtime.GOARCH), overlayRef)
 			if err != nil {
-				return prof, fmt.Errorf("error getting extension image %s: %w", overlayRef.TaggedReference, err)
+				return prof, BuildInputs{}, fmt.Errorf("error getting extension image %s: %w", overlayRef.TaggedReference, err)
 			}
 
 			imageTargetPath, err := artifactProducer.GetOverlayImage(ctx, artifacts.Arch(prof.Arch), overlayRef)
 			if err != nil {
-				return prof, fmt.Errorf("error getting extension image %s: %w", overlayRef.TaggedReference, err)
+				return prof, BuildInputs{}, fmt.Errorf("error getting extension image %s: %w", overlayRef.TaggedReference, err)
 			}
 
 			if err := MergeOverlayProfile(ctx, artifactProducer, &prof, overlayRef); err != nil {
-				return prof, fmt.Errorf("error merging overlay profile: %w", err)
+				return prof, BuildInputs{}, fmt.Errorf("error merging overlay profile: %w", err)
 			}
 
 			metricSystemExtensionHit.WithLabelValues(schematic.Overlay.Name).Inc()
@@ -454,6 +525,12 @@ func EnhanceFromSchematic(
 			}
 
 			prof.Input.OverlayInstaller = profile.ContainerAsset{OCIPath: imageTargetPath}
+
+			inputs.Overlay = &InputImage{
+				Name:   overlayRef.Name,
+				Ref:    overlayRef.TaggedReference.String(),
+				Digest: overlayRef.Digest,
+			}
 		}
 	}
 
@@ -485,7 +562,7 @@ func EnhanceFromSchematic(
 
 	prof.Version = versionTag
 
-	return prof, nil
+	return prof, inputs, nil
 }
 
 // MergeOverlayProfile merges the overlay profile into the main profile.
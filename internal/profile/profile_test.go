@@ -7,10 +7,12 @@ package profile_test
 import (
 	"fmt"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/siderolabs/gen/ensure"
+	"github.com/siderolabs/gen/xerrors"
 	"github.com/siderolabs/go-pointer"
 	"github.com/siderolabs/talos/pkg/imager/profile"
 	"github.com/siderolabs/talos/pkg/machinery/constants"
@@ -398,6 +400,10 @@ func (mockArtifactProducer) GetExtensionImage(_ context.Context, arch artifacts.
 	return fmt.Sprintf("%s-%s.oci", arch, ref.Digest), nil
 }
 
+func (mockArtifactProducer) GetCustomExtensionImage(_ context.Context, arch artifacts.Arch, ref name.Digest) (string, error) {
+	return fmt.Sprintf("%s-custom-%s.oci", arch, ref.DigestStr()), nil
+}
+
 func (mockArtifactProducer) GetOverlayImage(_ context.Context, arch artifacts.Arch, ref artifacts.OverlayRef) (string, error) {
 	return fmt.Sprintf("%s-%s.oci", arch, ref.Digest), nil
 }
@@ -406,6 +412,10 @@ func (mockArtifactProducer) GetInstallerImage(_ context.Context, arch artifacts.
 	return fmt.Sprintf("installer-%s-%s.oci", arch, tag), nil
 }
 
+func (mockArtifactProducer) GetInstallerImageRef(_ context.Context, arch artifacts.Arch, tag string) (string, error) {
+	return fmt.Sprintf("ghcr.io/siderolabs/installer-base@sha256:installer-%s-%s", arch, tag), nil
+}
+
 func (mockArtifactProducer) GetOverlayArtifact(_ context.Context, _ artifacts.Arch, ref artifacts.OverlayRef, kind artifacts.OverlayKind) (string, error) {
 	if ref.Name != "rpi_generic" {
 		return "", fmt.Errorf("unsupported overlay name: %s", ref.Name)
@@ -483,6 +493,51 @@ func TestEnhanceFromSchematic(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "custom extensions",
+			baseProfile: baseProfile,
+			schematic: schematic.Schematic{
+				Customization: schematic.Customization{
+					SystemExtensions: schematic.SystemExtensions{
+						OfficialExtensions: []string{
+							"siderolabs/amd-ucode",
+						},
+						CustomExtensions: []string{
+							"registry.example.com/extensions/my-driver:1.0.0@sha256:a7ae30ba8d5d21e2ba51dc9237b5e2d3d3b5b6e1c4e5d0a0f1c3bd2b5ac0e3f6",
+						},
+					},
+				},
+			},
+			versionString: "v1.7.0",
+
+			expectedProfile: profile.Profile{
+				Platform:   constants.PlatformMetal,
+				SecureBoot: pointer.To(false),
+				Arch:       "amd64",
+				Version:    "v1.7.0",
+				Input: profile.Input{
+					SystemExtensions: []profile.ContainerAsset{
+						{
+							OCIPath: "amd64-sha256:1234567890.oci",
+						},
+						{
+							OCIPath: "amd64-custom-sha256:a7ae30ba8d5d21e2ba51dc9237b5e2d3d3b5b6e1c4e5d0a0f1c3bd2b5ac0e3f6.oci",
+						},
+						{
+							TarballPath: "6e1e8b9e8d78acc6bdf20029d4d050717afb34c02879d613be44bfe2a10b8203.tar",
+						},
+					},
+				},
+				Output: profile.Output{
+					Kind:      profile.OutKindImage,
+					OutFormat: profile.OutFormatZSTD,
+					ImageOptions: &profile.ImageOptions{
+						DiskSize:   profile.MinRAWDiskSize,
+						DiskFormat: profile.DiskFormatRaw,
+					},
+				},
+			},
+		},
 		{
 			name:        "extra kernel args",
 			baseProfile: baseProfile,
@@ -992,6 +1047,107 @@ func TestEnhanceFromSchematic(t *testing.T) {
 	}
 }
 
+func TestEnhanceFromSchematicCustomExtensionNotPinned(t *testing.T) {
+	t.Parallel()
+
+	baseProfile := profile.Default[constants.PlatformMetal].DeepCopy()
+	baseProfile.Arch = "amd64"
+
+	secureBootService, err := secureboot.NewService(secureboot.Options{})
+	require.NoError(t, err)
+
+	_, err = imageprofile.EnhanceFromSchematic(t.Context(), baseProfile, &schematic.Schematic{
+		Customization: schematic.Customization{
+			SystemExtensions: schematic.SystemExtensions{
+				CustomExtensions: []string{
+					"registry.example.com/extensions/my-driver:1.0.0",
+				},
+			},
+		},
+	}, mockArtifactProducer{}, secureBootService, "v1.7.0")
+	require.Error(t, err)
+	require.True(t, xerrors.TagIs[imageprofile.InvalidErrorTag](err))
+}
+
+func TestEnhanceFromSchematicWithInputs(t *testing.T) {
+	t.Parallel()
+
+	baseProfile := profile.Default[constants.PlatformMetal].DeepCopy()
+	baseProfile.Arch = "arm64"
+
+	kernelProfile := baseProfile.DeepCopy()
+	kernelProfile.Output.Kind = profile.OutKindKernel
+
+	testSchematic := &schematic.Schematic{
+		Overlay: schematic.Overlay{
+			Name:  "rpi_generic",
+			Image: "siderolabs/sbc-raspberrypi",
+		},
+		Customization: schematic.Customization{
+			SystemExtensions: schematic.SystemExtensions{
+				OfficialExtensions: []string{"siderolabs/amd-ucode", "siderolabs/gasket"},
+				CustomExtensions:   []string{"registry.example.com/custom/ext@sha256:" + strings.Repeat("a", 64)},
+			},
+		},
+	}
+
+	_, inputs, err := imageprofile.EnhanceFromSchematicWithInputs(t.Context(), baseProfile, "abcd", testSchematic, mockArtifactProducer{}, nil, "v1.7.0")
+	require.NoError(t, err)
+
+	require.Equal(t, imageprofile.BuildInputs{
+		SchematicID:  "abcd",
+		TalosVersion: "v1.7.0",
+		Arch:         "arm64",
+		Extensions: []imageprofile.InputImage{
+			{
+				Name:   "siderolabs/amd-ucode",
+				Ref:    "ghcr.io/siderolabs/amd-ucode:2023048",
+				Digest: "sha256:1234567890",
+			},
+			{
+				Name:   "siderolabs/gasket-driver",
+				Ref:    "ghcr.io/siderolabs/gasket-driver:20240101",
+				Digest: "sha256:abcdef123456",
+			},
+			{
+				Name:   "custom/ext",
+				Ref:    "registry.example.com/custom/ext@sha256:" + strings.Repeat("a", 64),
+				Digest: "sha256:" + strings.Repeat("a", 64),
+			},
+		},
+		Overlay: &imageprofile.InputImage{
+			Name:   "rpi_generic",
+			Ref:    "ghcr.io/siderolabs/sbc-raspberrypi:v0.1.0",
+			Digest: "sha256:abcdef123456",
+		},
+	}, inputs)
+
+	// kernel doesn't include extensions & overlays
+	_, inputs, err = imageprofile.EnhanceFromSchematicWithInputs(t.Context(), kernelProfile, "abcd", testSchematic, mockArtifactProducer{}, nil, "v1.7.0")
+	require.NoError(t, err)
+
+	require.Empty(t, inputs.Extensions)
+	require.Nil(t, inputs.Overlay)
+
+	// installer records the base installer image
+	_, inputs, err = imageprofile.EnhanceFromSchematicWithInputs(
+		t.Context(), imageprofile.InstallerProfile(false, artifacts.ArchArm64, "metal"), "abcd", testSchematic, mockArtifactProducer{}, nil, "v1.7.0",
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, "ghcr.io/siderolabs/installer-base@sha256:installer-arm64-v1.7.0", inputs.BaseInstallerRef)
+
+	_, _, err = imageprofile.EnhanceFromSchematicWithInputs(t.Context(), baseProfile, "abcd", &schematic.Schematic{
+		Customization: schematic.Customization{
+			SystemExtensions: schematic.SystemExtensions{
+				OfficialExtensions: []string{"siderolabs/unknown"},
+			},
+		},
+	}, mockArtifactProducer{}, nil, "v1.7.0")
+	require.Error(t, err)
+	require.True(t, xerrors.TagIs[imageprofile.InvalidErrorTag](err))
+}
+
 func TestInstallerProfile(t *testing.T) {
 	t.Parallel()
 
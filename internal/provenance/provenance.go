@@ -0,0 +1,227 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package provenance generates SLSA provenance for the images built by the Image Factory.
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/version"
+	"github.com/siderolabs/image-factory/pkg/schematic"
+)
+
+// PredicateType is the SLSA provenance v1 predicate type.
+const PredicateType = "https://slsa.dev/provenance/v1"
+
+// StatementType is the in-toto statement v1 type.
+const StatementType = "https://in-toto.io/Statement/v1"
+
+// BuildType identifies the installer image build process.
+const BuildType = "https://github.com/siderolabs/image-factory/buildtypes/installer/v1"
+
+// Subject is the artifact the provenance is generated for.
+type Subject struct {
+	Name   string
+	Digest v1.Hash
+}
+
+// Build describes the installer image build.
+type Build struct {
+	StartedOn    time.Time
+	FinishedOn   time.Time
+	Schematic    *schematic.Schematic
+	SchematicID  string
+	TalosVersion string
+	// Image is the name of the installer image, e.g. 'metal-installer'.
+	Image string
+	// BuilderID is the URI of the Image Factory instance.
+	BuilderID string
+	// Inputs are the build inputs, one per architecture.
+	Inputs []profile.BuildInputs
+}
+
+type statement struct {
+	Type          string          `json:"_type"`
+	PredicateType string          `json:"predicateType"`
+	Subject       []subject       `json:"subject"`
+	Predicate     predicateSLSAv1 `json:"predicate"`
+}
+
+type subject struct {
+	Digest map[string]string `json:"digest"`
+	Name   string            `json:"name"`
+}
+
+type predicateSLSAv1 struct {
+	BuildDefinition buildDefinition `json:"buildDefinition"`
+	RunDetails      runDetails      `json:"runDetails"`
+}
+
+type buildDefinition struct {
+	ExternalParameters   externalParameters   `json:"externalParameters"`
+	BuildType            string               `json:"buildType"`
+	ResolvedDependencies []resourceDescriptor `json:"resolvedDependencies"`
+}
+
+type externalParameters struct {
+	Schematic    *schematic.Schematic `json:"schematic"`
+	Image        string               `json:"image"`
+	SchematicID  string               `json:"schematicId"`
+	TalosVersion string               `json:"talosVersion"`
+}
+
+type resourceDescriptor struct {
+	Digest      map[string]string `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Name        string            `json:"name"`
+	URI         string            `json:"uri"`
+}
+
+type runDetails struct {
+	Builder  builder  `json:"builder"`
+	Metadata metadata `json:"metadata"`
+}
+
+type builder struct {
+	Version map[string]string `json:"version"`
+	ID      string            `json:"id"`
+}
+
+type metadata struct {
+	StartedOn  string `json:"startedOn"`
+	FinishedOn string `json:"finishedOn"`
+}
+
+// Generate generates the in-toto statement with the SLSA provenance predicate for the subjects.
+func Generate(subjects []Subject, build Build) ([]byte, error) {
+	stmt := statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate: predicateSLSAv1{
+			BuildDefinition: buildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: externalParameters{
+					Schematic:    build.Schematic,
+					Image:        build.Image,
+					SchematicID:  build.SchematicID,
+					TalosVersion: build.TalosVersion,
+				},
+				ResolvedDependencies: []resourceDescriptor{},
+			},
+			RunDetails: runDetails{
+				Builder: builder{
+					ID: build.BuilderID,
+					Version: map[string]string{
+						"image-factory": version.Tag,
+					},
+				},
+				Metadata: metadata{
+					StartedOn:  build.StartedOn.UTC().Format(time.RFC3339),
+					FinishedOn: build.FinishedOn.UTC().Format(time.RFC3339),
+				},
+			},
+		},
+	}
+
+	for _, s := range subjects {
+		stmt.Subject = append(stmt.Subject, subject{
+			Name:   s.Name,
+			Digest: map[string]string{s.Digest.Algorithm: s.Digest.Hex},
+		})
+	}
+
+	for _, inputs := range build.Inputs {
+		dependencies, err := resolvedDependencies(inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.Predicate.BuildDefinition.ResolvedDependencies = append(stmt.Predicate.BuildDefinition.ResolvedDependencies, dependencies...)
+	}
+
+	return json.Marshal(stmt)
+}
+
+// resolvedDependencies lists the images the build for a single architecture depends on.
+func resolvedDependencies(inputs profile.BuildInputs) ([]resourceDescriptor, error) {
+	var dependencies []resourceDescriptor
+
+	for _, dep := range []struct {
+		role string
+		ref  string
+	}{
+		{role: "base-installer", ref: inputs.BaseInstallerRef},
+		{role: "imager", ref: inputs.ImagerRef},
+	} {
+		if dep.ref == "" {
+			continue
+		}
+
+		ref, err := name.NewDigest(dep.ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s reference: %w", dep.role, err)
+		}
+
+		descriptor, err := imageDescriptor(ref.Context(), ref.DigestStr(), dep.role, inputs.Arch)
+		if err != nil {
+			return nil, err
+		}
+
+		dependencies = append(dependencies, descriptor)
+	}
+
+	images := inputs.Extensions
+
+	if inputs.Overlay != nil {
+		images = append(images[:len(images):len(images)], *inputs.Overlay)
+	}
+
+	for i, image := range images {
+		ref, err := name.ParseReference(image.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image reference %q: %w", image.Ref, err)
+		}
+
+		role := "extension"
+
+		if i == len(inputs.Extensions) {
+			role = "overlay"
+		}
+
+		descriptor, err := imageDescriptor(ref.Context(), image.Digest, role, inputs.Arch)
+		if err != nil {
+			return nil, err
+		}
+
+		dependencies = append(dependencies, descriptor)
+	}
+
+	return dependencies, nil
+}
+
+func imageDescriptor(repo name.Repository, digest, role, arch string) (resourceDescriptor, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return resourceDescriptor{}, fmt.Errorf("failed to parse %s digest %q: %w", role, digest, err)
+	}
+
+	return resourceDescriptor{
+		Name: repo.RepositoryStr(),
+		URI:  repo.Digest(digest).String(),
+		Digest: map[string]string{
+			hash.Algorithm: hash.Hex,
+		},
+		Annotations: map[string]string{
+			"role": role,
+			"arch": arch,
+		},
+	}, nil
+}
@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package provenance_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/provenance"
+	"github.com/siderolabs/image-factory/pkg/schematic"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	digest := func(c string) string {
+		return "sha256:" + strings.Repeat(c, 64)
+	}
+
+	data, err := provenance.Generate(
+		[]provenance.Subject{
+			{
+				Name:   "metal-installer/abcd:v1.10.2",
+				Digest: v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)},
+			},
+		},
+		provenance.Build{
+			StartedOn:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			FinishedOn: time.Date(2025, 1, 2, 3, 5, 5, 0, time.UTC),
+			Schematic: &schematic.Schematic{
+				Customization: schematic.Customization{
+					SystemExtensions: schematic.SystemExtensions{
+						OfficialExtensions: []string{"siderolabs/gvisor"},
+					},
+				},
+			},
+			SchematicID:  "abcd",
+			TalosVersion: "v1.10.2",
+			Image:        "metal-installer",
+			BuilderID:    "https://factory.talos.dev",
+			Inputs: []profile.BuildInputs{
+				{
+					Arch:             "amd64",
+					ImagerRef:        "ghcr.io/siderolabs/imager@" + digest("b"),
+					BaseInstallerRef: "ghcr.io/siderolabs/installer-base@" + digest("c"),
+					Extensions: []profile.InputImage{
+						{
+							Name:   "siderolabs/gvisor",
+							Ref:    "ghcr.io/siderolabs/gvisor:20231214.0-v1.10.2",
+							Digest: digest("d"),
+						},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	var statement struct {
+		Type          string `json:"_type"`
+		PredicateType string `json:"predicateType"`
+		Subject       []struct {
+			Digest map[string]string `json:"digest"`
+			Name   string            `json:"name"`
+		} `json:"subject"`
+		Predicate struct {
+			BuildDefinition struct {
+				ExternalParameters struct {
+					Schematic    schematic.Schematic `json:"schematic"`
+					SchematicID  string              `json:"schematicId"`
+					TalosVersion string              `json:"talosVersion"`
+				} `json:"externalParameters"`
+				ResolvedDependencies []struct {
+					Digest      map[string]string `json:"digest"`
+					Annotations map[string]string `json:"annotations"`
+					URI         string            `json:"uri"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Builder struct {
+					ID string `json:"id"`
+				} `json:"builder"`
+				Metadata struct {
+					StartedOn  string `json:"startedOn"`
+					FinishedOn string `json:"finishedOn"`
+				} `json:"metadata"`
+			} `json:"runDetails"`
+		} `json:"predicate"`
+	}
+
+	require.NoError(t, json.Unmarshal(data, &statement))
+
+	assert.Equal(t, provenance.StatementType, statement.Type)
+	assert.Equal(t, provenance.PredicateType, statement.PredicateType)
+
+	require.Len(t, statement.Subject, 1)
+	assert.Equal(t, map[string]string{"sha256": strings.Repeat("a", 64)}, statement.Subject[0].Digest)
+
+	assert.Equal(t, "abcd", statement.Predicate.BuildDefinition.ExternalParameters.SchematicID)
+	assert.Equal(t, []string{"siderolabs/gvisor"}, statement.Predicate.BuildDefinition.ExternalParameters.Schematic.Customization.SystemExtensions.OfficialExtensions)
+
+	deps := statement.Predicate.BuildDefinition.ResolvedDependencies
+	require.Len(t, deps, 3)
+
+	assert.Equal(t, "base-installer", deps[0].Annotations["role"])
+	assert.Equal(t, "ghcr.io/siderolabs/installer-base@"+digest("c"), deps[0].URI)
+	assert.Equal(t, "imager", deps[1].Annotations["role"])
+	assert.Equal(t, "extension", deps[2].Annotations["role"])
+	assert.Equal(t, map[string]string{"sha256": strings.Repeat("d", 64)}, deps[2].Digest)
+	assert.Equal(t, "ghcr.io/siderolabs/gvisor@"+digest("d"), deps[2].URI)
+
+	assert.Equal(t, "https://factory.talos.dev", statement.Predicate.RunDetails.Builder.ID)
+	assert.Equal(t, "2025-01-02T03:04:05Z", statement.Predicate.RunDetails.Metadata.StartedOn)
+	assert.Equal(t, "2025-01-02T03:05:05Z", statement.Predicate.RunDetails.Metadata.FinishedOn)
+}
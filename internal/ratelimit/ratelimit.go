@@ -0,0 +1,248 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package ratelimit implements per-client rate limiting of the Image Factory requests and builds.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// Budget is the kind of the rate limited operation.
+type Budget string
+
+// Budgets.
+const (
+	// BudgetRequests is charged for every request (cached assets, metadata, etc.).
+	BudgetRequests Budget = "requests"
+	// BudgetBuilds is charged for every fresh build (asset missing in the cache).
+	BudgetBuilds Budget = "builds"
+)
+
+// pruneInterval is the interval to forget the idle clients.
+const pruneInterval = time.Minute
+
+// Limit configures the token bucket of the budget.
+type Limit struct {
+	// Every is the interval to refill a single token, zero disables the limit.
+	Every time.Duration
+	// Burst is the maximum number of tokens.
+	Burst int
+}
+
+func (limit Limit) enabled() bool {
+	return limit.Every > 0 && limit.Burst > 0
+}
+
+// Options configures the rate limiter.
+type Options struct {
+	Requests Limit
+	Builds   Limit
+}
+
+// Enabled returns true if any of the limits is enabled.
+func (options Options) Enabled() bool {
+	return options.Requests.enabled() || options.Builds.enabled()
+}
+
+// ExceededError is returned when the client is over the budget.
+type ExceededError struct {
+	Budget     Budget
+	RetryAfter time.Duration
+}
+
+// Error implements error interface.
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry after %s", e.Budget, e.RetryAfter.Round(time.Second))
+}
+
+// Limiter rate limits the clients.
+type Limiter struct {
+	clients   map[string]*clientLimiters
+	lastPrune time.Time
+
+	metricLimit, metricBurst *prometheus.GaugeVec
+	metricClients            prometheus.Gauge
+	metricRequests           *prometheus.CounterVec
+
+	options Options
+	mu      sync.Mutex
+}
+
+type clientLimiters struct {
+	lastSeen time.Time
+	requests *rate.Limiter
+	builds   *rate.Limiter
+}
+
+// New creates a new rate limiter.
+func New(options Options) *Limiter {
+	l := &Limiter{
+		options: options,
+		clients: map[string]*clientLimiters{},
+		metricLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "image_factory_rate_limit_per_second",
+			Help: "Configured rate limit (tokens per second) per client.",
+		}, []string{"budget"}),
+		metricBurst: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "image_factory_rate_limit_burst",
+			Help: "Configured rate limit burst per client.",
+		}, []string{"budget"}),
+		metricClients: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "image_factory_rate_limit_clients",
+			Help: "Number of clients tracked by the rate limiter.",
+		}),
+		metricRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "image_factory_rate_limit_requests_total",
+			Help: "Number of operations checked by the rate limiter.",
+		}, []string{"budget", "result"}),
+	}
+
+	for budget, limit := range map[Budget]Limit{BudgetRequests: options.Requests, BudgetBuilds: options.Builds} {
+		if !limit.enabled() {
+			continue
+		}
+
+		l.metricLimit.WithLabelValues(string(budget)).Set(float64(rate.Every(limit.Every)))
+		l.metricBurst.WithLabelValues(string(budget)).Set(float64(limit.Burst))
+	}
+
+	return l
+}
+
+// Allow charges the client budget, returning *ExceededError if the client is over the budget.
+func (l *Limiter) Allow(key string, budget Budget) error {
+	limit := l.options.Requests
+	if budget == BudgetBuilds {
+		limit = l.options.Builds
+	}
+
+	if !limit.enabled() {
+		return nil
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+
+	l.pruneLocked(now)
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientLimiters{
+			requests: newLimiter(l.options.Requests),
+			builds:   newLimiter(l.options.Builds),
+		}
+
+		l.clients[key] = client
+		l.metricClients.Set(float64(len(l.clients)))
+	}
+
+	client.lastSeen = now
+
+	limiter := client.requests
+	if budget == BudgetBuilds {
+		limiter = client.builds
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+
+	if delay > 0 {
+		// don't consume the token, the request is rejected
+		reservation.CancelAt(now)
+	}
+
+	l.mu.Unlock()
+
+	if delay > 0 {
+		l.metricRequests.WithLabelValues(string(budget), "limited").Inc()
+
+		return &ExceededError{
+			Budget:     budget,
+			RetryAfter: delay,
+		}
+	}
+
+	l.metricRequests.WithLabelValues(string(budget), "allowed").Inc()
+
+	return nil
+}
+
+// AdmitBuild charges the build budget of the client from the context.
+//
+// Builds without the client in the context (e.g. background builds) are always admitted.
+func (l *Limiter) AdmitBuild(ctx context.Context) error {
+	key, ok := ClientFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return l.Allow(key, BudgetBuilds)
+}
+
+func newLimiter(limit Limit) *rate.Limiter {
+	if !limit.enabled() {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	return rate.NewLimiter(rate.Every(limit.Every), limit.Burst)
+}
+
+// pruneLocked forgets the clients which were idle long enough for their buckets to be refilled.
+func (l *Limiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+
+	l.lastPrune = now
+
+	idleTimeout := max(
+		l.options.Requests.Every*time.Duration(l.options.Requests.Burst),
+		l.options.Builds.Every*time.Duration(l.options.Builds.Burst),
+	)
+
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) > idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+
+	l.metricClients.Set(float64(len(l.clients)))
+}
+
+// Describe implements prom.Collector interface.
+func (l *Limiter) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(l, ch)
+}
+
+// Collect implements prom.Collector interface.
+func (l *Limiter) Collect(ch chan<- prometheus.Metric) {
+	l.metricLimit.Collect(ch)
+	l.metricBurst.Collect(ch)
+	l.metricClients.Collect(ch)
+	l.metricRequests.Collect(ch)
+}
+
+var _ prometheus.Collector = &Limiter{}
+
+type clientKey struct{}
+
+// WithClient returns the context carrying the rate limiting key of the client.
+func WithClient(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, clientKey{}, key)
+}
+
+// ClientFromContext returns the rate limiting key of the client from the context.
+func ClientFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(clientKey{}).(string)
+
+	return key, ok
+}
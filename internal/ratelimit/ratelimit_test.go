@@ -0,0 +1,74 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := ratelimit.New(ratelimit.Options{
+		Requests: ratelimit.Limit{Every: time.Hour, Burst: 3},
+		Builds:   ratelimit.Limit{Every: time.Hour, Burst: 1},
+	})
+
+	for range 3 {
+		require.NoError(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetRequests))
+	}
+
+	var exceeded *ratelimit.ExceededError
+
+	require.ErrorAs(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetRequests), &exceeded)
+	assert.Equal(t, ratelimit.BudgetRequests, exceeded.Budget)
+	assert.InDelta(t, time.Hour.Seconds(), exceeded.RetryAfter.Seconds(), 1)
+
+	// builds have a separate budget
+	require.NoError(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetBuilds))
+	require.Error(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetBuilds))
+
+	// other clients are not affected
+	require.NoError(t, limiter.Allow("ip:10.0.0.2", ratelimit.BudgetRequests))
+	require.NoError(t, limiter.Allow("ip:10.0.0.2", ratelimit.BudgetBuilds))
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	t.Parallel()
+
+	limiter := ratelimit.New(ratelimit.Options{
+		Builds: ratelimit.Limit{Every: time.Hour, Burst: 1},
+	})
+
+	for range 100 {
+		require.NoError(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetRequests))
+	}
+
+	require.NoError(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetBuilds))
+	require.Error(t, limiter.Allow("ip:10.0.0.1", ratelimit.BudgetBuilds))
+}
+
+func TestAdmitBuild(t *testing.T) {
+	t.Parallel()
+
+	limiter := ratelimit.New(ratelimit.Options{
+		Builds: ratelimit.Limit{Every: time.Hour, Burst: 1},
+	})
+
+	ctx := ratelimit.WithClient(t.Context(), "token:ci")
+
+	require.NoError(t, limiter.AdmitBuild(ctx))
+	require.Error(t, limiter.AdmitBuild(ctx))
+
+	// background builds are not limited
+	require.NoError(t, limiter.AdmitBuild(t.Context()))
+	require.NoError(t, limiter.AdmitBuild(t.Context()))
+}
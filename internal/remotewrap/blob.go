@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package remotewrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrtransport "github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// BlobRangeFetcher fetches byte ranges of the blobs from the registry.
+type BlobRangeFetcher interface {
+	// BlobRange returns the reader of the blob starting at the offset.
+	//
+	// The reader returns at least length bytes (unless the blob is shorter), the caller should stop reading after that.
+	BlobRange(ctx context.Context, ref name.Digest, offset, length int64) (io.ReadCloser, error)
+}
+
+type blobRangeFetcher struct {
+	keychain        authn.Keychain
+	transports      map[string]cachedTransport
+	refreshInterval time.Duration
+	mu              sync.Mutex
+}
+
+type cachedTransport struct {
+	created time.Time
+	rt      http.RoundTripper
+}
+
+// NewBlobRangeFetcher creates a new BlobRangeFetcher which authenticates with the given keychain.
+//
+// The authenticated transports are reused for the same repository, and recreated with the given interval, like the Puller.
+// The keychain should be the one of the remote options of the Puller for the same registry.
+func NewBlobRangeFetcher(refreshInterval time.Duration, keychain authn.Keychain) BlobRangeFetcher {
+	if keychain == nil {
+		keychain = authn.DefaultKeychain
+	}
+
+	return &blobRangeFetcher{
+		keychain:        keychain,
+		transports:      map[string]cachedTransport{},
+		refreshInterval: refreshInterval,
+	}
+}
+
+// transport returns the authenticated transport for the repository, the failures to create it are not cached.
+func (f *blobRangeFetcher) transport(ctx context.Context, repo name.Repository) (http.RoundTripper, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cached, ok := f.transports[repo.Name()]; ok && time.Since(cached.created) < f.refreshInterval {
+		return cached.rt, nil
+	}
+
+	auth, err := authn.Resolve(ctx, f.keychain, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials: %w", err)
+	}
+
+	rt, err := gcrtransport.NewWithContext(ctx, repo.Registry, auth, transport(), []string{repo.Scope(gcrtransport.PullScope)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry transport: %w", err)
+	}
+
+	f.transports[repo.Name()] = cachedTransport{
+		created: time.Now(),
+		rt:      rt,
+	}
+
+	return rt, nil
+}
+
+// BlobRange implements BlobRangeFetcher.
+//
+// If the registry doesn't support ranged requests, the beginning of the blob is skipped.
+func (f *blobRangeFetcher) BlobRange(ctx context.Context, ref name.Digest, offset, length int64) (io.ReadCloser, error) {
+	repo := ref.Context()
+
+	rt, err := f.transport(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	u := url.URL{
+		Scheme: repo.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/blobs/%s", repo.RepositoryStr(), ref.DigestStr()),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = gcrtransport.CheckError(resp, http.StatusOK, http.StatusPartialContent); err != nil {
+		resp.Body.Close() //nolint:errcheck
+
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		// the registry ignored the range, skip to the offset
+		if _, err = io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close() //nolint:errcheck
+
+			return nil, fmt.Errorf("failed to skip to the offset: %w", err)
+		}
+	}
+
+	return resp.Body, nil
+}
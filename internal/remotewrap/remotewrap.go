@@ -35,6 +35,7 @@ func ShutdownTransport() {
 // Pusher is an interface which is implemented by go-containerregistry's *remote.Pusher.
 type Pusher interface {
 	Push(ctx context.Context, ref name.Reference, t remote.Taggable) error
+	Delete(ctx context.Context, ref name.Reference) error
 }
 
 type pusherWrapper struct {
@@ -50,6 +51,15 @@ func (p *pusherWrapper) Push(ctx context.Context, ref name.Reference, t remote.T
 	return instance.Push(ctx, ref, t)
 }
 
+func (p *pusherWrapper) Delete(ctx context.Context, ref name.Reference) error {
+	instance, err := p.refresher.Get()
+	if err != nil {
+		return err
+	}
+
+	return instance.Delete(ctx, ref)
+}
+
 // NewPusher creates a new Pusher with the given options.
 func NewPusher(refreshInterval time.Duration, opts ...remote.Option) (Pusher, error) {
 	return &pusherWrapper{
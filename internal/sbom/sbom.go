@@ -0,0 +1,267 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package sbom generates SPDX SBOMs for the assets built by the Image Factory.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/version"
+)
+
+// MediaType is the media type of the generated SBOMs.
+const MediaType = "application/spdx+json"
+
+// Purpose is the SPDX primary package purpose of the SBOM subject.
+type Purpose string
+
+// Supported purposes.
+const (
+	PurposeFile      Purpose = "FILE"
+	PurposeContainer Purpose = "CONTAINER"
+)
+
+// Subject describes the artifact the SBOM is generated for.
+type Subject struct {
+	// Name is the name of the artifact, e.g. 'metal-amd64.iso'.
+	Name    string
+	Purpose Purpose
+	// SHA256 and SHA512 are hex-encoded checksums of the artifact, SHA512 is optional.
+	SHA256 string
+	SHA512 string
+}
+
+// Options configures the SBOM generation.
+type Options struct {
+	// Created is the SBOM creation timestamp.
+	Created time.Time
+	// Namespace is the base URI of the document namespace, the subject checksum is appended to make it unique.
+	Namespace string
+}
+
+const (
+	spdxVersion           = "SPDX-2.3"
+	spdxDataLicense       = "CC0-1.0"
+	spdxNoAssertion       = "NOASSERTION"
+	spdxDocumentID        = "SPDXRef-DOCUMENT"
+	spdxSubjectID         = "SPDXRef-Subject"
+	spdxTalosID           = "SPDXRef-Talos"
+	spdxSchematicID       = "SPDXRef-Schematic"
+	spdxOverlayID         = "SPDXRef-Overlay"
+	spdxExtensionIDFormat = "SPDXRef-Extension-%d"
+)
+
+type document struct {
+	SPDXVersion       string         `json:"spdxVersion"`
+	DataLicense       string         `json:"dataLicense"`
+	SPDXID            string         `json:"SPDXID"`
+	Name              string         `json:"name"`
+	DocumentNamespace string         `json:"documentNamespace"`
+	CreationInfo      creationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage  `json:"packages"`
+	Relationships     []relationship `json:"relationships"`
+}
+
+type creationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string        `json:"name"`
+	SPDXID                string        `json:"SPDXID"`
+	VersionInfo           string        `json:"versionInfo,omitempty"`
+	Supplier              string        `json:"supplier,omitempty"`
+	DownloadLocation      string        `json:"downloadLocation"`
+	Comment               string        `json:"comment,omitempty"`
+	PrimaryPackagePurpose string        `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []checksum    `json:"checksums,omitempty"`
+	ExternalRefs          []externalRef `json:"externalRefs,omitempty"`
+	FilesAnalyzed         bool          `json:"filesAnalyzed"`
+}
+
+type checksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type externalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type relationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// Generate generates the SBOM (SPDX 2.3 JSON) for the subject built from the inputs.
+func Generate(subject Subject, inputs profile.BuildInputs, opts Options) ([]byte, error) {
+	if subject.SHA256 == "" {
+		return nil, fmt.Errorf("missing SHA256 checksum for %q", subject.Name)
+	}
+
+	doc := document{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       spdxDataLicense,
+		SPDXID:            spdxDocumentID,
+		Name:              subject.Name,
+		DocumentNamespace: opts.Namespace + "-" + subject.SHA256,
+		CreationInfo: creationInfo{
+			Created:  opts.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: image-factory-" + version.Tag},
+		},
+	}
+
+	subjectPackage := spdxPackage{
+		Name:                  subject.Name,
+		SPDXID:                spdxSubjectID,
+		VersionInfo:           inputs.TalosVersion,
+		DownloadLocation:      spdxNoAssertion,
+		PrimaryPackagePurpose: string(subject.Purpose),
+		Checksums: []checksum{
+			{Algorithm: "SHA256", ChecksumValue: subject.SHA256},
+		},
+	}
+
+	if subject.SHA512 != "" {
+		subjectPackage.Checksums = append(subjectPackage.Checksums, checksum{Algorithm: "SHA512", ChecksumValue: subject.SHA512})
+	}
+
+	talosPackage := spdxPackage{
+		Name:                  "talos",
+		SPDXID:                spdxTalosID,
+		VersionInfo:           inputs.TalosVersion,
+		Supplier:              "Organization: Sidero Labs",
+		DownloadLocation:      spdxNoAssertion,
+		Comment:               "Talos Linux, built with the imager image for " + inputs.Arch,
+		PrimaryPackagePurpose: "OPERATING-SYSTEM",
+	}
+
+	if inputs.ImagerRef != "" {
+		imagerRef, err := name.NewDigest(inputs.ImagerRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse imager reference: %w", err)
+		}
+
+		talosPackage.Checksums = digestChecksums(imagerRef.DigestStr())
+		talosPackage.ExternalRefs = purlRefs(imagerRef.Context(), imagerRef.DigestStr(), inputs.TalosVersion)
+	}
+
+	schematicPackage := spdxPackage{
+		Name:             "schematic",
+		SPDXID:           spdxSchematicID,
+		VersionInfo:      inputs.SchematicID,
+		DownloadLocation: spdxNoAssertion,
+		Comment:          "Image Factory schematic the asset is customized with",
+	}
+
+	doc.Packages = []spdxPackage{subjectPackage, talosPackage, schematicPackage}
+	doc.Relationships = []relationship{
+		{SPDXElementID: spdxDocumentID, RelationshipType: "DESCRIBES", RelatedSPDXElement: spdxSubjectID},
+		{SPDXElementID: spdxSubjectID, RelationshipType: "GENERATED_FROM", RelatedSPDXElement: spdxTalosID},
+		{SPDXElementID: spdxSubjectID, RelationshipType: "GENERATED_FROM", RelatedSPDXElement: spdxSchematicID},
+	}
+
+	for i, extension := range inputs.Extensions {
+		extensionPackage, err := imagePackage(fmt.Sprintf(spdxExtensionIDFormat, i), extension)
+		if err != nil {
+			return nil, err
+		}
+
+		extensionPackage.Comment = "Talos system extension"
+
+		doc.Packages = append(doc.Packages, extensionPackage)
+		doc.Relationships = append(doc.Relationships, relationship{SPDXElementID: spdxSubjectID, RelationshipType: "CONTAINS", RelatedSPDXElement: extensionPackage.SPDXID})
+	}
+
+	if inputs.Overlay != nil {
+		overlayPackage, err := imagePackage(spdxOverlayID, *inputs.Overlay)
+		if err != nil {
+			return nil, err
+		}
+
+		overlayPackage.Comment = "Talos overlay"
+
+		doc.Packages = append(doc.Packages, overlayPackage)
+		doc.Relationships = append(doc.Relationships, relationship{SPDXElementID: spdxSubjectID, RelationshipType: "CONTAINS", RelatedSPDXElement: overlayPackage.SPDXID})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// imagePackage describes the container image as an SPDX package.
+func imagePackage(spdxID string, image profile.InputImage) (spdxPackage, error) {
+	ref, err := name.ParseReference(image.Ref)
+	if err != nil {
+		return spdxPackage{}, fmt.Errorf("failed to parse image reference %q: %w", image.Ref, err)
+	}
+
+	var tag string
+
+	versionInfo := image.Digest
+
+	if tagged, ok := ref.(name.Tag); ok {
+		tag = tagged.TagStr()
+		versionInfo = tag
+	}
+
+	return spdxPackage{
+		Name:                  image.Name,
+		SPDXID:                spdxID,
+		VersionInfo:           versionInfo,
+		DownloadLocation:      spdxNoAssertion,
+		PrimaryPackagePurpose: string(PurposeContainer),
+		Checksums:             digestChecksums(image.Digest),
+		ExternalRefs:          purlRefs(ref.Context(), image.Digest, tag),
+	}, nil
+}
+
+// digestChecksums converts the image digest to the SPDX checksums.
+func digestChecksums(digest string) []checksum {
+	hash, err := v1.NewHash(digest)
+	if err != nil || hash.Algorithm != "sha256" {
+		// not a valid digest, skip it
+		return nil
+	}
+
+	return []checksum{
+		{Algorithm: "SHA256", ChecksumValue: hash.Hex},
+	}
+}
+
+// purlRefs builds the package URL for the container image.
+//
+// See https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#oci.
+func purlRefs(repo name.Repository, digest, tag string) []externalRef {
+	if digest == "" {
+		return nil
+	}
+
+	qualifiers := url.Values{}
+	qualifiers.Set("repository_url", repo.Name())
+
+	if tag != "" {
+		qualifiers.Set("tag", tag)
+	}
+
+	return []externalRef{
+		{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  "pkg:oci/" + path.Base(repo.RepositoryStr()) + "@" + url.QueryEscape(digest) + "?" + qualifiers.Encode(),
+		},
+	}
+}
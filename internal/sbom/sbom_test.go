@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sbom_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/sbom"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("b", 64)
+
+	data, err := sbom.Generate(
+		sbom.Subject{
+			Name:    "metal-amd64.iso",
+			Purpose: sbom.PurposeFile,
+			SHA256:  strings.Repeat("a", 64),
+		},
+		profile.BuildInputs{
+			SchematicID:  "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba",
+			TalosVersion: "v1.10.2",
+			Arch:         "amd64",
+			ImagerRef:    "ghcr.io/siderolabs/imager@" + digest,
+			Extensions: []profile.InputImage{
+				{
+					Name:   "siderolabs/gvisor",
+					Ref:    "ghcr.io/siderolabs/gvisor:20231214.0-v1.10.2",
+					Digest: digest,
+				},
+			},
+			Overlay: &profile.InputImage{
+				Name:   "rpi_generic",
+				Ref:    "ghcr.io/siderolabs/sbc-raspberrypi:v0.1.0",
+				Digest: digest,
+			},
+		},
+		sbom.Options{
+			Created:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			Namespace: "https://factory.talos.dev/image/metal-amd64.iso.sbom.json",
+		},
+	)
+	require.NoError(t, err)
+
+	var doc struct {
+		SPDXVersion       string `json:"spdxVersion"`
+		DocumentNamespace string `json:"documentNamespace"`
+		CreationInfo      struct {
+			Created string `json:"created"`
+		} `json:"creationInfo"`
+		Packages []struct {
+			Name         string `json:"name"`
+			SPDXID       string `json:"SPDXID"`
+			VersionInfo  string `json:"versionInfo"`
+			ExternalRefs []struct {
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []struct {
+			SPDXElementID      string `json:"spdxElementId"`
+			RelationshipType   string `json:"relationshipType"`
+			RelatedSPDXElement string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}
+
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "https://factory.talos.dev/image/metal-amd64.iso.sbom.json-"+strings.Repeat("a", 64), doc.DocumentNamespace)
+	assert.Equal(t, "2025-01-02T03:04:05Z", doc.CreationInfo.Created)
+
+	require.Len(t, doc.Packages, 5)
+
+	var names []string
+
+	for _, pkg := range doc.Packages {
+		names = append(names, pkg.Name)
+	}
+
+	assert.Equal(t, []string{"metal-amd64.iso", "talos", "schematic", "siderolabs/gvisor", "rpi_generic"}, names)
+
+	assert.Equal(t, "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba", doc.Packages[2].VersionInfo)
+	assert.Equal(t, "20231214.0-v1.10.2", doc.Packages[3].VersionInfo)
+
+	require.Len(t, doc.Packages[1].ExternalRefs, 1)
+	assert.Equal(t,
+		"pkg:oci/imager@sha256%3A"+strings.Repeat("b", 64)+"?repository_url=ghcr.io%2Fsiderolabs%2Fimager&tag=v1.10.2",
+		doc.Packages[1].ExternalRefs[0].ReferenceLocator,
+	)
+
+	require.Len(t, doc.Relationships, 5)
+	assert.Equal(t, "DESCRIBES", doc.Relationships[0].RelationshipType)
+	assert.Equal(t, "CONTAINS", doc.Relationships[3].RelationshipType)
+	assert.Equal(t, "SPDXRef-Extension-0", doc.Relationships[3].RelatedSPDXElement)
+	assert.Equal(t, "SPDXRef-Overlay", doc.Relationships[4].RelatedSPDXElement)
+}
+
+func TestGenerateMissingChecksum(t *testing.T) {
+	t.Parallel()
+
+	_, err := sbom.Generate(sbom.Subject{Name: "kernel-amd64"}, profile.BuildInputs{}, sbom.Options{})
+	require.Error(t, err)
+}
@@ -6,8 +6,10 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/siderolabs/gen/optional"
@@ -17,26 +19,66 @@ import (
 	"github.com/siderolabs/image-factory/internal/schematic/storage"
 )
 
+// Options configures the cache.
+type Options struct {
+	// MaxSize is the maximum number of entries (both positive and negative) in the cache.
+	//
+	// When the cache is full, least recently used entries are evicted.
+	// Zero means no limit.
+	MaxSize int
+
+	// NegativeTTL is the time to keep "not found" entries in the cache.
+	//
+	// Zero means "not found" responses are not cached.
+	NegativeTTL time.Duration
+}
+
 // Storage is a schematic storage in-memory cache.
 type Storage struct {
 	underlying storage.Storage
 
-	metricCacheSize prometheus.Gauge
+	metricCacheSize                                         prometheus.Gauge
+	metricCacheHits, metricCacheMisses, metricCacheEviction prometheus.Counter
 
 	g  singleflight.Group
-	m  map[string]optional.Optional[[]byte]
+	m  map[string]*list.Element
+	l  *list.List
 	mu sync.Mutex
+
+	options Options
+}
+
+// cacheEntry is stored in the LRU list.
+type cacheEntry struct {
+	// expires is only set for negative entries.
+	expires time.Time
+	data    optional.Optional[[]byte]
+	id      string
 }
 
 // NewCache returns a new cache storage.
-func NewCache(underlying storage.Storage) *Storage {
+func NewCache(underlying storage.Storage, options Options) *Storage {
 	return &Storage{
 		underlying: underlying,
-		m:          map[string]optional.Optional[[]byte]{},
+		options:    options,
+		m:          map[string]*list.Element{},
+		l:          list.New(),
 		metricCacheSize: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "image_factory_schematic_cache_size",
 			Help: "Number of schematics in in-memory cache.",
 		}),
+		metricCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_schematic_cache_hits_total",
+			Help: "Number of schematic lookups served from in-memory cache.",
+		}),
+		metricCacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_schematic_cache_misses_total",
+			Help: "Number of schematic lookups not found in in-memory cache.",
+		}),
+		metricCacheEviction: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "image_factory_schematic_cache_evictions_total",
+			Help: "Number of schematics evicted from in-memory cache due to the size limit.",
+		}),
 	}
 }
 
@@ -45,13 +87,8 @@ var _ storage.Storage = (*Storage)(nil)
 
 // Head checks if the schematic exists.
 func (s *Storage) Head(ctx context.Context, id string) error {
-	// check cache
-	s.mu.Lock()
-	v, ok := s.m[id]
-	s.mu.Unlock()
-
 	// cache entry is there, return immediate response
-	if ok {
+	if v, ok := s.lookup(id); ok {
 		if v.IsPresent() {
 			return nil
 		}
@@ -60,20 +97,15 @@ func (s *Storage) Head(ctx context.Context, id string) error {
 	}
 
 	// cache entry is not there, use .Get to populate it
-	_, err := s.Get(ctx, id)
+	_, err := s.get(ctx, id)
 
 	return err
 }
 
 // Get returns the schematic.
 func (s *Storage) Get(ctx context.Context, id string) ([]byte, error) {
-	// check cache
-	s.mu.Lock()
-	v, ok := s.m[id]
-	s.mu.Unlock()
-
 	// cache entry is there, return immediate response
-	if ok {
+	if v, ok := s.lookup(id); ok {
 		if v.IsPresent() {
 			return v.ValueOrZero(), nil
 		}
@@ -81,31 +113,23 @@ func (s *Storage) Get(ctx context.Context, id string) ([]byte, error) {
 		return nil, xerrors.NewTaggedf[storage.ErrNotFoundTag]("schematic ID %q not found", id)
 	}
 
+	return s.get(ctx, id)
+}
+
+func (s *Storage) get(ctx context.Context, id string) ([]byte, error) {
 	ch := s.g.DoChan(id, func() (any, error) {
 		data, err := s.underlying.Get(ctx, id)
 		if err != nil {
-			if xerrors.TagIs[storage.ErrNotFoundTag](err) {
-				s.mu.Lock()
-
+			if xerrors.TagIs[storage.ErrNotFoundTag](err) && s.options.NegativeTTL > 0 {
 				// never overwrite a present value, as Put might have been called
-				if _, ok := s.m[id]; !ok {
-					s.m[id] = optional.None[[]byte]()
-				}
-
-				s.mu.Unlock()
+				s.store(id, optional.None[[]byte](), false)
 			}
 
 			return nil, err
 		}
 
-		s.mu.Lock()
-
 		// never overwrite a present value, as Put might have been called
-		if _, ok := s.m[id]; !ok {
-			s.m[id] = optional.Some(data)
-		}
-
-		s.mu.Unlock()
+		s.store(id, optional.Some(data), false)
 
 		return data, nil
 	})
@@ -129,13 +153,80 @@ func (s *Storage) Put(ctx context.Context, id string, data []byte) error {
 		return err
 	}
 
-	s.mu.Lock()
-	s.m[id] = optional.Some(data)
-	s.mu.Unlock()
+	s.store(id, optional.Some(data), true)
 
 	return nil
 }
 
+// lookup returns the cached value, and marks the entry as recently used.
+func (s *Storage) lookup(id string) (optional.Optional[[]byte], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.m[id]; ok {
+		entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert,errcheck
+
+		if entry.expires.IsZero() || time.Now().Before(entry.expires) {
+			s.l.MoveToFront(elem)
+			s.metricCacheHits.Inc()
+
+			return entry.data, true
+		}
+
+		// negative entry has expired, the schematic might have been created by another replica
+		s.remove(elem)
+	}
+
+	s.metricCacheMisses.Inc()
+
+	return optional.None[[]byte](), false
+}
+
+// store puts the value into the cache, evicting least recently used entries if needed.
+//
+// If overwrite is false, existing present values are not overwritten.
+func (s *Storage) store(id string, data optional.Optional[[]byte], overwrite bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &cacheEntry{
+		id:   id,
+		data: data,
+	}
+
+	if !data.IsPresent() {
+		entry.expires = time.Now().Add(s.options.NegativeTTL)
+	}
+
+	if elem, ok := s.m[id]; ok {
+		existing := elem.Value.(*cacheEntry) //nolint:forcetypeassert,errcheck
+
+		if !overwrite && existing.data.IsPresent() {
+			return
+		}
+
+		elem.Value = entry
+		s.l.MoveToFront(elem)
+
+		return
+	}
+
+	s.m[id] = s.l.PushFront(entry)
+
+	for s.options.MaxSize > 0 && s.l.Len() > s.options.MaxSize {
+		s.remove(s.l.Back())
+
+		s.metricCacheEviction.Inc()
+	}
+}
+
+// remove deletes the entry from the cache, should be called with the lock held.
+func (s *Storage) remove(elem *list.Element) {
+	entry := s.l.Remove(elem).(*cacheEntry) //nolint:forcetypeassert,errcheck
+
+	delete(s.m, entry.id)
+}
+
 // Describe implements prom.Collector interface.
 func (s *Storage) Describe(ch chan<- *prometheus.Desc) {
 	prometheus.DescribeByCollect(s, ch)
@@ -144,10 +235,13 @@ func (s *Storage) Describe(ch chan<- *prometheus.Desc) {
 // Collect implements prom.Collector interface.
 func (s *Storage) Collect(ch chan<- prometheus.Metric) {
 	s.mu.Lock()
-	s.metricCacheSize.Set(float64(len(s.m)))
+	s.metricCacheSize.Set(float64(s.l.Len()))
 	s.mu.Unlock()
 
 	s.metricCacheSize.Collect(ch)
+	s.metricCacheHits.Collect(ch)
+	s.metricCacheMisses.Collect(ch)
+	s.metricCacheEviction.Collect(ch)
 }
 
 var _ prometheus.Collector = &Storage{}
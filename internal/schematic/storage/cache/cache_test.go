@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/siderolabs/gen/xerrors"
@@ -55,7 +56,7 @@ func TestStorage(t *testing.T) {
 	defer cancel()
 
 	underlying := &mockStorage{}
-	strg := cache.NewCache(underlying)
+	strg := cache.NewCache(underlying, cache.Options{NegativeTTL: time.Hour})
 
 	v, err := strg.Get(ctx, "foo")
 	require.NoError(t, err)
@@ -114,3 +115,89 @@ func TestStorage(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "lastone-8", string(v)) // counter was incremented twice on 'failing' and once on 'lastone'
 }
+
+func TestStorageEviction(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	underlying := &mockStorage{}
+	strg := cache.NewCache(underlying, cache.Options{MaxSize: 2, NegativeTTL: time.Hour})
+
+	v, err := strg.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo-1", string(v))
+
+	v, err = strg.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar-2", string(v))
+
+	v, err = strg.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo-1", string(v)) // cached value, 'foo' is now most recently used
+
+	v, err = strg.Get(ctx, "baz")
+	require.NoError(t, err)
+	assert.Equal(t, "baz-3", string(v)) // 'bar' gets evicted
+
+	v, err = strg.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo-1", string(v)) // still cached
+
+	v, err = strg.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar-4", string(v)) // fetched again, 'baz' gets evicted
+
+	_, err = strg.Get(ctx, "not-found")
+	require.Error(t, err)
+	require.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err)) // negative entries are subject to eviction as well, 'foo' gets evicted
+
+	v, err = strg.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo-6", string(v))
+}
+
+func TestStorageNegativeTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	underlying := &mockStorage{}
+	strg := cache.NewCache(underlying, cache.Options{NegativeTTL: 100 * time.Millisecond})
+
+	_, err := strg.Get(ctx, "not-found")
+	require.Error(t, err)
+	require.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+	_, err = strg.Get(ctx, "not-found")
+	require.Error(t, err)
+	require.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+	assert.EqualValues(t, 1, underlying.counter.Load()) // negative entry is cached
+
+	// negative entry expires, so the underlying storage is queried again
+	require.Eventually(t, func() bool {
+		_, err = strg.Get(ctx, "not-found")
+		require.Error(t, err)
+		require.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+		return underlying.counter.Load() > 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestStorageNoNegativeCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	underlying := &mockStorage{}
+	strg := cache.NewCache(underlying, cache.Options{})
+
+	for range 3 {
+		_, err := strg.Get(ctx, "not-found")
+		require.Error(t, err)
+		require.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+	}
+
+	assert.EqualValues(t, 3, underlying.counter.Load())
+}
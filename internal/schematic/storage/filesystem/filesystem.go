@@ -0,0 +1,145 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package filesystem implements a schematic storage in a local directory.
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/siderolabs/gen/xerrors"
+
+	"github.com/siderolabs/image-factory/internal/schematic/storage"
+)
+
+// Storage is a schematic storage in a local directory.
+//
+// Each schematic is stored as a separate file named after the schematic ID.
+// Schematic ID is a sha256 of the contents, so the contents are verified on read.
+type Storage struct {
+	path string
+}
+
+// Check interface.
+var _ storage.Storage = (*Storage)(nil)
+
+// NewStorage creates a new storage.
+func NewStorage(path string) (*Storage, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &Storage{
+		path: path,
+	}, nil
+}
+
+// Head checks if the schematic exists.
+func (s *Storage) Head(_ context.Context, id string) error {
+	if !validID(id) {
+		return xerrors.NewTaggedf[storage.ErrNotFoundTag]("schematic ID %q not found", id)
+	}
+
+	_, err := os.Stat(filepath.Join(s.path, id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return xerrors.NewTaggedf[storage.ErrNotFoundTag]("schematic ID %q not found", id)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Get returns the schematic.
+func (s *Storage) Get(_ context.Context, id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, xerrors.NewTaggedf[storage.ErrNotFoundTag]("schematic ID %q not found", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.path, id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, xerrors.NewTaggedf[storage.ErrNotFoundTag]("schematic ID %q not found", id)
+		}
+
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+
+	if actualID := hex.EncodeToString(hash[:]); actualID != id {
+		return nil, fmt.Errorf("schematic ID %q content hash mismatch: got %q", id, actualID)
+	}
+
+	return data, nil
+}
+
+// Put stores the schematic.
+//
+// The schematic is written to a temporary file first, and then atomically renamed.
+func (s *Storage) Put(_ context.Context, id string, data []byte) error {
+	if !validID(id) {
+		return fmt.Errorf("invalid schematic ID %q", id)
+	}
+
+	f, err := os.CreateTemp(s.path, "."+id+"-*")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := f.Name()
+
+	defer os.Remove(tmpPath) //nolint:errcheck
+
+	if _, err = f.Write(data); err != nil {
+		f.Close() //nolint:errcheck
+
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close() //nolint:errcheck
+
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(s.path, id))
+}
+
+// Describe implements prom.Collector interface.
+func (s *Storage) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+// Collect implements prom.Collector interface.
+func (s *Storage) Collect(chan<- prometheus.Metric) {
+	// no metrics for now
+}
+
+var _ prometheus.Collector = &Storage{}
+
+// idRe matches the schematic IDs: the lowercase hex-encoded sha256 digests.
+var idRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// validID checks that the ID looks like a sha256 hex digest.
+//
+// This also guards against path traversal.
+func validID(id string) bool {
+	return idRe.MatchString(id)
+}
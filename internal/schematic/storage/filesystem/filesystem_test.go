@@ -0,0 +1,106 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package filesystem_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/siderolabs/gen/xerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siderolabs/image-factory/internal/schematic/storage"
+	"github.com/siderolabs/image-factory/internal/schematic/storage/filesystem"
+)
+
+func schematicID(data []byte) string {
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	strg, err := filesystem.NewStorage(dir)
+	require.NoError(t, err)
+
+	data := []byte("customization: {}\n")
+	id := schematicID(data)
+
+	err = strg.Head(ctx, id)
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+	_, err = strg.Get(ctx, id)
+	require.Error(t, err)
+	assert.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+	require.NoError(t, strg.Put(ctx, id, data))
+
+	require.NoError(t, strg.Head(ctx, id))
+
+	v, err := strg.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, data, v)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, id, entries[0].Name())
+
+	// storing same schematic again is fine
+	require.NoError(t, strg.Put(ctx, id, data))
+}
+
+func TestStorageInvalidID(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	strg, err := filesystem.NewStorage(t.TempDir())
+	require.NoError(t, err)
+
+	for _, id := range []string{"", "foo", "../../etc/passwd", "zz" + schematicID(nil)[2:], strings.ToUpper(schematicID(nil))} {
+		err = strg.Head(ctx, id)
+		require.Error(t, err)
+		assert.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+		_, err = strg.Get(ctx, id)
+		require.Error(t, err)
+		assert.True(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+
+		require.Error(t, strg.Put(ctx, id, nil))
+	}
+}
+
+func TestStorageCorrupted(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	strg, err := filesystem.NewStorage(dir)
+	require.NoError(t, err)
+
+	data := []byte("customization: {}\n")
+	id := schematicID(data)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, id), []byte("customization:\n    extraKernelArgs:\n        - evil\n"), 0o644))
+
+	_, err = strg.Get(ctx, id)
+	require.Error(t, err)
+	assert.False(t, xerrors.TagIs[storage.ErrNotFoundTag](err))
+	assert.ErrorContains(t, err, "content hash mismatch")
+}
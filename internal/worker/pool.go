@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package worker
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+)
+
+// Pool assigns the builds to the remote workers.
+//
+// Builds are assigned by the profile hash with rendezvous hashing, so that the same asset is built on the same worker
+// (which has the inputs already fetched). If the worker is unavailable or busy, the build is assigned to the next one.
+type Pool struct {
+	logger    *zap.Logger
+	client    *http.Client
+	token     string
+	endpoints []*url.URL
+}
+
+// PoolOptions configures the worker pool.
+type PoolOptions struct {
+	// Client is the HTTP client to talk to the workers (optional).
+	Client *http.Client
+	// Token authenticates the coordinator to the workers.
+	Token string
+	// Endpoints are the base URLs of the workers.
+	Endpoints []string
+}
+
+// NewPool creates a new worker pool.
+func NewPool(logger *zap.Logger, options PoolOptions) (*Pool, error) {
+	if len(options.Endpoints) == 0 {
+		return nil, errors.New("no worker endpoints configured")
+	}
+
+	pool := &Pool{
+		logger: logger.With(zap.String("component", "worker-pool")),
+		client: options.Client,
+		token:  options.Token,
+	}
+
+	if pool.client == nil {
+		pool.client = http.DefaultClient
+	}
+
+	for _, endpoint := range options.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse worker endpoint %q: %w", endpoint, err)
+		}
+
+		pool.endpoints = append(pool.endpoints, u)
+	}
+
+	return pool, nil
+}
+
+// BuildRemote implements asset.RemoteBuilder.
+func (p *Pool) BuildRemote(ctx context.Context, req asset.RemoteBuildRequest) error {
+	profileYAML, err := yaml.Marshal(req.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	body, err := json.Marshal(buildRequest{
+		ProfileHash: req.ProfileHash,
+		SchematicID: req.SchematicID,
+		Version:     req.Version,
+		Profile:     string(profileYAML),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, endpoint := range p.assign(req.ProfileHash) {
+		retry, buildErr := p.build(ctx, endpoint, body, req.Log)
+		if buildErr == nil {
+			return nil
+		}
+
+		if !retry {
+			return buildErr
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		p.logger.Warn("worker unavailable, trying the next one", zap.Stringer("worker", endpoint), zap.String("profile_hash", req.ProfileHash), zap.Error(buildErr))
+
+		errs = append(errs, buildErr)
+	}
+
+	return fmt.Errorf("no worker available: %w", errors.Join(errs...))
+}
+
+// build sends the build request to the worker.
+//
+// It returns true if the build should be retried with the next worker.
+// The log of the build is written to the log writer (if set).
+func (p *Pool) build(ctx context.Context, endpoint *url.URL, body []byte, log io.Writer) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.JoinPath(BuildPath).String(), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("worker %s: %w", endpoint, err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	var buildResp buildResponse
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) //nolint:errcheck
+
+	if json.Unmarshal(data, &buildResp) != nil {
+		buildResp = buildResponse{Error: strings.TrimSpace(string(data))}
+	}
+
+	if log != nil && buildResp.Log != "" {
+		fmt.Fprintf(log, "--- worker %s build log ---\n%s--- end of worker build log ---\n", endpoint, buildResp.Log) //nolint:errcheck
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+
+	err = fmt.Errorf("worker %s: %s: %s", endpoint, resp.Status, buildResp.Error)
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, err
+	default:
+		return false, err
+	}
+}
+
+// assign returns the workers in the order of preference for the profile hash.
+func (p *Pool) assign(profileHash string) []*url.URL {
+	type scored struct {
+		endpoint *url.URL
+		score    uint64
+	}
+
+	candidates := make([]scored, 0, len(p.endpoints))
+
+	for _, endpoint := range p.endpoints {
+		sum := sha256.Sum256([]byte(endpoint.String() + "\x00" + profileHash))
+
+		candidates = append(candidates, scored{endpoint: endpoint, score: binary.BigEndian.Uint64(sum[:8])})
+	}
+
+	slices.SortFunc(candidates, func(a, b scored) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	endpoints := make([]*url.URL, 0, len(candidates))
+
+	for _, c := range candidates {
+		endpoints = append(endpoints, c.endpoint)
+	}
+
+	return endpoints
+}
+
+var _ asset.RemoteBuilder = &Pool{}
@@ -0,0 +1,162 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package worker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/siderolabs/talos/pkg/imager/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/worker"
+)
+
+type fakeWorker struct {
+	*httptest.Server
+
+	requests atomic.Int32
+}
+
+func newFakeWorker(t *testing.T, status int) *fakeWorker {
+	t.Helper()
+
+	w := &fakeWorker{}
+
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.requests.Add(1)
+
+		if !assert.Equal(t, worker.BuildPath, r.URL.Path) || !assert.Equal(t, "Bearer secret", r.Header.Get("Authorization")) {
+			rw.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		var req map[string]string
+
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			rw.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		assert.Equal(t, "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba", req["schematic_id"])
+		assert.Equal(t, "1.10.2", req["version"])
+		assert.Contains(t, req["profile"], "arch: amd64")
+
+		rw.WriteHeader(status)
+
+		if status != http.StatusOK {
+			rw.Write([]byte(`{"error":"build failed"}`)) //nolint:errcheck
+		}
+	}))
+
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+func buildRequest(profileHash string) asset.RemoteBuildRequest {
+	return asset.RemoteBuildRequest{
+		Source: asset.Source{
+			Profile:     profile.Profile{Arch: "amd64"},
+			SchematicID: "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba",
+		},
+		ProfileHash: profileHash,
+		Version:     "1.10.2",
+	}
+}
+
+func TestPoolAssignment(t *testing.T) {
+	t.Parallel()
+
+	workers := []*fakeWorker{
+		newFakeWorker(t, http.StatusOK),
+		newFakeWorker(t, http.StatusOK),
+		newFakeWorker(t, http.StatusOK),
+	}
+
+	pool, err := worker.NewPool(zaptest.NewLogger(t), worker.PoolOptions{
+		Endpoints: []string{workers[0].URL, workers[1].URL, workers[2].URL},
+		Token:     "secret",
+	})
+	require.NoError(t, err)
+
+	// the same profile hash is always assigned to the same worker
+	for range 3 {
+		require.NoError(t, pool.BuildRemote(t.Context(), buildRequest("hash-a")))
+	}
+
+	var assigned []int32
+
+	for _, w := range workers {
+		assigned = append(assigned, w.requests.Load())
+	}
+
+	assert.ElementsMatch(t, []int32{3, 0, 0}, assigned)
+}
+
+func TestPoolFailover(t *testing.T) {
+	t.Parallel()
+
+	busy := newFakeWorker(t, http.StatusServiceUnavailable)
+	healthy := newFakeWorker(t, http.StatusOK)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	pool, err := worker.NewPool(zaptest.NewLogger(t), worker.PoolOptions{
+		Endpoints: []string{busy.URL, down.URL, healthy.URL},
+		Token:     "secret",
+	})
+	require.NoError(t, err)
+
+	for _, hash := range []string{"hash-a", "hash-b", "hash-c", "hash-d"} {
+		require.NoError(t, pool.BuildRemote(t.Context(), buildRequest(hash)))
+	}
+
+	assert.EqualValues(t, 4, healthy.requests.Load())
+}
+
+func TestPoolBuildError(t *testing.T) {
+	t.Parallel()
+
+	failing := newFakeWorker(t, http.StatusInternalServerError)
+	healthy := newFakeWorker(t, http.StatusOK)
+
+	pool, err := worker.NewPool(zaptest.NewLogger(t), worker.PoolOptions{
+		Endpoints: []string{failing.URL},
+		Token:     "secret",
+	})
+	require.NoError(t, err)
+
+	err = pool.BuildRemote(t.Context(), buildRequest("hash-a"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "build failed")
+
+	// build errors are not retried on the other workers
+	pool, err = worker.NewPool(zaptest.NewLogger(t), worker.PoolOptions{
+		Endpoints: []string{failing.URL, healthy.URL},
+		Token:     "secret",
+	})
+	require.NoError(t, err)
+
+	var errs int
+
+	for _, hash := range []string{"hash-a", "hash-b", "hash-c", "hash-d", "hash-e", "hash-f"} {
+		if pool.BuildRemote(t.Context(), buildRequest(hash)) != nil {
+			errs++
+		}
+	}
+
+	assert.EqualValues(t, errs, failing.requests.Load()-1)
+	assert.EqualValues(t, 6-errs, healthy.requests.Load())
+}
@@ -0,0 +1,199 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package worker
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/siderolabs/gen/xerrors"
+	imagerprofile "github.com/siderolabs/talos/pkg/imager/profile"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/siderolabs/image-factory/internal/artifacts"
+	"github.com/siderolabs/image-factory/internal/asset"
+	"github.com/siderolabs/image-factory/internal/asset/scheduler"
+	"github.com/siderolabs/image-factory/internal/auth"
+	"github.com/siderolabs/image-factory/internal/profile"
+	"github.com/siderolabs/image-factory/internal/schematic"
+	"github.com/siderolabs/image-factory/internal/schematic/storage"
+	"github.com/siderolabs/image-factory/internal/secureboot"
+)
+
+// profileMismatchErrorTag tags the errors when the worker resolves a different profile than the coordinator.
+type profileMismatchErrorTag struct{}
+
+// Server serves the build requests from the coordinator.
+type Server struct {
+	logger            *zap.Logger
+	builder           *asset.Builder
+	schematicFactory  *schematic.Factory
+	artifactsManager  *artifacts.Manager
+	secureBootService *secureboot.Service
+	token             string
+	insecure          bool
+}
+
+// ServerOptions configures the worker server.
+type ServerOptions struct {
+	// Token is required from the coordinator.
+	Token string
+	// Insecure accepts the build requests without the token, it's only allowed if the token is not set.
+	//
+	// The worker builds any asset requested, so it should only be used if the worker is not reachable by anyone else.
+	Insecure bool
+}
+
+// NewServer creates a new worker server.
+//
+// The token is required, unless the server is explicitly insecure.
+func NewServer(
+	logger *zap.Logger,
+	builder *asset.Builder,
+	schematicFactory *schematic.Factory,
+	artifactsManager *artifacts.Manager,
+	secureBootService *secureboot.Service,
+	options ServerOptions,
+) (*Server, error) {
+	if options.Token == "" && !options.Insecure {
+		return nil, errors.New("worker token is required")
+	}
+
+	if options.Token != "" && options.Insecure {
+		return nil, errors.New("worker token can't be used with the insecure mode")
+	}
+
+	return &Server{
+		logger:            logger.With(zap.String("component", "worker")),
+		builder:           builder,
+		schematicFactory:  schematicFactory,
+		artifactsManager:  artifactsManager,
+		secureBootService: secureBootService,
+		token:             options.Token,
+		insecure:          options.Insecure,
+	}, nil
+}
+
+// Handler returns the HTTP handler of the worker.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+BuildPath, s.handleBuild)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return mux
+}
+
+func (s *Server) handleBuild(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if !s.insecure {
+		token, ok := auth.TokenFromRequest(r)
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeResponse(w, http.StatusUnauthorized, buildResponse{Error: "invalid worker token"})
+
+			return
+		}
+	}
+
+	var req buildRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, buildResponse{Error: fmt.Sprintf("error decoding build request: %s", err)})
+
+		return
+	}
+
+	err := s.build(r.Context(), req)
+
+	// the log is available if the build was started
+	log, _, _ := s.builder.BuildLog(req.ProfileHash) //nolint:errcheck
+
+	s.logger.Info("build request",
+		zap.String("profile_hash", req.ProfileHash),
+		zap.String("schematic", req.SchematicID),
+		zap.String("version", req.Version),
+		zap.Duration("duration", time.Since(start)),
+		zap.Error(err),
+	)
+
+	if err != nil {
+		writeResponse(w, errorStatus(err), buildResponse{Error: err.Error(), Log: log})
+
+		return
+	}
+
+	writeResponse(w, http.StatusOK, buildResponse{Log: log})
+}
+
+// build reproduces the profile from the source, builds the asset and pushes it to the cache.
+func (s *Server) build(ctx context.Context, req buildRequest) error {
+	var baseProf imagerprofile.Profile
+
+	if err := yaml.Unmarshal([]byte(req.Profile), &baseProf); err != nil {
+		return xerrors.NewTaggedf[profile.InvalidErrorTag]("error decoding profile: %w", err)
+	}
+
+	cfg, err := s.schematicFactory.Get(ctx, req.SchematicID)
+	if err != nil {
+		return err
+	}
+
+	prof, inputs, err := profile.EnhanceFromSchematicWithInputs(ctx, baseProf.DeepCopy(), req.SchematicID, cfg, s.artifactsManager, s.secureBootService, "v"+req.Version)
+	if err != nil {
+		return fmt.Errorf("error enhancing profile from schematic: %w", err)
+	}
+
+	if err = prof.Validate(); err != nil {
+		return fmt.Errorf("error validating profile: %w", err)
+	}
+
+	profileHash, err := profile.Hash(prof)
+	if err != nil {
+		return err
+	}
+
+	if profileHash != req.ProfileHash {
+		return xerrors.NewTaggedf[profileMismatchErrorTag](
+			"profile hash mismatch: coordinator %s, worker %s (check that the worker and the coordinator are configured the same way)",
+			req.ProfileHash, profileHash,
+		)
+	}
+
+	// the builder pushes the asset to the cache before returning
+	_, err = s.builder.Build(ctx, prof, req.Version, asset.Source{Profile: baseProf, SchematicID: req.SchematicID, Inputs: &inputs})
+
+	return err
+}
+
+func errorStatus(err error) int {
+	switch {
+	case xerrors.TagIs[storage.ErrNotFoundTag](err):
+		return http.StatusNotFound
+	case xerrors.TagIs[profile.InvalidErrorTag](err):
+		return http.StatusBadRequest
+	case xerrors.TagIs[profileMismatchErrorTag](err):
+		return http.StatusConflict
+	case xerrors.TagIs[scheduler.QueueFullErrorTag](err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp buildResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(resp) //nolint:errcheck,errchkjson
+}
@@ -0,0 +1,38 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package worker implements distributed asset builds.
+//
+// The coordinator (the factory serving the frontends) assigns the builds to the workers by the profile hash,
+// the workers build the assets and push them to the shared asset cache.
+//
+// The protocol is a single HTTP endpoint:
+//
+//	POST /build
+//	Authorization: Bearer <token>
+//
+//	{"profile_hash": "...", "schematic_id": "...", "version": "1.10.2", "profile": "<base profile YAML>"}
+//
+// The worker responds once the asset is in the cache, with 200 OK on success,
+// or with an error status otherwise. The response is a JSON object `{"error": "...", "log": "..."}`
+// with the error message and the build log.
+package worker
+
+// BuildPath is the path of the build endpoint.
+const BuildPath = "/build"
+
+// buildRequest is the request to build the asset.
+type buildRequest struct {
+	ProfileHash string `json:"profile_hash"`
+	SchematicID string `json:"schematic_id"`
+	Version     string `json:"version"`
+	// Profile is the YAML-encoded profile before it was enhanced from the schematic.
+	Profile string `json:"profile"`
+}
+
+// buildResponse is the response of the build endpoint.
+type buildResponse struct {
+	Error string `json:"error,omitempty"`
+	Log   string `json:"log,omitempty"`
+}
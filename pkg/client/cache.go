@@ -0,0 +1,97 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CacheEntry defines the cached asset response item of the admin API.
+type CacheEntry struct {
+	Created     time.Time `json:"created,omitzero"`
+	ProfileHash string    `json:"profile_hash"`
+	SchematicID string    `json:"schematic_id,omitempty"`
+	Version     string    `json:"talos_version,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+	Arch        string    `json:"arch,omitempty"`
+	Profile     string    `json:"profile,omitempty"`
+	Digest      string    `json:"digest"`
+	Size        int64     `json:"size"`
+}
+
+// CacheFilter selects the cached assets by the schematic and the Talos version.
+type CacheFilter struct {
+	SchematicID string
+	Version     string
+}
+
+func (f CacheFilter) query() string {
+	query := url.Values{}
+
+	if f.SchematicID != "" {
+		query.Set("schematic", f.SchematicID)
+	}
+
+	if f.Version != "" {
+		query.Set("version", f.Version)
+	}
+
+	if len(query) == 0 {
+		return ""
+	}
+
+	return "?" + query.Encode()
+}
+
+// CacheList lists the cached assets matching the filter.
+//
+// The admin API requires the client to authenticate as an admin subject.
+func (c *Client) CacheList(ctx context.Context, filter CacheFilter) ([]CacheEntry, error) {
+	var entries []CacheEntry
+
+	if err := c.do(ctx, http.MethodGet, "/admin/cache"+filter.query(), nil, &entries, nil); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// CacheGet inspects the cached asset by the profile hash.
+func (c *Client) CacheGet(ctx context.Context, profileHash string) (*CacheEntry, error) {
+	var entry CacheEntry
+
+	if err := c.do(ctx, http.MethodGet, "/admin/cache/"+profileHash, nil, &entry, nil); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// CachePurge purges the cached asset by the profile hash, and returns the purged assets.
+func (c *Client) CachePurge(ctx context.Context, profileHash string) ([]CacheEntry, error) {
+	return c.cachePurge(ctx, "/admin/cache/"+profileHash)
+}
+
+// CachePurgeFiltered purges the cached assets matching the filter, and returns the purged assets.
+//
+// The filter should not be empty.
+func (c *Client) CachePurgeFiltered(ctx context.Context, filter CacheFilter) ([]CacheEntry, error) {
+	return c.cachePurge(ctx, "/admin/cache"+filter.query())
+}
+
+func (c *Client) cachePurge(ctx context.Context, uri string) ([]CacheEntry, error) {
+	var resp struct {
+		Purged []CacheEntry `json:"purged"`
+	}
+
+	if err := c.do(ctx, http.MethodDelete, uri, nil, &resp, nil); err != nil {
+		return nil, err
+	}
+
+	return resp.Purged, nil
+}
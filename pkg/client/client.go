@@ -11,8 +11,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/siderolabs/image-factory/pkg/schematic"
 )
@@ -34,10 +37,32 @@ type OverlayInfo struct {
 	Digest string `json:"digest"`
 }
 
+// BuildStatus is the status of the asynchronous build.
+type BuildStatus string
+
+// Build statuses.
+const (
+	BuildStatusQueued  BuildStatus = "queued"
+	BuildStatusRunning BuildStatus = "running"
+	BuildStatusDone    BuildStatus = "done"
+	BuildStatusFailed  BuildStatus = "failed"
+)
+
+// BuildInfo defines the asynchronous build status response.
+type BuildInfo struct {
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	ID        string      `json:"id"`
+	Status    BuildStatus `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	URL       string      `json:"url,omitempty"`
+}
+
 // Client is the Image Factory HTTP API client.
 type Client struct {
-	baseURL *url.URL
-	client  http.Client
+	baseURL   *url.URL
+	authToken string
+	client    http.Client
 }
 
 // New creates a new Image Factory API client.
@@ -50,8 +75,9 @@ func New(baseURL string, options ...Option) (*Client, error) {
 	}
 
 	c := &Client{
-		baseURL: bURL,
-		client:  opts.Client,
+		baseURL:   bURL,
+		authToken: opts.AuthToken,
+		client:    opts.Client,
 	}
 
 	return c, nil
@@ -82,6 +108,64 @@ func (c *Client) SchematicCreate(ctx context.Context, schematic schematic.Schema
 	return response.ID, nil
 }
 
+// SchematicGet retrieves the schematic by ID.
+func (c *Client) SchematicGet(ctx context.Context, id string) (*schematic.Schematic, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/schematics/"+id, nil, map[string]string{
+		"Accept": "application/yaml",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return schematic.Unmarshal(data)
+}
+
+// BuildCreate submits an asynchronous build of the boot asset.
+//
+// The path is the asset path as in the download URL, e.g. `metal-amd64.iso`.
+func (c *Client) BuildCreate(ctx context.Context, schematicID, talosVersion, path string) (*BuildInfo, error) {
+	data, err := json.Marshal(struct {
+		Schematic string `json:"schematic"`
+		Version   string `json:"version"`
+		Path      string `json:"path"`
+	}{
+		Schematic: schematicID,
+		Version:   talosVersion,
+		Path:      path,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var build BuildInfo
+
+	if err = c.do(ctx, http.MethodPost, "/builds", data, &build, map[string]string{
+		"Content-Type": "application/json",
+	}); err != nil {
+		return nil, err
+	}
+
+	return &build, nil
+}
+
+// BuildGet gets the status of the asynchronous build.
+func (c *Client) BuildGet(ctx context.Context, id string) (*BuildInfo, error) {
+	var build BuildInfo
+
+	if err := c.do(ctx, http.MethodGet, "/builds/"+id, nil, &build, nil); err != nil {
+		return nil, err
+	}
+
+	return &build, nil
+}
+
 // Versions gets the list of Talos versions available.
 func (c *Client) Versions(ctx context.Context) ([]string, error) {
 	var versions []string
@@ -116,39 +200,62 @@ func (c *Client) OverlaysVersions(ctx context.Context, talosVersion string) ([]O
 }
 
 func (c *Client) do(ctx context.Context, method, uri string, requestData []byte, responseData any, headers map[string]string) error {
+	resp, err := c.doRequest(ctx, method, uri, requestData, headers)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if responseData != nil {
+		decoder := json.NewDecoder(resp.Body)
+
+		return decoder.Decode(responseData)
+	}
+
+	return nil
+}
+
+// doRequest performs the request and checks the response for errors.
+//
+// On success, the caller is responsible for closing the response body.
+func (c *Client) doRequest(ctx context.Context, method, uri string, requestData []byte, headers map[string]string) (*http.Response, error) {
 	var reader io.Reader
 
 	if requestData != nil {
 		reader = bytes.NewReader(requestData)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(uri).String(), reader)
+	path, query, _ := strings.Cut(uri, "?")
+
+	reqURL := c.baseURL.JoinPath(path)
+	reqURL.RawQuery = query
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
 
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer resp.Body.Close() //nolint:errcheck
-
 	if err = c.checkError(resp); err != nil {
-		return err
-	}
-
-	if responseData != nil {
-		decoder := json.NewDecoder(resp.Body)
+		resp.Body.Close() //nolint:errcheck
 
-		return decoder.Decode(responseData)
+		return nil, err
 	}
 
-	return nil
+	return resp, nil
 }
 
 func (c *Client) checkError(resp *http.Response) error {
@@ -163,11 +270,22 @@ func (c *Client) checkError(resp *http.Response) error {
 		return err
 	}
 
-	err = &HTTPError{
+	httpErr := &HTTPError{
 		Code:    resp.StatusCode,
 		Message: string(body),
 	}
 
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" { //nolint:errcheck
+		var apiErr APIError
+
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+			httpErr.Message = apiErr.Message
+			httpErr.ErrorCode = apiErr.Code
+		}
+	}
+
+	err = httpErr
+
 	if resp.StatusCode == http.StatusBadRequest {
 		return &InvalidSchematicError{
 			e: err,
@@ -11,10 +11,34 @@ import (
 	"github.com/siderolabs/gen/xerrors"
 )
 
+// ErrorCode is a stable error code returned by the versioned API (`/api/v1`).
+type ErrorCode string
+
+// Error codes.
+const (
+	ErrorCodeNotFound         ErrorCode = "not_found"
+	ErrorCodeInvalidProfile   ErrorCode = "invalid_profile"
+	ErrorCodeInvalidSchematic ErrorCode = "invalid_schematic"
+	ErrorCodeUnauthorized     ErrorCode = "unauthorized"
+	ErrorCodeForbidden        ErrorCode = "forbidden"
+	ErrorCodeRateLimited      ErrorCode = "rate_limited"
+	ErrorCodeQueueFull        ErrorCode = "queue_full"
+	ErrorCodeBuildFailed      ErrorCode = "build_failed"
+	ErrorCodeInternal         ErrorCode = "internal_error"
+)
+
+// APIError is the JSON error object returned by the versioned API (`/api/v1`).
+type APIError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
 // HTTPError is a generic HTTP error wrapper.
 type HTTPError struct {
 	Message string
-	Code    int
+	// ErrorCode is set if the server returned a JSON error object.
+	ErrorCode ErrorCode
+	Code      int
 }
 
 // Error implements error interface.
@@ -29,6 +53,13 @@ func IsHTTPErrorCode(err error, code int) bool {
 	return errors.As(err, &expected) && expected.Code == code
 }
 
+// IsErrorCode checks if the error is HTTP error with a specific API error code.
+func IsErrorCode(err error, code ErrorCode) bool {
+	var expected *HTTPError
+
+	return errors.As(err, &expected) && expected.ErrorCode == code
+}
+
 // InvalidSchematicError is parsed from 400 response from the server.
 type InvalidSchematicError struct {
 	e error
@@ -39,6 +70,11 @@ func (e *InvalidSchematicError) Error() string {
 	return fmt.Sprintf("invalid schematic: %s", e.e)
 }
 
+// Unwrap implements errors.Unwrap interface.
+func (e *InvalidSchematicError) Unwrap() error {
+	return e.e
+}
+
 // IsInvalidSchematicError checks if the error is invalid schematic.
 func IsInvalidSchematicError(err error) bool {
 	return xerrors.TypeIs[*InvalidSchematicError](err)
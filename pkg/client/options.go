@@ -11,6 +11,8 @@ import "net/http"
 type Options struct {
 	// Client is the http client.
 	Client http.Client
+	// AuthToken is the bearer token sent with each request.
+	AuthToken string
 }
 
 // Option defines a single client option setter.
@@ -23,6 +25,13 @@ func WithClient(client http.Client) Option {
 	}
 }
 
+// WithAuthToken sets the bearer token to authenticate to the Image Factory.
+func WithAuthToken(token string) Option {
+	return func(o *Options) {
+		o.AuthToken = token
+	}
+}
+
 func withDefaults(options []Option) *Options {
 	opts := &Options{}
 
@@ -0,0 +1,103 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SigningPublicKey fetches the public key (PEM-encoded) used to sign the assets and the cached images.
+func (c *Client) SigningPublicKey(ctx context.Context) ([]byte, error) {
+	return c.get(ctx, "/oci/cosign/signing-key.pub")
+}
+
+// AssetSignature fetches the detached signature of the asset (base64-encoded).
+func (c *Client) AssetSignature(ctx context.Context, schematicID, talosVersion, path string) ([]byte, error) {
+	return c.get(ctx, fmt.Sprintf("/image/%s/%s/%s.sig", schematicID, talosVersion, path))
+}
+
+// VerifyAsset verifies the downloaded asset against the detached signature and the signing public key
+// served by the image factory.
+//
+// The asset is read until EOF.
+func (c *Client) VerifyAsset(ctx context.Context, schematicID, talosVersion, path string, asset io.Reader) error {
+	publicKeyPEM, err := c.SigningPublicKey(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching signing public key: %w", err)
+	}
+
+	signature, err := c.AssetSignature(ctx, schematicID, talosVersion, path)
+	if err != nil {
+		return fmt.Errorf("error fetching asset signature: %w", err)
+	}
+
+	return VerifyAssetSignature(publicKeyPEM, signature, asset)
+}
+
+// VerifyAssetSignature verifies the asset against the detached signature (base64-encoded, as produced by `cosign sign-blob`).
+//
+// The asset is read until EOF.
+func VerifyAssetSignature(publicKeyPEM, signature []byte, asset io.Reader) error {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return errors.New("failed to decode public key PEM")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	rawSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	hash := sha256.New()
+
+	if _, err = io.Copy(hash, asset); err != nil {
+		return fmt.Errorf("failed to read asset: %w", err)
+	}
+
+	digest := hash.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, rawSignature) {
+			return errors.New("invalid asset signature")
+		}
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, rawSignature); err != nil {
+			return fmt.Errorf("invalid asset signature: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, uri string) ([]byte, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	return io.ReadAll(resp.Body)
+}
@@ -17,29 +17,31 @@ import (
 // Schematic represents the requested image customization.
 type Schematic struct {
 	// Overlay represents the overlay options for image generation.
-	Overlay Overlay `yaml:"overlay,omitempty"`
+	Overlay Overlay `yaml:"overlay,omitempty" json:"overlay,omitzero"`
 	// Customization represents the Talos image customization.
-	Customization Customization `yaml:"customization"`
+	Customization Customization `yaml:"customization" json:"customization"`
+	// Private schematics are only served to authenticated callers.
+	Private bool `yaml:"private,omitempty" json:"private,omitempty"`
 }
 
 // Customization represents the Talos image customization.
 type Customization struct {
 	// Extra kernel arguments to be passed to the kernel.
-	ExtraKernelArgs []string `yaml:"extraKernelArgs,omitempty"`
+	ExtraKernelArgs []string `yaml:"extraKernelArgs,omitempty" json:"extraKernelArgs,omitempty"`
 	// Meta provides initial META contents for the image.
-	Meta []MetaValue `yaml:"meta,omitempty"`
+	Meta []MetaValue `yaml:"meta,omitempty" json:"meta,omitempty"`
 	// SystemExtensions represents the Talos system extensions to be installed.
-	SystemExtensions SystemExtensions `yaml:"systemExtensions,omitempty"`
+	SystemExtensions SystemExtensions `yaml:"systemExtensions,omitempty" json:"systemExtensions,omitzero"`
 	// SecureBoot represents the secure boot options for the image.
-	SecureBoot SecureBootCustomization `yaml:"secureboot,omitempty"`
+	SecureBoot SecureBootCustomization `yaml:"secureboot,omitempty" json:"secureboot,omitzero"`
 }
 
 // MetaValue provides initial META contents for the image.
 type MetaValue struct { //nolint:govet
 	// Key is the META key.
-	Key uint8 `yaml:"key"`
+	Key uint8 `yaml:"key" json:"key"`
 	// Value is the META value.
-	Value string `yaml:"value"`
+	Value string `yaml:"value" json:"value"`
 }
 
 // SystemExtensions represents the Talos system extensions to be installed.
@@ -47,20 +49,25 @@ type SystemExtensions struct {
 	// OfficialExtensions represents the Talos official system extensions to be installed.
 	//
 	// The image factory will pick up automatically the version compatible with Talos version.
-	OfficialExtensions []string `yaml:"officialExtensions,omitempty"`
+	OfficialExtensions []string `yaml:"officialExtensions,omitempty" json:"officialExtensions,omitempty"`
+	// CustomExtensions represents additional (non-official) system extensions to be installed.
+	//
+	// Each entry is a container image reference pinned by digest, e.g.
+	// `registry.example.com/extensions/my-driver:1.0.0@sha256:...`.
+	CustomExtensions []string `yaml:"customExtensions,omitempty" json:"customExtensions,omitempty"`
 }
 
 // Overlay represents the overlay options for image generation.
 type Overlay struct { //nolint:govet
-	Image   string         `yaml:"image"`
-	Name    string         `yaml:"name"`
-	Options map[string]any `yaml:"options,omitempty"`
+	Image   string         `yaml:"image" json:"image"`
+	Name    string         `yaml:"name" json:"name"`
+	Options map[string]any `yaml:"options,omitempty" json:"options,omitempty"`
 }
 
 // SecureBootCustomization represents the secure boot options for the image.
 type SecureBootCustomization struct {
 	// Include well-known UEFI certificates in the auto-enrollment database.
-	IncludeWellKnownCertificates bool `yaml:"includeWellKnownCertificates,omitempty"`
+	IncludeWellKnownCertificates bool `yaml:"includeWellKnownCertificates,omitempty" json:"includeWellKnownCertificates,omitempty"`
 }
 
 // InvalidErrorTag is a tag for invalid schematic errors.
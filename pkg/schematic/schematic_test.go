@@ -60,6 +60,13 @@ func TestID(t *testing.T) {
 			},
 			expectedID: "fa8e05f142a851d3ee568eb0a8e5841eaf6b0ebc8df9a63df16ac5ed2c04f3e6",
 		},
+		{
+			name: "private",
+			cfg: schematic.Schematic{
+				Private: true,
+			},
+			expectedID: "14ba141e799b350b220060b6437e5c18f41078c3b7613848fb71f7ecbd73e725",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
@@ -105,6 +112,11 @@ func TestUnmarshalID(t *testing.T) {
 			cfg:        []byte(`{"customization": {"extraKernelArgs": ["noapic", "nolapic"], "systemExtensions": {}}}`),
 			expectedID: "9cba8e32753f91a16c1837ab8abf356af021706ef284aef07380780177d9a06c",
 		},
+		{
+			name:       "extra args 3",
+			cfg:        []byte(`{"customization": {"extraKernelArgs": ["noapic", "nolapic"], "systemExtensions": {"customExtensions": []}}}`),
+			expectedID: "9cba8e32753f91a16c1837ab8abf356af021706ef284aef07380780177d9a06c",
+		},
 		{
 			name:       "meta",
 			cfg:        []byte(`{"customization": {"meta": [{"key": 10, "value": "foo"}], "extraKernelArgs": [], "systemExtensions": {}}}`),
@@ -125,6 +137,16 @@ func TestUnmarshalID(t *testing.T) {
 			cfg:        []byte(`{"customization":{"secureboot": {"includeWellKnownCertificates": true}}}`),
 			expectedID: "fa8e05f142a851d3ee568eb0a8e5841eaf6b0ebc8df9a63df16ac5ed2c04f3e6",
 		},
+		{
+			name:       "private",
+			cfg:        []byte(`{"private": true, "customization": {}}`),
+			expectedID: "14ba141e799b350b220060b6437e5c18f41078c3b7613848fb71f7ecbd73e725",
+		},
+		{
+			name:       "not private",
+			cfg:        []byte(`{"private": false}`),
+			expectedID: "376567988ad370138ad8b2698212367b8edcb69b5fd68c80be1f2ec7d603b4ba",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()